@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bufio"
+	"crud/internal/adapters/breach"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RunBuildBreachFilter builds the Bloom filter for breach_check.bloom_filter
+// from a breach list. The list is read twice, first to size the filter.
+func RunBuildBreachFilter(args []string) error {
+	flags := flag.NewFlagSet("build-breach-filter", flag.ContinueOnError)
+	in := flags.String("in", "", "breach list: SHA-1 hashes, optionally with :count, one per line")
+	out := flags.String("out", "breach.bloom", "where to write the filter")
+	fpRate := flags.Float64("fp", 1e-6, "false positive rate")
+	plain := flags.Bool("plain", false, "the list holds plaintext passwords instead of hashes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New("-in is required")
+	}
+
+	var n uint64
+	err := eachBreachHash(*in, *plain, func([20]byte) { n++ })
+	if err != nil {
+		return err
+	}
+	filter, err := breach.NewBloomFilter(n, *fpRate)
+	if err != nil {
+		return err
+	}
+	err = eachBreachHash(*in, *plain, filter.Add)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(*out), ".breach-*.bloom")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	if _, err := filter.WriteTo(w); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), *out); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s: %d hashes at a false positive rate of %g\n", *out, n, *fpRate)
+	return nil
+}
+
+func eachBreachHash(path string, plain bool, fn func([20]byte)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := r.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		sum, ok, err := breach.ParseLine(line, plain)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		if ok {
+			fn(sum)
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
@@ -2,19 +2,39 @@ package cmd
 
 import (
 	"context"
+	codeStore "crud/internal/adapters/authorization_code/redis"
+	"crud/internal/adapters/breach"
+	externalLoginStore "crud/internal/adapters/external_login_state/redis"
 	id_gen "crud/internal/adapters/id_generator"
+	idp "crud/internal/adapters/identity_provider"
+	"crud/internal/adapters/jwt"
+	loginAttemptsFallback "crud/internal/adapters/login_attempts/fallback"
+	loginAttemptsMemory "crud/internal/adapters/login_attempts/memory"
+	loginAttemptsStore "crud/internal/adapters/login_attempts/redis"
+	"crud/internal/adapters/notifier"
 	"crud/internal/adapters/password"
+	rateLimitStore "crud/internal/adapters/rate_limit/redis"
+	refreshStore "crud/internal/adapters/refresh_token/redis"
 	"crud/internal/adapters/repository/postgres"
 	redisStore "crud/internal/adapters/session/redis"
+	twoFactorStore "crud/internal/adapters/two_factor_challenge/redis"
 	"crud/internal/config"
+	"crud/internal/services/oidc"
 	"crud/internal/services/user"
 	httpapi "crud/internal/transport/http"
+	"crud/internal/transport/http/helpers"
 	"crud/internal/transport/http/middleware"
+	"crud/internal/transport/http/middleware/ratelimit"
+	"crypto/rand"
+	"expvar"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
@@ -48,29 +68,361 @@ func RunServer() error {
 
 	repo := postgres.NewUserRepository(pool)
 	idGen := id_gen.NewDefaultIDGen()
-	hasher := password.NewBcryptHasher(0)
 
 	rdb := redis.NewClient(&redis.Options{
 		Addr: fmt.Sprintf("%s:%d",config.Redis.Host,config.Redis.Port),
 		Password: "",
 		DB: 0,
 	})
-	sessionStore := redisStore.NewRedisStore(rdb, config.Session.TTL, idGen.NewID)
+	sessionStore, err := redisStore.NewRedisStore(rdb, user.SessionLifetime{
+		IdleTimeout:   config.Session.IdleTimeout,
+		MaxLifetime:   config.Session.MaxLifetime,
+		RememberMeTTL: config.Session.RememberMeTTL,
+	}, idGen.NewID)
+	if err != nil {
+		return fmt.Errorf("session store: %w", err)
+	}
+
+	logger := log.New(os.Stdout, "[http] ", log.LstdFlags|log.Lshortfile)
+
+	passwordHasher, err := newPasswordHasher(config, logger)
+	if err != nil {
+		return err
+	}
+	hasher := password.NewPool(passwordHasher, config.PasswordHashing.Pool.Concurrency, config.PasswordHashing.Pool.QueueDepth)
+	expvar.Publish("password_hashing", expvar.Func(func() any { return hasher.Stats() }))
+
+	keys, err := loadKeySet(config, logger)
+	if err != nil {
+		return err
+	}
+	accessTokens := jwt.NewAccessTokens(keys, config.JWT.Issuer, config.JWT.AccessTTL)
+	refreshTokens := refreshStore.NewRedisStore(rdb)
+	tokenService := user.NewTokenService(accessTokens, refreshTokens, config.JWT.RefreshTTL, idGen)
+
+	passwordPolicy := &user.PasswordPolicy{
+		MinLength:     config.PasswordPolicy.MinLength,
+		MaxBytes:      config.PasswordPolicy.MaxBytes,
+		LongPasswords: hashesLongPasswords(config),
+		RequireLower:  config.PasswordPolicy.RequireLower,
+		RequireUpper:  config.PasswordPolicy.RequireUpper,
+		RequireDigit:  config.PasswordPolicy.RequireDigit,
+		RequireSymbol: config.PasswordPolicy.RequireSymbol,
+		MinClasses:    config.PasswordPolicy.MinClasses,
+		BannedWords:   config.PasswordPolicy.BannedWords,
+		MinStrength:   config.PasswordPolicy.MinStrength,
+	}
+	passwordPolicy.Breaches, err = loadBreachChecker(config)
+	if err != nil {
+		return err
+	}
 
 	registerService := user.NewRegisterService(repo, hasher, idGen)
+	registerService.PasswordPolicy = passwordPolicy
 	loginService := user.NewLoginService(repo, hasher, sessionStore)
-	updateService := user.NewUpdateService(repo, hasher)
-	deleteService := user.NewDeleteService(repo)
-	logger := log.New(os.Stdout, "[http] ", log.LstdFlags|log.Lshortfile)
-	userHandler := httpapi.NewUserHandler(registerService, loginService, updateService, deleteService, logger)
-	authHandler := middleware.NewAuthMiddleware(sessionStore)
+	loginService.Tokens = tokenService
+	if err = loginService.PrepareDummyHash(ctx); err != nil {
+		return fmt.Errorf("prepare dummy password hash: %w", err)
+	}
+	if config.LoginThrottle.Enabled {
+		loginAttempts := loginAttemptsFallback.NewFallbackStore(loginAttemptsStore.NewRedisStore(rdb), loginAttemptsMemory.NewMemoryStore(), logger)
+		loginService.Throttle = user.NewLoginThrottle(loginAttempts)
+		loginService.Throttle.Account = throttlePolicy(config.LoginThrottle.Account, loginService.Throttle.Account)
+		loginService.Throttle.IP = throttlePolicy(config.LoginThrottle.IP, loginService.Throttle.IP)
+		if config.LoginThrottle.Window > 0 {
+			loginService.Throttle.Window = config.LoginThrottle.Window
+		}
+	}
+	twoFactorService := user.NewTwoFactorService(postgres.NewTwoFactorRepository(pool), repo, twoFactorStore.NewRedisStore(rdb), config.TwoFactor.Issuer)
+	twoFactorService.Throttle = loginService.Throttle
+	loginService.TwoFactor = twoFactorService
+	updateService := user.NewUpdateService(repo, hasher, sessionStore)
+	updateService.PasswordPolicy = passwordPolicy
+	updateService.RefreshTokens = refreshTokens
+	deleteService := user.NewDeleteService(repo, sessionStore)
+	deleteService.RefreshTokens = refreshTokens
+
+	notifierOut := io.Writer(os.Stdout)
+	if config.Notifier.File != "" {
+		f, err := os.OpenFile(config.Notifier.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		notifierOut = f
+	}
+	messages := notifier.NewLogNotifier(notifierOut)
+	oneTimeTokens := postgres.NewOneTimeTokenRepository(pool)
+	passwordResetService := user.NewPasswordResetService(repo, oneTimeTokens, hasher, sessionStore, messages, config.PasswordReset.URL)
+	passwordResetService.RefreshTokens = refreshTokens
+	passwordResetService.PasswordPolicy = passwordPolicy
+	if config.PasswordReset.TokenTTL > 0 {
+		passwordResetService.TokenTTL = config.PasswordReset.TokenTTL
+	}
+	verificationSecret, err := loadVerificationSecret(config, logger)
+	if err != nil {
+		return err
+	}
+	emailVerificationService := user.NewEmailVerificationService(repo, messages, verificationSecret, config.EmailVerification.URL)
+	if config.EmailVerification.TokenTTL > 0 {
+		emailVerificationService.TokenTTL = config.EmailVerification.TokenTTL
+	}
+	if config.EmailVerification.ResendInterval > 0 {
+		emailVerificationService.ResendInterval = config.EmailVerification.ResendInterval
+	}
+	registerService.Verification = emailVerificationService
+	registerService.ConcealExistingEmail = config.Registration.ConcealExistingEmail
+	registerService.Notifier = messages
+	updateService.Verification = emailVerificationService
+	loginService.RequireVerifiedEmail = config.EmailVerification.Required
+	magicLinkService := user.NewMagicLinkService(repo, oneTimeTokens, messages, loginService, config.MagicLink.URL)
+	if config.MagicLink.TokenTTL > 0 {
+		magicLinkService.TokenTTL = config.MagicLink.TokenTTL
+	}
+	identityProviders := make(map[string]user.IdentityProvider, len(config.ExternalLogin.Providers))
+	for _, provider := range config.ExternalLogin.Providers {
+		identityProviders[provider.Name] = idp.NewOIDCProvider(idp.Config{
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  strings.ReplaceAll(config.ExternalLogin.CallbackURL, "{provider}", provider.Name),
+			Scopes:       provider.Scopes,
+		}, nil)
+	}
+	externalLoginService := user.NewExternalLoginService(identityProviders, postgres.NewLinkedIdentityRepository(pool), externalLoginStore.NewRedisStore(rdb), repo, registerService, loginService)
+	if config.ExternalLogin.StateTTL > 0 {
+		externalLoginService.StateTTL = config.ExternalLogin.StateTTL
+	}
+	sessionService := user.NewSessionService(sessionStore)
+	sessionService.RefreshTokens = refreshTokens
+	personalTokenService := user.NewPersonalTokenService(postgres.NewPersonalAccessTokenRepository(pool), idGen)
+	oidcTokens := jwt.NewOIDCTokens(keys, config.JWT.Issuer, config.OIDC.AccessTTL, config.OIDC.IDTokenTTL)
+	oidcProvider := oidc.NewProvider(postgres.NewOAuthClientRepository(pool), postgres.NewOAuthConsentRepository(pool), codeStore.NewRedisStore(rdb), repo, oidcTokens)
+	if config.OIDC.CodeTTL > 0 {
+		oidcProvider.CodeTTL = config.OIDC.CodeTTL
+	}
+	oauthClientService := oidc.NewClientService(oidcProvider.Clients, idGen)
+	clientIP, err := helpers.NewClientIPResolver(config.Server.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	userHandler := httpapi.NewUserHandler(registerService, loginService, updateService, deleteService, sessionService, magicLinkService, externalLoginService, clientIP, logger)
+	tokenHandler := httpapi.NewTokenHandler(tokenService, keys, logger)
+	personalTokenHandler := httpapi.NewPersonalTokenHandler(personalTokenService, logger)
+	twoFactorHandler := httpapi.NewTwoFactorHandler(twoFactorService, logger)
+	passwordResetHandler := httpapi.NewPasswordResetHandler(passwordResetService, logger)
+	emailVerificationHandler := httpapi.NewEmailVerificationHandler(emailVerificationService, logger)
+	oidcHandler := httpapi.NewOIDCHandler(oidcProvider, oauthClientService, config.JWT.Issuer, logger)
+	authHandler, err := middleware.NewAuthMiddleware(sessionStore, accessTokens, personalTokenService, middleware.AuthConfig{
+		Sources:       config.Auth.Sources,
+		TouchInterval: config.Session.TouchInterval,
+	})
+	if err != nil {
+		return err
+	}
+
+	rateLimits := httpapi.RateLimits{}
+	rateLimits.Public, err = newRateLimiter("public", config.RateLimit.Public, rateLimitStore.NewRedisStore(rdb), clientIP, logger)
+	if err != nil {
+		return err
+	}
+	rateLimits.Authenticated, err = newRateLimiter("authenticated", config.RateLimit.Authenticated, rateLimitStore.NewRedisStore(rdb), clientIP, logger)
+	if err != nil {
+		return err
+	}
+
+	var csrf *middleware.CSRFProtection
+	if config.CSRF.Enabled {
+		csrf, err = middleware.NewCSRFProtection(middleware.CSRFConfig{
+			TrustedOrigins: config.CSRF.TrustedOrigins,
+			DoubleSubmit:   config.CSRF.DoubleSubmit,
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-	router := httpapi.NewRouter(userHandler, authHandler)
+	router := httpapi.NewRouter(userHandler, tokenHandler, personalTokenHandler, twoFactorHandler, passwordResetHandler, emailVerificationHandler, oidcHandler, authHandler, rateLimits, csrf)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port),
 		Handler: router,
 	}
+	if config.Server.DebugAddr != "" {
+		go serveDebug(config.Server.DebugAddr, logger)
+	}
 	logger.Printf("Starting server on %s:%d", config.Server.Host, config.Server.Port)
 	return server.ListenAndServe()
 }
+
+// serveDebug serves the expvar metrics, such as the password hashing queue,
+// at /debug/vars. The address should not be reachable from outside.
+func serveDebug(addr string, logger *log.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	logger.Printf("Starting debug server on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Printf("debug server: %v", err)
+	}
+}
+
+func loadKeySet(cfg config.Config, logger *log.Logger) (*jwt.KeySet, error) {
+	keys := make([]jwt.Key, 0, len(cfg.JWT.Keys))
+	for _, keyCfg := range cfg.JWT.Keys {
+		key, err := jwt.LoadKey(keyCfg.ID, keyCfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("load jwt key %q: %w", keyCfg.ID, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		logger.Printf("no jwt signing keys configured, generating an ephemeral key")
+		key, err := jwt.GenerateKey(fmt.Sprintf("ephemeral-%d", time.Now().Unix()))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return jwt.NewKeySet(keys, cfg.JWT.ActiveKey)
+}
+
+func loadVerificationSecret(cfg config.Config, logger *log.Logger) ([]byte, error) {
+	if cfg.EmailVerification.Secret != "" {
+		return []byte(cfg.EmailVerification.Secret), nil
+	}
+
+	logger.Printf("no email verification secret configured, generating an ephemeral one")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func throttlePolicy(cfg config.ThrottlePolicy, policy user.ThrottlePolicy) user.ThrottlePolicy {
+	if cfg.FreeAttempts > 0 {
+		policy.FreeAttempts = cfg.FreeAttempts
+	}
+	if cfg.BaseDelay > 0 {
+		policy.BaseDelay = cfg.BaseDelay
+	}
+	if cfg.MaxDelay > 0 {
+		policy.MaxDelay = cfg.MaxDelay
+	}
+	if cfg.LockoutThreshold > 0 {
+		policy.LockoutThreshold = cfg.LockoutThreshold
+	}
+	if cfg.LockoutDuration > 0 {
+		policy.LockoutDuration = cfg.LockoutDuration
+	}
+	return policy
+}
+
+func newRateLimiter(name string, rule config.RateLimitRule, store ratelimit.Store, clientIP *helpers.ClientIPResolver, logger *log.Logger) (*ratelimit.Limiter, error) {
+	if rule.Limit == 0 {
+		return nil, nil
+	}
+	limiter, err := ratelimit.New(store, ratelimit.Config{
+		Name:      name,
+		Algorithm: rule.Algorithm,
+		Limit:     rule.Limit,
+		Period:    rule.Period,
+		Burst:     rule.Burst,
+		Key:       rule.Key,
+	}, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	limiter.Logger = logger
+	return limiter, nil
+}
+
+func loadBreachChecker(cfg config.Config) (user.BreachChecker, error) {
+	switch {
+	case cfg.BreachCheck.BloomFilter != "":
+		filter, err := breach.LoadBloomFilter(cfg.BreachCheck.BloomFilter)
+		if err != nil {
+			return nil, fmt.Errorf("load breach filter: %w", err)
+		}
+		return filter, nil
+	case cfg.BreachCheck.RangeDir != "":
+		checker, err := breach.NewRangeChecker(cfg.BreachCheck.RangeDir)
+		if err != nil {
+			return nil, fmt.Errorf("open breach range dataset: %w", err)
+		}
+		return checker, nil
+	default:
+		return nil, nil
+	}
+}
+
+// hashesLongPasswords tells whether new hashes cover every byte of the
+// password: Argon2id reads all of it, and a pepper shortens it to a fixed
+// length before bcrypt sees it.
+func hashesLongPasswords(cfg config.Config) bool {
+	return cfg.PasswordHashing.Algorithm == "argon2id" || len(cfg.PasswordHashing.Pepper.Keys) > 0
+}
+
+func newPasswordHasher(cfg config.Config, logger *log.Logger) (*password.CompositeHasher, error) {
+	bcryptCost := cfg.PasswordHashing.BcryptCost
+	if calibration := cfg.PasswordHashing.BcryptCalibration; calibration.Target > 0 {
+		cost, took, err := password.CalibrateBcryptCost(calibration.Target, calibration.MinCost)
+		if err != nil {
+			return nil, fmt.Errorf("calibrate bcrypt cost: %w", err)
+		}
+		logger.Printf("calibrated bcrypt cost %d, %s per hash (target %s)", cost, took.Round(time.Millisecond), calibration.Target)
+		bcryptCost = cost
+	}
+	bcryptHasher := password.NewBcryptHasher(bcryptCost)
+	argon2Hasher := password.NewArgon2idHasher(password.Argon2Params{
+		Memory:      cfg.PasswordHashing.Argon2.Memory,
+		Iterations:  cfg.PasswordHashing.Argon2.Iterations,
+		Parallelism: cfg.PasswordHashing.Argon2.Parallelism,
+		SaltLength:  cfg.PasswordHashing.Argon2.SaltLength,
+		KeyLength:   cfg.PasswordHashing.Argon2.KeyLength,
+	})
+
+	var current, previous password.Scheme
+	switch cfg.PasswordHashing.Algorithm {
+	case "", "bcrypt":
+		current, previous = bcryptHasher, argon2Hasher
+	case "argon2id":
+		current, previous = argon2Hasher, bcryptHasher
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q", cfg.PasswordHashing.Algorithm)
+	}
+
+	var hasher *password.CompositeHasher
+	if len(cfg.PasswordHashing.Pepper.Keys) > 0 {
+		peppers := make([]password.Pepper, 0, len(cfg.PasswordHashing.Pepper.Keys))
+		for _, keyCfg := range cfg.PasswordHashing.Pepper.Keys {
+			pepper, err := password.LoadPepper(keyCfg.ID, keyCfg.File)
+			if err != nil {
+				return nil, fmt.Errorf("load password pepper %q: %w", keyCfg.ID, err)
+			}
+			peppers = append(peppers, pepper)
+		}
+		pepperedCurrent, err := password.NewPepperedHasher(current, peppers, cfg.PasswordHashing.Pepper.Active)
+		if err != nil {
+			return nil, err
+		}
+		pepperedPrevious, err := password.NewPepperedHasher(previous, peppers, cfg.PasswordHashing.Pepper.Active)
+		if err != nil {
+			return nil, err
+		}
+		hasher = password.NewCompositeHasher(pepperedCurrent, pepperedPrevious, current, previous)
+	} else {
+		hasher = password.NewCompositeHasher(current, previous)
+	}
+
+	for _, format := range cfg.PasswordHashing.LegacyFormats {
+		verifier, err := password.LegacyVerifier(format)
+		if err != nil {
+			return nil, fmt.Errorf("legacy password format %q: %w", format, err)
+		}
+		hasher.Others = append(hasher.Others, verifier)
+	}
+	return hasher, nil
+}
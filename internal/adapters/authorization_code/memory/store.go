@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/oidc"
+	"sync"
+	"time"
+)
+
+type MemoryStore struct {
+	mu    sync.Mutex
+	codes map[string]oidc.AuthorizationCode
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{codes: make(map[string]oidc.AuthorizationCode)}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, code oidc.AuthorizationCode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.codes[code.Hash] = code
+	return nil
+}
+
+func (s *MemoryStore) Consume(ctx context.Context, hash string) (oidc.AuthorizationCode, error) {
+	if err := ctx.Err(); err != nil {
+		return oidc.AuthorizationCode{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code, ok := s.codes[hash]
+	if !ok {
+		return oidc.AuthorizationCode{}, oidc.ErrInvalidGrant
+	}
+	delete(s.codes, hash)
+	if time.Now().After(code.ExpiresAt) {
+		return oidc.AuthorizationCode{}, oidc.ErrInvalidGrant
+	}
+	return code, nil
+}
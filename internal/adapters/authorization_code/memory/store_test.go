@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/oidc"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_ConsumeOnce(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	err := store.Create(ctx, oidc.AuthorizationCode{Hash: "h", UserID: "1", ExpiresAt: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if code, err := store.Consume(ctx, "h"); err != nil || code.UserID != "1" {
+		t.Fatalf("Consume failed: %+v %v", code, err)
+	}
+	if _, err := store.Consume(ctx, "h"); !errors.Is(err, oidc.ErrInvalidGrant) {
+		t.Fatalf("second Consume must fail, got: %v", err)
+	}
+}
+
+func TestMemoryStore_Expired(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_ = store.Create(ctx, oidc.AuthorizationCode{Hash: "h", ExpiresAt: time.Now().Add(-time.Second)})
+	if _, err := store.Consume(ctx, "h"); !errors.Is(err, oidc.ErrInvalidGrant) {
+		t.Fatalf("expected ErrInvalidGrant, got: %v", err)
+	}
+}
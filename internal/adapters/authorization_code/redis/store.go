@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"crud/internal/services/oidc"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func codeKey(hash string) string {
+	return fmt.Sprintf("authorization_code:%s", hash)
+}
+
+func (s *RedisStore) Create(ctx context.Context, code oidc.AuthorizationCode) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	ttl := time.Until(code.ExpiresAt)
+	if ttl <= 0 {
+		return oidc.ErrInvalidGrant
+	}
+	payload, err := json.Marshal(code)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, codeKey(code.Hash), payload, ttl).Err()
+}
+
+func (s *RedisStore) Consume(ctx context.Context, hash string) (oidc.AuthorizationCode, error) {
+	if ctx.Err() != nil {
+		return oidc.AuthorizationCode{}, ctx.Err()
+	}
+
+	payload, err := s.client.GetDel(ctx, codeKey(hash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return oidc.AuthorizationCode{}, oidc.ErrInvalidGrant
+	}
+	if err != nil {
+		return oidc.AuthorizationCode{}, err
+	}
+
+	var code oidc.AuthorizationCode
+	if err := json.Unmarshal(payload, &code); err != nil {
+		return oidc.AuthorizationCode{}, err
+	}
+	return code, nil
+}
@@ -0,0 +1,149 @@
+package breach
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// bloomMagic starts every filter file, followed by the number of bits and of
+// hash functions and the bits themselves, all big endian.
+const bloomMagic = "CRUDBLM1"
+
+// maxBloomBits keeps a corrupt header from allocating without bound; 2^36
+// bits are 8 GiB, well above what the full Pwned Passwords list needs at a
+// false positive rate of 1e-6.
+const maxBloomBits = 1 << 36
+
+// BloomFilter answers whether a password is in a breach list from a compact
+// in-memory filter. It can report a password that is not in the list, at the
+// false positive rate it was built for, but never misses one that is.
+type BloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint32
+}
+
+// NewBloomFilter sizes an empty filter for n hashes at the false positive
+// rate fpRate.
+func NewBloomFilter(n uint64, fpRate float64) (*BloomFilter, error) {
+	if n == 0 || fpRate <= 0 || fpRate >= 1 {
+		return nil, fmt.Errorf("bloom filter needs n > 0 and 0 < fpRate < 1, got %d and %g", n, fpRate)
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(max(math.Round(float64(m)/float64(n)*math.Ln2), 1))
+	if m > maxBloomBits {
+		return nil, fmt.Errorf("bloom filter of %d bits is too large", m)
+	}
+	return &BloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}, nil
+}
+
+// LoadBloomFilter reads a filter written by WriteTo.
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBloomFilter(bufio.NewReader(f))
+}
+
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, len(bloomMagic)+12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if string(header[:len(bloomMagic)]) != bloomMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidFilter)
+	}
+	m := binary.BigEndian.Uint64(header[len(bloomMagic):])
+	k := binary.BigEndian.Uint32(header[len(bloomMagic)+8:])
+	if m == 0 || m > maxBloomBits || k == 0 {
+		return nil, fmt.Errorf("%w: %d bits, %d hashes", ErrInvalidFilter, m, k)
+	}
+
+	f := &BloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+	if err := binary.Read(r, binary.BigEndian, f.bits); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return f, nil
+}
+
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, len(bloomMagic)+12)
+	copy(header, bloomMagic)
+	binary.BigEndian.PutUint64(header[len(bloomMagic):], f.m)
+	binary.BigEndian.PutUint32(header[len(bloomMagic)+8:], f.k)
+	if _, err := w.Write(header); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(w, binary.BigEndian, f.bits); err != nil {
+		return int64(len(header)), err
+	}
+	return int64(len(header) + 8*len(f.bits)), nil
+}
+
+// Add puts a SHA-1 hash of a password into the filter.
+func (f *BloomFilter) Add(sum [sha1.Size]byte) {
+	h1, h2 := f.hashes(sum)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (f *BloomFilter) Contains(sum [sha1.Size]byte) bool {
+	h1, h2 := f.hashes(sum)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *BloomFilter) Breached(ctx context.Context, password string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return f.Contains(sha1.Sum([]byte(password))), nil
+}
+
+// hashes derives the two hashes of double hashing from the SHA-1, which is
+// already uniform. h2 is odd so that it never collapses the probes.
+func (f *BloomFilter) hashes(sum [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]) | 1
+}
+
+// ParseLine reads a line of a breach list: a hex SHA-1 hash optionally
+// followed by ":count", as in the ordered-by-hash Pwned Passwords download,
+// or a plaintext password when plain is set. Blank lines give ok false.
+func ParseLine(line string, plain bool) (sum [sha1.Size]byte, ok bool, err error) {
+	if plain {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return sum, false, nil
+		}
+		return sha1.Sum([]byte(line)), true, nil
+	}
+
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	if hash == "" {
+		return sum, false, nil
+	}
+	if len(hash) != 2*sha1.Size {
+		return sum, false, fmt.Errorf("%w: %q", ErrInvalidLine, hash)
+	}
+	if _, err := hex.Decode(sum[:], []byte(hash)); err != nil {
+		return sum, false, fmt.Errorf("%w: %q", ErrInvalidLine, hash)
+	}
+	return sum, true, nil
+}
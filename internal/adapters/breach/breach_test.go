@@ -0,0 +1,102 @@
+package breach
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestRangeChecker(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("P@ssw0rd")
+	padding := sha1Hex("padding")
+	write := func(hash string, count int) {
+		path := filepath.Join(dir, hash[:5]+".txt")
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			t.Fatalf("failed to write range file: %v", err)
+		}
+		defer f.Close()
+		fmt.Fprintf(f, "%s:%d\r\n", hash[5:], count)
+	}
+	write(hash, 42)
+	write(padding, 0)
+
+	checker, err := NewRangeChecker(dir)
+	if err != nil {
+		t.Fatalf("NewRangeChecker failed: %v", err)
+	}
+	ctx := context.Background()
+	for password, want := range map[string]bool{"P@ssw0rd": true, "padding": false, "unlisted": false} {
+		if got, err := checker.Breached(ctx, password); err != nil || got != want {
+			t.Errorf("%q: expected %v, got %v %v", password, want, got, err)
+		}
+	}
+}
+
+func TestBloomFilter_RoundTrip(t *testing.T) {
+	filter, err := NewBloomFilter(100, 1e-6)
+	if err != nil {
+		t.Fatalf("NewBloomFilter failed: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		filter.Add(sha1.Sum([]byte(fmt.Sprintf("breached-%d", i))))
+	}
+
+	var buf bytes.Buffer
+	if _, err := filter.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	loaded, err := ReadBloomFilter(&buf)
+	if err != nil {
+		t.Fatalf("ReadBloomFilter failed: %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		if ok, _ := loaded.Breached(ctx, fmt.Sprintf("breached-%d", i)); !ok {
+			t.Fatalf("breached-%d must be found", i)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		if ok, _ := loaded.Breached(ctx, fmt.Sprintf("safe-%d", i)); ok {
+			t.Fatalf("unexpected false positive for safe-%d", i)
+		}
+	}
+}
+
+func TestReadBloomFilter_Invalid(t *testing.T) {
+	for _, data := range []string{"", "NOTAFILTER0000000000", "CRUDBLM1\x00\x00\x00\x00\x00\x00\x00\x40\x00\x00\x00\x01"} {
+		if _, err := ReadBloomFilter(strings.NewReader(data)); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%q: expected ErrInvalidFilter, got %v", data, err)
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	hash := sha1Hex("P@ssw0rd")
+	if sum, ok, err := ParseLine(strings.ToLower(hash)+":42\n", false); err != nil || !ok || sum != sha1.Sum([]byte("P@ssw0rd")) {
+		t.Fatalf("hash line not parsed: %v %v", ok, err)
+	}
+	if sum, ok, err := ParseLine("P@ssw0rd\r\n", true); err != nil || !ok || sum != sha1.Sum([]byte("P@ssw0rd")) {
+		t.Fatalf("plain line not parsed: %v %v", ok, err)
+	}
+	if _, ok, err := ParseLine("\n", false); ok || err != nil {
+		t.Fatalf("blank lines must be skipped: %v %v", ok, err)
+	}
+	if _, _, err := ParseLine("not-a-hash", false); !errors.Is(err, ErrInvalidLine) {
+		t.Fatalf("expected ErrInvalidLine, got %v", err)
+	}
+}
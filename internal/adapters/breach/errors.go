@@ -0,0 +1,8 @@
+package breach
+
+import "errors"
+
+var (
+	ErrInvalidFilter = errors.New("invalid bloom filter file")
+	ErrInvalidLine   = errors.New("line is not a SHA-1 hash")
+)
@@ -0,0 +1,74 @@
+package breach
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// prefixLength is the length of the hash prefix the dataset is split by.
+const prefixLength = 5
+
+// RangeChecker looks passwords up in a k-anonymity range dataset as written by
+// the Pwned Passwords downloader: one file per five character SHA-1 prefix,
+// named like 21BD1.txt, with lines of the remaining 35 characters and a count,
+// like 0018A45C4D1DEF81644B54AB7F969B88D65:10.
+type RangeChecker struct {
+	dir string
+	// MinCount ignores hashes seen fewer times, such as the zero-count padding
+	// entries of the online API.
+	MinCount int
+}
+
+func NewRangeChecker(dir string) (*RangeChecker, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &RangeChecker{dir: dir, MinCount: 1}, nil
+}
+
+// Breached reports false for prefixes without a file, so an incomplete
+// download lets the missing passwords through.
+func (c *RangeChecker) Breached(ctx context.Context, password string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	f, err := os.Open(filepath.Join(c.dir, hash[:prefixLength]+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	suffix := hash[prefixLength:]
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(entry, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			// Lists without counts only hold breached hashes.
+			n = 1
+		}
+		return n >= c.MinCount, nil
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"sync"
+	"time"
+)
+
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]user.ExternalLoginState
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]user.ExternalLoginState)}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, state user.ExternalLoginState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[state.Hash] = state
+	return nil
+}
+
+func (s *MemoryStore) Consume(ctx context.Context, hash string) (user.ExternalLoginState, error) {
+	if err := ctx.Err(); err != nil {
+		return user.ExternalLoginState{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[hash]
+	if !ok {
+		return user.ExternalLoginState{}, user.ErrExternalLoginStateInvalid
+	}
+	delete(s.states, hash)
+	if time.Now().After(state.ExpiresAt) {
+		return user.ExternalLoginState{}, user.ErrExternalLoginStateInvalid
+	}
+	return state, nil
+}
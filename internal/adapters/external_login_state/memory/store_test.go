@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_ConsumeOnce(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	err := store.Create(ctx, user.ExternalLoginState{Hash: "h", Provider: "idp", ExpiresAt: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if state, err := store.Consume(ctx, "h"); err != nil || state.Provider != "idp" {
+		t.Fatalf("Consume failed: %+v %v", state, err)
+	}
+	if _, err := store.Consume(ctx, "h"); !errors.Is(err, user.ErrExternalLoginStateInvalid) {
+		t.Fatalf("second Consume must fail, got: %v", err)
+	}
+}
+
+func TestMemoryStore_Expired(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_ = store.Create(ctx, user.ExternalLoginState{Hash: "h", ExpiresAt: time.Now().Add(-time.Second)})
+	if _, err := store.Consume(ctx, "h"); !errors.Is(err, user.ErrExternalLoginStateInvalid) {
+		t.Fatalf("expected ErrExternalLoginStateInvalid, got: %v", err)
+	}
+}
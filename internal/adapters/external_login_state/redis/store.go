@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func stateKey(hash string) string {
+	return fmt.Sprintf("external_login_state:%s", hash)
+}
+
+func (s *RedisStore) Create(ctx context.Context, state user.ExternalLoginState) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	ttl := time.Until(state.ExpiresAt)
+	if ttl <= 0 {
+		return user.ErrExternalLoginStateInvalid
+	}
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, stateKey(state.Hash), payload, ttl).Err()
+}
+
+func (s *RedisStore) Consume(ctx context.Context, hash string) (user.ExternalLoginState, error) {
+	if ctx.Err() != nil {
+		return user.ExternalLoginState{}, ctx.Err()
+	}
+
+	payload, err := s.client.GetDel(ctx, stateKey(hash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return user.ExternalLoginState{}, user.ErrExternalLoginStateInvalid
+	}
+	if err != nil {
+		return user.ExternalLoginState{}, err
+	}
+
+	var state user.ExternalLoginState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return user.ExternalLoginState{}, err
+	}
+	return state, nil
+}
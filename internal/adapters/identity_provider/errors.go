@@ -0,0 +1,9 @@
+package idp
+
+import "errors"
+
+var (
+	ErrDiscoveryFailed    = errors.New("failed to load provider configuration")
+	ErrTokenRequestFailed = errors.New("token request failed")
+	ErrIDTokenInvalid     = errors.New("id token is invalid")
+)
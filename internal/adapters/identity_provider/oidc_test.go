@@ -0,0 +1,145 @@
+package idp
+
+import (
+	"context"
+	"crud/internal/adapters/jwt"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is a minimal OpenID Connect provider. It hands out an ID token with
+// claims for the code "good" and the code verifier "verifier".
+type mockIdP struct {
+	server *httptest.Server
+	keys   *jwt.KeySet
+	claims jwtlib.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := jwt.GenerateKey("idp-key")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keys, _ := jwt.NewKeySet([]jwt.Key{key}, "")
+	m := &mockIdP{keys: keys}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(m.keys.JWKS())
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "rp" || secret != "rp-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.PostFormValue("code") != "good" || r.PostFormValue("code_verifier") != "verifier" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		idToken, err := m.keys.Sign("JWT", m.claims)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	m.claims = jwtlib.MapClaims{
+		"iss":            m.server.URL,
+		"sub":            "external-1",
+		"aud":            "rp",
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"nonce":          "nonce-1",
+		"email":          "demo@example.com",
+		"email_verified": "true",
+	}
+	return m
+}
+
+func (m *mockIdP) provider() *OIDCProvider {
+	return NewOIDCProvider(Config{
+		Issuer:       m.server.URL,
+		ClientID:     "rp",
+		ClientSecret: "rp-secret",
+		RedirectURL:  "http://localhost:8080/callback",
+	}, m.server.Client())
+}
+
+func TestOIDCProvider_AuthURL(t *testing.T) {
+	m := newMockIdP(t)
+
+	authURL, err := m.provider().AuthURL(context.Background(), "state-1", "nonce-1", "challenge")
+	if err != nil {
+		t.Fatalf("AuthURL failed: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	query := u.Query()
+	if u.Path != "/authorize" || query.Get("client_id") != "rp" || query.Get("state") != "state-1" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("scope") != "openid email profile" {
+		t.Fatalf("unexpected auth url: %s", authURL)
+	}
+}
+
+func TestOIDCProvider_Exchange(t *testing.T) {
+	m := newMockIdP(t)
+	provider := m.provider()
+	ctx := context.Background()
+
+	identity, err := provider.Exchange(ctx, "good", "verifier", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	if identity.Subject != "external-1" || identity.Email != "demo@example.com" || !identity.EmailVerified {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+
+	if _, err := provider.Exchange(ctx, "good", "verifier", "other-nonce"); !errors.Is(err, ErrIDTokenInvalid) {
+		t.Fatalf("expected ErrIDTokenInvalid for nonce mismatch, got: %v", err)
+	}
+	if _, err := provider.Exchange(ctx, "bad", "verifier", "nonce-1"); !errors.Is(err, ErrTokenRequestFailed) {
+		t.Fatalf("expected ErrTokenRequestFailed for rejected code, got: %v", err)
+	}
+
+	m.claims["aud"] = "someone-else"
+	if _, err := provider.Exchange(ctx, "good", "verifier", "nonce-1"); !errors.Is(err, ErrIDTokenInvalid) {
+		t.Fatalf("expected ErrIDTokenInvalid for foreign audience, got: %v", err)
+	}
+}
+
+func TestOIDCProvider_KeyRotation(t *testing.T) {
+	m := newMockIdP(t)
+	provider := m.provider()
+	ctx := context.Background()
+
+	if _, err := provider.Exchange(ctx, "good", "verifier", "nonce-1"); err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+
+	key, _ := jwt.GenerateKey("rotated")
+	m.keys, _ = jwt.NewKeySet([]jwt.Key{key}, "")
+	if _, err := provider.Exchange(ctx, "good", "verifier", "nonce-1"); err != nil {
+		t.Fatalf("rotated key must be fetched, got: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package jwt
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"errors"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+)
+
+// accessTokenType is the RFC 9068 typ header. It keeps ID tokens and other
+// JWTs signed with the same keys from being accepted as access tokens.
+const accessTokenType = "at+jwt"
+
+type AccessTokens struct {
+	keys   *KeySet
+	issuer string
+	ttl    time.Duration
+}
+
+func NewAccessTokens(keys *KeySet, issuer string, ttl time.Duration) *AccessTokens {
+	return &AccessTokens{
+		keys:   keys,
+		issuer: issuer,
+		ttl:    ttl,
+	}
+}
+
+func (a *AccessTokens) Issue(ctx context.Context, userID string) (user.AccessToken, error) {
+	if err := ctx.Err(); err != nil {
+		return user.AccessToken{}, err
+	}
+
+	timeNow := time.Now().UTC()
+	expiresAt := timeNow.Add(a.ttl)
+	claims := jwtlib.RegisteredClaims{
+		Issuer:    a.issuer,
+		Subject:   userID,
+		IssuedAt:  jwtlib.NewNumericDate(timeNow),
+		NotBefore: jwtlib.NewNumericDate(timeNow),
+		ExpiresAt: jwtlib.NewNumericDate(expiresAt),
+	}
+
+	token, err := a.keys.Sign(accessTokenType, claims)
+	if err != nil {
+		return user.AccessToken{}, err
+	}
+	return user.AccessToken{Token: token, ExpiresAt: expiresAt.Truncate(time.Second)}, nil
+}
+
+func (a *AccessTokens) Verify(ctx context.Context, token string) (user.AccessClaims, error) {
+	if err := ctx.Err(); err != nil {
+		return user.AccessClaims{}, err
+	}
+
+	var claims jwtlib.RegisteredClaims
+	parsed, err := jwtlib.ParseWithClaims(token, &claims, a.keys.Keyfunc,
+		jwtlib.WithValidMethods([]string{jwtlib.SigningMethodRS256.Alg()}),
+		jwtlib.WithIssuer(a.issuer),
+		jwtlib.WithExpirationRequired(),
+		jwtlib.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return user.AccessClaims{}, errors.Join(user.ErrAccessTokenInvalid, err)
+	}
+	if typ, _ := parsed.Header["typ"].(string); typ != accessTokenType || claims.Subject == "" {
+		return user.AccessClaims{}, user.ErrAccessTokenInvalid
+	}
+	// Tokens with an audience were issued to an OpenID Connect client and
+	// are only good at that client.
+	if len(claims.Audience) > 0 {
+		return user.AccessClaims{}, user.ErrAccessTokenInvalid
+	}
+
+	return user.AccessClaims{
+		UserID:    claims.Subject,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,11 @@
+package jwt
+
+import "errors"
+
+var (
+	ErrNoKeys         = errors.New("key set is empty")
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrDuplicateKey   = errors.New("duplicate key id")
+	ErrInvalidKeyFile = errors.New("key file does not contain an RSA private key")
+	ErrInvalidJWK     = errors.New("invalid json web key")
+)
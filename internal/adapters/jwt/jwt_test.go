@@ -0,0 +1,173 @@
+package jwt
+
+import (
+	"context"
+	"crud/internal/services/oidc"
+	"crud/internal/services/user"
+	"errors"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKeySet(t *testing.T, ids ...string) *KeySet {
+	t.Helper()
+
+	keys := make([]Key, 0, len(ids))
+	for _, id := range ids {
+		key, err := GenerateKey(id)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	set, err := NewKeySet(keys, "")
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	return set
+}
+
+func TestAccessTokens(t *testing.T) {
+	tokens := NewAccessTokens(newTestKeySet(t, "k1"), "crud", time.Minute)
+	ctx := context.Background()
+
+	issued, err := tokens.Issue(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	claims, err := tokens.Verify(ctx, issued.Token)
+	if err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+	if claims.UserID != "user-1" || !claims.ExpiresAt.Equal(issued.ExpiresAt) {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+
+	tampered := issued.Token[:len(issued.Token)-4] + "AAAA"
+	if _, err := tokens.Verify(ctx, tampered); !errors.Is(err, user.ErrAccessTokenInvalid) {
+		t.Fatalf("expected ErrAccessTokenInvalid for tampered token, got: %v", err)
+	}
+
+	otherIssuer := NewAccessTokens(tokens.keys, "someone-else", time.Minute)
+	if _, err := otherIssuer.Verify(ctx, issued.Token); !errors.Is(err, user.ErrAccessTokenInvalid) {
+		t.Fatalf("expected ErrAccessTokenInvalid for foreign issuer, got: %v", err)
+	}
+}
+
+func TestAccessTokens_Expired(t *testing.T) {
+	tokens := NewAccessTokens(newTestKeySet(t, "k1"), "crud", -time.Hour)
+
+	issued, err := tokens.Issue(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+	if _, err := tokens.Verify(context.Background(), issued.Token); !errors.Is(err, user.ErrAccessTokenInvalid) {
+		t.Fatalf("expected ErrAccessTokenInvalid for expired token, got: %v", err)
+	}
+}
+
+func TestAccessTokens_RejectsOtherTokenTypes(t *testing.T) {
+	keys := newTestKeySet(t, "k1")
+	tokens := NewAccessTokens(keys, "crud", time.Minute)
+
+	idToken, err := keys.Sign("JWT", jwtlib.RegisteredClaims{
+		Issuer:    "crud",
+		Subject:   "user-1",
+		ExpiresAt: jwtlib.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := tokens.Verify(context.Background(), idToken); !errors.Is(err, user.ErrAccessTokenInvalid) {
+		t.Fatalf("expected ErrAccessTokenInvalid for non access token, got: %v", err)
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	old := newTestKeySet(t, "k1")
+	oldToken, err := NewAccessTokens(old, "crud", time.Minute).Issue(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	newKey, err := GenerateKey("k2")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	rotated, err := NewKeySet([]Key{{ID: "k1", PrivateKey: old.keys["k1"]}, newKey}, "k2")
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	tokens := NewAccessTokens(rotated, "crud", time.Minute)
+
+	if _, err := tokens.Verify(context.Background(), oldToken.Token); err != nil {
+		t.Fatalf("token signed with retired key should verify: %v", err)
+	}
+
+	newToken, err := tokens.Issue(context.Background(), "user-1")
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+	parsed, _, err := jwtlib.NewParser().ParseUnverified(newToken.Token, &jwtlib.RegisteredClaims{})
+	if err != nil || parsed.Header["kid"] != "k2" {
+		t.Fatalf("expected token signed with k2, got: %v %v", parsed.Header, err)
+	}
+
+	if _, err := NewAccessTokens(old, "crud", time.Minute).Verify(context.Background(), newToken.Token); !errors.Is(err, user.ErrAccessTokenInvalid) {
+		t.Fatalf("expected unknown kid to be rejected, got: %v", err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := newTestKeySet(t, "k1", "k2")
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "k1" || jwks.Keys[1].Kid != "k2" {
+		t.Fatalf("unexpected jwks: %+v", jwks)
+	}
+
+	publicKey, err := jwks.Keys[0].RSAPublicKey()
+	if err != nil {
+		t.Fatalf("failed to decode jwk: %v", err)
+	}
+	if !publicKey.Equal(&keys.keys["k1"].PublicKey) {
+		t.Fatalf("jwk does not round trip")
+	}
+}
+
+func TestOIDCTokens_SeparateFromAPITokens(t *testing.T) {
+	keys := newTestKeySet(t, "k1")
+	apiTokens := NewAccessTokens(keys, "crud", time.Minute)
+	oidcTokens := NewOIDCTokens(keys, "crud", time.Minute, time.Minute)
+	ctx := context.Background()
+
+	issued, err := oidcTokens.IssueAccessToken(ctx, oidc.AccessTokenClaims{UserID: "user-1", ClientID: "client-1", Scopes: []string{"openid", "email"}})
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+	claims, err := oidcTokens.VerifyAccessToken(ctx, issued.Token)
+	if err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.ClientID != "client-1" || len(claims.Scopes) != 2 {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+	if _, err := apiTokens.Verify(ctx, issued.Token); !errors.Is(err, user.ErrAccessTokenInvalid) {
+		t.Fatalf("client access token must not be accepted by the API, got: %v", err)
+	}
+
+	apiToken, _ := apiTokens.Issue(ctx, "user-1")
+	if _, err := oidcTokens.VerifyAccessToken(ctx, apiToken.Token); !errors.Is(err, oidc.ErrAccessTokenInvalid) {
+		t.Fatalf("API access token must not be accepted by userinfo, got: %v", err)
+	}
+
+	idToken, err := oidcTokens.IssueIDToken(ctx, oidc.IDTokenClaims{UserClaims: oidc.UserClaims{Subject: "user-1"}, ClientID: "client-1"})
+	if err != nil {
+		t.Fatalf("failed to issue id token: %v", err)
+	}
+	if _, err := oidcTokens.VerifyAccessToken(ctx, idToken); !errors.Is(err, oidc.ErrAccessTokenInvalid) {
+		t.Fatalf("id token must not be accepted as access token, got: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+)
+
+type Key struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+}
+
+// KeySet holds the RSA keys tokens are signed with. Only the active key signs
+// new tokens; the others stay around to verify tokens issued before a
+// rotation and are published in the JWKS until they are removed.
+type KeySet struct {
+	keys   map[string]*rsa.PrivateKey
+	order  []string
+	active string
+}
+
+func NewKeySet(keys []Key, activeID string) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	set := &KeySet{keys: make(map[string]*rsa.PrivateKey, len(keys))}
+	for _, key := range keys {
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateKey, key.ID)
+		}
+		set.keys[key.ID] = key.PrivateKey
+		set.order = append(set.order, key.ID)
+	}
+
+	if activeID == "" {
+		activeID = keys[len(keys)-1].ID
+	}
+	if _, ok := set.keys[activeID]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, activeID)
+	}
+	set.active = activeID
+
+	return set, nil
+}
+
+func LoadKey(id string, path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, ErrInvalidKeyFile
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return Key{ID: id, PrivateKey: key}, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: %v", ErrInvalidKeyFile, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return Key{}, ErrInvalidKeyFile
+	}
+	return Key{ID: id, PrivateKey: key}, nil
+}
+
+func GenerateKey(id string) (Key, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{ID: id, PrivateKey: key}, nil
+}
+
+// Sign signs claims with the active key and stamps its ID into the kid header.
+func (s *KeySet) Sign(typ string, claims jwtlib.Claims) (string, error) {
+	token := jwtlib.NewWithClaims(jwtlib.SigningMethodRS256, claims)
+	token.Header["kid"] = s.active
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+	return token.SignedString(s.keys[s.active])
+}
+
+// Keyfunc resolves the verification key from the kid header of a token.
+func (s *KeySet) Keyfunc(token *jwtlib.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	return &key.PublicKey, nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: jwtlib.SigningMethodRS256.Alg(),
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func (k JWK) RSAPublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidJWK, k.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: bad exponent", ErrInvalidJWK)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.order))}
+	for _, kid := range s.order {
+		jwks.Keys = append(jwks.Keys, NewJWK(kid, &s.keys[kid].PublicKey))
+	}
+	return jwks
+}
@@ -0,0 +1,124 @@
+package jwt
+
+import (
+	"context"
+	"crud/internal/services/oidc"
+	"crud/internal/services/user"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+)
+
+// idTokenType is the typ header of ID tokens. Their audience is the client,
+// so they are never accepted as access tokens.
+const idTokenType = "JWT"
+
+type oidcAccessClaims struct {
+	jwtlib.RegisteredClaims
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+type idTokenClaims struct {
+	jwtlib.RegisteredClaims
+	Nonce             string  `json:"nonce,omitempty"`
+	Email             *string `json:"email,omitempty"`
+	EmailVerified     *bool   `json:"email_verified,omitempty"`
+	PreferredUsername *string `json:"preferred_username,omitempty"`
+}
+
+// OIDCTokens signs the tokens handed out to OpenID Connect clients. Their
+// access tokens are RFC 9068 JWTs bound to the client by aud and client_id,
+// which AccessTokens refuses, so they cannot be used against the API.
+type OIDCTokens struct {
+	keys       *KeySet
+	issuer     string
+	accessTTL  time.Duration
+	idTokenTTL time.Duration
+}
+
+func NewOIDCTokens(keys *KeySet, issuer string, accessTTL time.Duration, idTokenTTL time.Duration) *OIDCTokens {
+	return &OIDCTokens{
+		keys:       keys,
+		issuer:     issuer,
+		accessTTL:  accessTTL,
+		idTokenTTL: idTokenTTL,
+	}
+}
+
+func (o *OIDCTokens) IssueAccessToken(ctx context.Context, claims oidc.AccessTokenClaims) (user.AccessToken, error) {
+	if err := ctx.Err(); err != nil {
+		return user.AccessToken{}, err
+	}
+
+	timeNow := time.Now().UTC()
+	expiresAt := timeNow.Add(o.accessTTL)
+	token, err := o.keys.Sign(accessTokenType, oidcAccessClaims{
+		RegisteredClaims: jwtlib.RegisteredClaims{
+			Issuer:    o.issuer,
+			Subject:   claims.UserID,
+			Audience:  jwtlib.ClaimStrings{claims.ClientID},
+			IssuedAt:  jwtlib.NewNumericDate(timeNow),
+			NotBefore: jwtlib.NewNumericDate(timeNow),
+			ExpiresAt: jwtlib.NewNumericDate(expiresAt),
+		},
+		ClientID: claims.ClientID,
+		Scope:    strings.Join(claims.Scopes, " "),
+	})
+	if err != nil {
+		return user.AccessToken{}, err
+	}
+	return user.AccessToken{Token: token, ExpiresAt: expiresAt.Truncate(time.Second)}, nil
+}
+
+func (o *OIDCTokens) VerifyAccessToken(ctx context.Context, token string) (oidc.AccessTokenClaims, error) {
+	if err := ctx.Err(); err != nil {
+		return oidc.AccessTokenClaims{}, err
+	}
+
+	var claims oidcAccessClaims
+	parsed, err := jwtlib.ParseWithClaims(token, &claims, o.keys.Keyfunc,
+		jwtlib.WithValidMethods([]string{jwtlib.SigningMethodRS256.Alg()}),
+		jwtlib.WithIssuer(o.issuer),
+		jwtlib.WithExpirationRequired(),
+		jwtlib.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return oidc.AccessTokenClaims{}, errors.Join(oidc.ErrAccessTokenInvalid, err)
+	}
+	if typ, _ := parsed.Header["typ"].(string); typ != accessTokenType || claims.Subject == "" ||
+		claims.ClientID == "" || !slices.Contains(claims.Audience, claims.ClientID) {
+		return oidc.AccessTokenClaims{}, oidc.ErrAccessTokenInvalid
+	}
+
+	return oidc.AccessTokenClaims{
+		UserID:    claims.Subject,
+		ClientID:  claims.ClientID,
+		Scopes:    strings.Fields(claims.Scope),
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+func (o *OIDCTokens) IssueIDToken(ctx context.Context, claims oidc.IDTokenClaims) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	timeNow := time.Now().UTC()
+	return o.keys.Sign(idTokenType, idTokenClaims{
+		RegisteredClaims: jwtlib.RegisteredClaims{
+			Issuer:    o.issuer,
+			Subject:   claims.Subject,
+			Audience:  jwtlib.ClaimStrings{claims.ClientID},
+			IssuedAt:  jwtlib.NewNumericDate(timeNow),
+			ExpiresAt: jwtlib.NewNumericDate(timeNow.Add(o.idTokenTTL)),
+		},
+		Nonce:             claims.Nonce,
+		Email:             claims.Email.ToPointer(),
+		EmailVerified:     claims.EmailVerified.ToPointer(),
+		PreferredUsername: claims.PreferredUsername.ToPointer(),
+	})
+}
@@ -0,0 +1,52 @@
+package fallback
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"log"
+	"time"
+)
+
+// FallbackStore uses Primary and switches to Secondary for calls Primary
+// fails, so that an outage of Redis neither blocks logins nor switches
+// throttling off. Counts kept in Secondary are per instance only.
+type FallbackStore struct {
+	Primary   user.LoginAttemptStore
+	Secondary user.LoginAttemptStore
+	logger    *log.Logger
+}
+
+func NewFallbackStore(primary user.LoginAttemptStore, secondary user.LoginAttemptStore, logger *log.Logger) *FallbackStore {
+	return &FallbackStore{Primary: primary, Secondary: secondary, logger: logger}
+}
+
+func (s *FallbackStore) Get(ctx context.Context, key string) (user.LoginFailures, error) {
+	failures, err := s.Primary.Get(ctx, key)
+	if err == nil || ctx.Err() != nil {
+		return failures, err
+	}
+	s.logger.Printf("login attempts: get failed, using fallback: %v", err)
+	return s.Secondary.Get(ctx, key)
+}
+
+func (s *FallbackStore) Fail(ctx context.Context, key string, failedAt time.Time, window time.Duration) (user.LoginFailures, error) {
+	failures, err := s.Primary.Fail(ctx, key, failedAt, window)
+	if err == nil || ctx.Err() != nil {
+		return failures, err
+	}
+	s.logger.Printf("login attempts: fail failed, using fallback: %v", err)
+	return s.Secondary.Fail(ctx, key, failedAt, window)
+}
+
+// Reset clears both stores, since failures may have been counted in either.
+func (s *FallbackStore) Reset(ctx context.Context, key string) error {
+	err := s.Primary.Reset(ctx, key)
+	if err != nil && ctx.Err() == nil {
+		s.logger.Printf("login attempts: reset failed, using fallback: %v", err)
+		err = nil
+	}
+	if secondaryErr := s.Secondary.Reset(ctx, key); secondaryErr != nil {
+		return secondaryErr
+	}
+	return err
+}
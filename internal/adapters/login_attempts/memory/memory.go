@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	failures  user.LoginFailures
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]entry)}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (user.LoginFailures, error) {
+	if err := ctx.Err(); err != nil {
+		return user.LoginFailures{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		return user.LoginFailures{}, nil
+	}
+	return e.failures, nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, failedAt time.Time, window time.Duration) (user.LoginFailures, error) {
+	if err := ctx.Err(); err != nil {
+		return user.LoginFailures{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	e := s.entries[key]
+	e.failures.Count++
+	e.failures.LastFailedAt = failedAt
+	e.expiresAt = now.Add(window)
+	s.entries[key] = e
+	return e.failures, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops expired entries so that guesses against many accounts do not
+// grow the map without bound.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if now.After(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
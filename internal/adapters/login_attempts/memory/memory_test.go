@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_FailAndReset(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	_, _ = store.Fail(ctx, "k", now, time.Minute)
+	failures, err := store.Fail(ctx, "k", now, time.Minute)
+	if err != nil || failures.Count != 2 || !failures.LastFailedAt.Equal(now) {
+		t.Fatalf("unexpected failures: %+v %v", failures, err)
+	}
+	if got, _ := store.Get(ctx, "k"); got.Count != 2 {
+		t.Fatalf("expected 2 failures, got %+v", got)
+	}
+
+	if err := store.Reset(ctx, "k"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if got, _ := store.Get(ctx, "k"); got.Count != 0 {
+		t.Fatalf("expected no failures after reset, got %+v", got)
+	}
+}
+
+func TestMemoryStore_Window(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_, _ = store.Fail(ctx, "k", time.Now(), -time.Second)
+	if got, _ := store.Get(ctx, "k"); got.Count != 0 {
+		t.Fatalf("expected expired failures to be forgotten, got %+v", got)
+	}
+}
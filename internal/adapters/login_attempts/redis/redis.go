@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	countField = "count"
+	lastField  = "last"
+)
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func attemptsKey(key string) string {
+	return fmt.Sprintf("login_attempts:%s", key)
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (user.LoginFailures, error) {
+	if ctx.Err() != nil {
+		return user.LoginFailures{}, ctx.Err()
+	}
+
+	values, err := s.client.HGetAll(ctx, attemptsKey(key)).Result()
+	if err != nil {
+		return user.LoginFailures{}, err
+	}
+	return parseFailures(values[countField], values[lastField])
+}
+
+// Fail increments the counter and moves the expiry in one transaction, so
+// concurrent failures from several instances are all counted.
+func (s *RedisStore) Fail(ctx context.Context, key string, failedAt time.Time, window time.Duration) (user.LoginFailures, error) {
+	if ctx.Err() != nil {
+		return user.LoginFailures{}, ctx.Err()
+	}
+
+	redisKey := attemptsKey(key)
+	var count *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.HIncrBy(ctx, redisKey, countField, 1)
+		pipe.HSet(ctx, redisKey, lastField, failedAt.UnixMilli())
+		pipe.PExpire(ctx, redisKey, window)
+		return nil
+	})
+	if err != nil {
+		return user.LoginFailures{}, err
+	}
+	return user.LoginFailures{Count: int(count.Val()), LastFailedAt: failedAt}, nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return s.client.Del(ctx, attemptsKey(key)).Err()
+}
+
+func parseFailures(count string, last string) (user.LoginFailures, error) {
+	if count == "" {
+		return user.LoginFailures{}, nil
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return user.LoginFailures{}, err
+	}
+	ms, err := strconv.ParseInt(last, 10, 64)
+	if err != nil {
+		return user.LoginFailures{}, err
+	}
+	return user.LoginFailures{Count: n, LastFailedAt: time.UnixMilli(ms)}, nil
+}
@@ -0,0 +1,34 @@
+package notifier
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogNotifier writes messages to w instead of sending them. It is meant for
+// local development, where the log or file stands in for a mailbox.
+type LogNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogNotifier(w io.Writer) *LogNotifier {
+	return &LogNotifier{w: w}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg user.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err := fmt.Fprintf(n.w, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().UTC().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	return err
+}
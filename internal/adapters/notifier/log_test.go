@@ -0,0 +1,25 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crud/internal/services/user"
+	"strings"
+	"testing"
+)
+
+func TestLogNotifier_Notify(t *testing.T) {
+	var buf bytes.Buffer
+	n := NewLogNotifier(&buf)
+
+	err := n.Notify(context.Background(), user.Message{To: "demo@example.com", Subject: "Hi", Body: "link"})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"To: demo@example.com\n", "Subject: Hi\n", "\nlink\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in output:\n%s", want, out)
+		}
+	}
+}
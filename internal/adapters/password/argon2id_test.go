@@ -0,0 +1,81 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2Params)
+	ctx := context.Background()
+
+	hash, err := hasher.Hash(ctx, "mysecret123")
+	if err != nil {
+		t.Fatalf("Hashing failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected PHC string: %s", hash)
+	}
+
+	if err := hasher.Compare(ctx, hash, "mysecret123"); err != nil {
+		t.Fatalf("compare err: %v", err)
+	}
+	if err := hasher.Compare(ctx, hash, "bad"); !errors.Is(err, user.ErrPasswordIncorrect) {
+		t.Fatalf("expected ErrPasswordIncorrect, got: %v", err)
+	}
+	if hasher.NeedsRehash(hash) {
+		t.Fatalf("fresh hash must not need a rehash")
+	}
+
+	stronger := NewArgon2idHasher(Argon2Params{Memory: 2048, Iterations: 1, Parallelism: 1})
+	if !stronger.NeedsRehash(hash) {
+		t.Fatalf("hash with outdated parameters must need a rehash")
+	}
+	if err := stronger.Compare(ctx, hash, "mysecret123"); err != nil {
+		t.Fatalf("parameters must be read from the hash, got: %v", err)
+	}
+}
+
+func TestArgon2idHasher_Malformed(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2Params)
+	ctx := context.Background()
+
+	for _, hash := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=99999999,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+	} {
+		if err := hasher.Compare(ctx, hash, "x"); !errors.Is(err, ErrInvalidHash) && !errors.Is(err, ErrUnknownHashFormat) {
+			t.Errorf("%s: expected a malformed hash error, got: %v", hash, err)
+		}
+	}
+}
+
+func TestCompositeHasher_MigratesBcrypt(t *testing.T) {
+	bcryptHasher := NewBcryptHasher(4)
+	hasher := NewCompositeHasher(NewArgon2idHasher(testArgon2Params), bcryptHasher)
+	ctx := context.Background()
+
+	old, _ := bcryptHasher.Hash(ctx, "mysecret123")
+	if err := hasher.Compare(ctx, old, "mysecret123"); err != nil {
+		t.Fatalf("bcrypt hash must still verify, got: %v", err)
+	}
+	if !hasher.NeedsRehash(old) {
+		t.Fatalf("bcrypt hash must need a rehash")
+	}
+
+	hash, _ := hasher.Hash(ctx, "mysecret123")
+	if !strings.HasPrefix(hash, argon2idPrefix) || hasher.NeedsRehash(hash) {
+		t.Fatalf("expected a current argon2id hash, got %s", hash)
+	}
+
+	if err := hasher.Compare(ctx, "plaintext", "plaintext"); !errors.Is(err, ErrUnknownHashFormat) {
+		t.Fatalf("expected ErrUnknownHashFormat, got: %v", err)
+	}
+}
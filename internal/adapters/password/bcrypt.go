@@ -4,6 +4,8 @@ import (
 	"context"
 	"crud/internal/services/user"
 	"errors"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -41,3 +43,49 @@ func (h *BcryptHasher) Compare(ctx context.Context, hash string, plaintext strin
 	return err
 
 }
+
+// NeedsRehash is true for hashes made with a lower cost or that are no
+// bcrypt hash at all. Hashes with a higher cost are kept, so that a
+// calibrated cost that varies between restarts does not churn them.
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.cost
+}
+
+// Recognizes reports whether hash is a bcrypt hash.
+func (h *BcryptHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// calibrationPassword is hashed to time a cost; its value does not matter.
+const calibrationPassword = "calibration-password"
+
+// CalibrateBcryptCost returns the highest cost whose hash takes at most
+// target on this host, and how long one hash took with it. The cost is never
+// below minCost, even when that takes longer than target.
+func CalibrateBcryptCost(target time.Duration, minCost int) (int, time.Duration, error) {
+	cost := max(minCost, bcrypt.MinCost)
+	took, err := timeBcrypt(cost)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Every step of cost doubles the work, so there is no need to time a cost
+	// that would clearly take too long.
+	for cost < bcrypt.MaxCost && 2*took <= target {
+		next, err := timeBcrypt(cost + 1)
+		if err != nil {
+			return 0, 0, err
+		}
+		if next > target {
+			break
+		}
+		cost, took = cost+1, next
+	}
+	return cost, took, nil
+}
+
+func timeBcrypt(cost int) (time.Duration, error) {
+	start := time.Now()
+	_, err := bcrypt.GenerateFromPassword([]byte(calibrationPassword), cost)
+	return time.Since(start), err
+}
@@ -3,6 +3,7 @@ package password
 import (
 	"context"
 	"testing"
+	"time"
 )
 
 func TestBcryptHasher(t *testing.T) {
@@ -32,3 +33,34 @@ func TestBcryptHasher_WrongPassword(t *testing.T) {
 		t.Fatal("expected error for wrong password")
 	}
 }
+
+func TestBcryptHasher_NeedsRehash(t *testing.T) {
+	ctx := context.Background()
+	hash, _ := NewBcryptHasher(5).Hash(ctx, "mysecret123")
+
+	if !NewBcryptHasher(6).NeedsRehash(hash) {
+		t.Fatal("hash with a lower cost must need a rehash")
+	}
+	if NewBcryptHasher(4).NeedsRehash(hash) {
+		t.Fatal("hash with a higher cost must be kept")
+	}
+}
+
+func TestCalibrateBcryptCost(t *testing.T) {
+	cost, took, err := CalibrateBcryptCost(0, 5)
+	if err != nil {
+		t.Fatalf("calibration failed: %v", err)
+	}
+	if cost != 5 || took <= 0 {
+		t.Fatalf("cost must not go below the floor, got %d (%s)", cost, took)
+	}
+
+	target := 20 * time.Millisecond
+	cost, took, err = CalibrateBcryptCost(target, 4)
+	if err != nil {
+		t.Fatalf("calibration failed: %v", err)
+	}
+	if cost > 4 && took > target {
+		t.Fatalf("cost %d took %s, more than the %s target", cost, took, target)
+	}
+}
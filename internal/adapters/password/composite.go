@@ -0,0 +1,53 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+)
+
+// Scheme is a hasher that can tell its own hashes apart from others.
+type Scheme interface {
+	user.PasswordHasher
+	Recognizes(hash string) bool
+}
+
+// CompositeHasher hashes with Current and compares with whichever scheme
+// made the stored hash. Others may be older schemes or verify-only legacy
+// formats. Hashes of any scheme but Current need a rehash, so users move to
+// Current as they log in.
+type CompositeHasher struct {
+	Current Scheme
+	Others  []Verifier
+}
+
+func NewCompositeHasher(current Scheme, others ...Verifier) *CompositeHasher {
+	return &CompositeHasher{Current: current, Others: others}
+}
+
+func (h *CompositeHasher) Hash(ctx context.Context, plaintext string) (string, error) {
+	return h.Current.Hash(ctx, plaintext)
+}
+
+func (h *CompositeHasher) Compare(ctx context.Context, hash string, plaintext string) error {
+	scheme := h.verifier(hash)
+	if scheme == nil {
+		return ErrUnknownHashFormat
+	}
+	return scheme.Compare(ctx, hash, plaintext)
+}
+
+func (h *CompositeHasher) NeedsRehash(hash string) bool {
+	return !h.Current.Recognizes(hash) || h.Current.NeedsRehash(hash)
+}
+
+func (h *CompositeHasher) verifier(hash string) Verifier {
+	if h.Current.Recognizes(hash) {
+		return h.Current
+	}
+	for _, scheme := range h.Others {
+		if scheme.Recognizes(hash) {
+			return scheme
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package password
+
+import "errors"
+
+var (
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+	ErrInvalidHash       = errors.New("malformed password hash")
+	ErrNoPeppers         = errors.New("no password peppers")
+	ErrUnknownPepper     = errors.New("unknown password pepper")
+	ErrDuplicatePepper   = errors.New("duplicate password pepper")
+	ErrInvalidPepperID   = errors.New("invalid password pepper id")
+	ErrPepperTooShort    = errors.New("password pepper too short")
+)
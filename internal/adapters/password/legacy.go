@@ -0,0 +1,44 @@
+package password
+
+import (
+	"context"
+	"crypto/subtle"
+)
+
+// Verifier checks passwords against hashes of one format without being able
+// to make new ones. Legacy formats of imported users are verifiers only.
+type Verifier interface {
+	Compare(ctx context.Context, hash string, plaintext string) error
+	Recognizes(hash string) bool
+}
+
+const (
+	LegacyDjangoPBKDF2 = "django_pbkdf2"
+	LegacyScrypt       = "scrypt"
+	LegacySHACrypt     = "sha_crypt"
+	LegacyPHPass       = "phpass"
+)
+
+// LegacyVerifier returns the verifier for one of the Legacy* format names.
+func LegacyVerifier(name string) (Verifier, error) {
+	switch name {
+	case LegacyDjangoPBKDF2:
+		return DjangoPBKDF2Verifier{}, nil
+	case LegacyScrypt:
+		return ScryptVerifier{}, nil
+	case LegacySHACrypt:
+		return SHACryptVerifier{}, nil
+	case LegacyPHPass:
+		return PHPassVerifier{}, nil
+	default:
+		return nil, ErrUnknownHashFormat
+	}
+}
+
+// cryptB64 is the alphabet of crypt(3) style hashes, SHA-crypt and phpass
+// among them.
+const cryptB64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func equalHashes(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
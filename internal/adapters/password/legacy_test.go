@@ -0,0 +1,87 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"errors"
+	"testing"
+)
+
+func TestLegacyVerifiers(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		verifier Verifier
+		hash     string
+		password string
+	}{
+		{"django pbkdf2_sha256", DjangoPBKDF2Verifier{}, "pbkdf2_sha256$1000$seasalt12345$Z+nnHCjHo1EcXJHpSbmoQLbOnqBar149Gl7nrWbhwYw=", "correct horse"},
+		{"django pbkdf2_sha1", DjangoPBKDF2Verifier{}, "pbkdf2_sha1$1000$seasalt12345$IIBWRwLWNOHN3cksOPvnmW+8wFs=", "correct horse"},
+		{"django scrypt", ScryptVerifier{}, "scrypt$seasalt12345$1024$8$1$C6UUU6s2/8y7q4Le8Z+GnnfZP8XxJOxl3+qyW2zxql51E9WWySP19rbx70qjK2Rzqiu1mr+Y7nNrvMtu+0BgXQ==", "correct horse"},
+		{"passlib scrypt", ScryptVerifier{}, "$scrypt$ln=10,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg$6g3umF.uVrJsObaTZhIbbTlgrvOEFcCItdwSjtPF67M", "correct horse"},
+		{"sha256-crypt", SHACryptVerifier{}, "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5", "Hello world!"},
+		{"sha256-crypt empty password", SHACryptVerifier{}, "$5$saltstring$FdNfA4gXqvCeO6iZs7G/.wwwoywYZqo0l1pwmfWaBA7", ""},
+		{"sha256-crypt rounds", SHACryptVerifier{}, "$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA", "Hello world!"},
+		{"sha512-crypt", SHACryptVerifier{}, "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", "Hello world!"},
+		{"sha512-crypt rounds", SHACryptVerifier{}, "$6$rounds=1000$abcdefgh$DbXqPck5ABZumVXwcH5eMVHsPZwvQ3jylSxpJmKkZVxcoCiC3N0rPerjHmAqL7r6PK6CIeYnO9.vxpbsevscg/", "correct horse"},
+		{"phpass", PHPassVerifier{}, "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0", "test12345"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.verifier.Recognizes(tt.hash) {
+				t.Fatalf("hash not recognized: %s", tt.hash)
+			}
+			if err := tt.verifier.Compare(ctx, tt.hash, tt.password); err != nil {
+				t.Fatalf("compare err: %v", err)
+			}
+			if err := tt.verifier.Compare(ctx, tt.hash, tt.password+"x"); !errors.Is(err, user.ErrPasswordIncorrect) {
+				t.Fatalf("expected ErrPasswordIncorrect, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLegacyVerifiers_Malformed(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		verifier Verifier
+		hash     string
+	}{
+		{DjangoPBKDF2Verifier{}, "pbkdf2_sha256$0$salt$a2V5"},
+		{DjangoPBKDF2Verifier{}, "pbkdf2_sha256$1000$salt"},
+		{ScryptVerifier{}, "scrypt$salt$1000$8$1$a2V5"},
+		{ScryptVerifier{}, "scrypt$salt$1073741824$8$1$a2V5"},
+		{ScryptVerifier{}, "$scrypt$ln=40,r=8,p=1$c2FsdA$a2V5"},
+		{SHACryptVerifier{}, "$5$rounds=999999999$salt$hash"},
+		{SHACryptVerifier{}, "$6$saltonly"},
+		{PHPassVerifier{}, "$P$Ishort"},
+		{PHPassVerifier{}, "$P$zIQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0"},
+	} {
+		if err := tt.verifier.Compare(ctx, tt.hash, "x"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("%s: expected ErrInvalidHash, got: %v", tt.hash, err)
+		}
+	}
+}
+
+func TestCompositeHasher_MigratesLegacyFormats(t *testing.T) {
+	ctx := context.Background()
+	hasher := NewCompositeHasher(NewArgon2idHasher(testArgon2Params), PHPassVerifier{}, SHACryptVerifier{})
+
+	legacy := "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0"
+	if err := hasher.Compare(ctx, legacy, "test12345"); err != nil {
+		t.Fatalf("compare err: %v", err)
+	}
+	if !hasher.NeedsRehash(legacy) {
+		t.Fatalf("legacy hash must need a rehash")
+	}
+	if err := hasher.Compare(ctx, "pbkdf2_sha256$1000$salt$a2V5", "x"); !errors.Is(err, ErrUnknownHashFormat) {
+		t.Fatalf("expected ErrUnknownHashFormat for a format not configured, got: %v", err)
+	}
+
+	if _, err := LegacyVerifier("md5"); !errors.Is(err, ErrUnknownHashFormat) {
+		t.Fatalf("expected ErrUnknownHashFormat, got: %v", err)
+	}
+}
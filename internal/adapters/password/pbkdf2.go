@@ -0,0 +1,63 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// maxPBKDF2Iterations bounds the work a stored hash can ask for.
+const maxPBKDF2Iterations = 10_000_000
+
+// DjangoPBKDF2Verifier checks Django's pbkdf2_sha256$<iterations>$<salt>$<hash>
+// and pbkdf2_sha1 hashes.
+type DjangoPBKDF2Verifier struct{}
+
+func (DjangoPBKDF2Verifier) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "pbkdf2_sha256$") || strings.HasPrefix(hash, "pbkdf2_sha1$")
+}
+
+func (DjangoPBKDF2Verifier) Compare(ctx context.Context, encoded string, plaintext string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 4 {
+		return fmt.Errorf("%w: django pbkdf2", ErrInvalidHash)
+	}
+	var newHash func() hash.Hash
+	switch fields[0] {
+	case "pbkdf2_sha256":
+		newHash = sha256.New
+	case "pbkdf2_sha1":
+		newHash = sha1.New
+	default:
+		return ErrUnknownHashFormat
+	}
+	iterations, err := strconv.Atoi(fields[1])
+	if err != nil || iterations <= 0 || iterations > maxPBKDF2Iterations {
+		return fmt.Errorf("%w: django pbkdf2 iterations %q", ErrInvalidHash, fields[1])
+	}
+	want, err := base64.StdEncoding.DecodeString(fields[3])
+	if err != nil || len(want) == 0 {
+		return fmt.Errorf("%w: django pbkdf2 hash", ErrInvalidHash)
+	}
+
+	got, err := pbkdf2.Key(newHash, plaintext, []byte(fields[2]), iterations, len(want))
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return user.ErrPasswordIncorrect
+	}
+	return nil
+}
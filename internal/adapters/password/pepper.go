@@ -0,0 +1,129 @@
+package password
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const pepperPrefix = "$pepper$k="
+
+// minPepperLength is the shortest pepper accepted, in bytes.
+const minPepperLength = 32
+
+// Pepper is a secret kept outside the database. ID is written into every
+// hash made with it, so that peppers can be rotated.
+type Pepper struct {
+	ID  string
+	Key []byte
+}
+
+// PepperedHasher hashes HMAC-SHA256(pepper, password) with an inner scheme
+// and stores the result as $pepper$k=<id><inner hash>, e.g.
+// $pepper$k=2$argon2id$v=19$... A leaked users table is of no use without
+// the pepper, and since the inner scheme sees a 43 byte digest, bcrypt no
+// longer cuts passwords off at 72 bytes.
+type PepperedHasher struct {
+	inner   Scheme
+	peppers map[string][]byte
+	active  string
+}
+
+// NewPepperedHasher hashes with the pepper activeID, or the last one if
+// activeID is empty. The other peppers only verify.
+func NewPepperedHasher(inner Scheme, peppers []Pepper, activeID string) (*PepperedHasher, error) {
+	if len(peppers) == 0 {
+		return nil, ErrNoPeppers
+	}
+
+	h := &PepperedHasher{inner: inner, peppers: make(map[string][]byte, len(peppers))}
+	for _, pepper := range peppers {
+		if pepper.ID == "" || strings.Contains(pepper.ID, "$") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPepperID, pepper.ID)
+		}
+		if len(pepper.Key) < minPepperLength {
+			return nil, fmt.Errorf("%w: %q is shorter than %d bytes", ErrPepperTooShort, pepper.ID, minPepperLength)
+		}
+		if _, ok := h.peppers[pepper.ID]; ok {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicatePepper, pepper.ID)
+		}
+		h.peppers[pepper.ID] = pepper.Key
+	}
+
+	if activeID == "" {
+		activeID = peppers[len(peppers)-1].ID
+	}
+	if _, ok := h.peppers[activeID]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPepper, activeID)
+	}
+	h.active = activeID
+	return h, nil
+}
+
+// LoadPepper reads a pepper from a file, ignoring surrounding whitespace.
+func LoadPepper(id string, path string) (Pepper, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Pepper{}, err
+	}
+	return Pepper{ID: id, Key: bytes.TrimSpace(data)}, nil
+}
+
+func (h *PepperedHasher) Hash(ctx context.Context, plaintext string) (string, error) {
+	hash, err := h.inner.Hash(ctx, h.prehash(h.peppers[h.active], plaintext))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(hash, "$") {
+		return "", fmt.Errorf("%w: inner hash must start with $", ErrUnknownHashFormat)
+	}
+	return pepperPrefix + h.active + hash, nil
+}
+
+func (h *PepperedHasher) Compare(ctx context.Context, hash string, plaintext string) error {
+	id, inner, err := splitPeppered(hash)
+	if err != nil {
+		return err
+	}
+	key, ok := h.peppers[id]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownPepper, id)
+	}
+	return h.inner.Compare(ctx, inner, h.prehash(key, plaintext))
+}
+
+// NeedsRehash is true for hashes without a pepper, with a pepper other than
+// the active one, or that the inner scheme wants to rehash.
+func (h *PepperedHasher) NeedsRehash(hash string) bool {
+	id, inner, err := splitPeppered(hash)
+	return err != nil || id != h.active || h.inner.NeedsRehash(inner)
+}
+
+// Recognizes reports whether hash is peppered and made by the inner scheme.
+func (h *PepperedHasher) Recognizes(hash string) bool {
+	_, inner, err := splitPeppered(hash)
+	return err == nil && h.inner.Recognizes(inner)
+}
+
+func (h *PepperedHasher) prehash(key []byte, plaintext string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(plaintext))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func splitPeppered(hash string) (id string, inner string, err error) {
+	rest, ok := strings.CutPrefix(hash, pepperPrefix)
+	if !ok {
+		return "", "", ErrUnknownHashFormat
+	}
+	id, inner, ok = strings.Cut(rest, "$")
+	if !ok || id == "" {
+		return "", "", fmt.Errorf("%w: pepper id", ErrInvalidHash)
+	}
+	return id, "$" + inner, nil
+}
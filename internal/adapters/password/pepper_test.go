@@ -0,0 +1,123 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var (
+	testPepperV1 = Pepper{ID: "1", Key: []byte(strings.Repeat("a", minPepperLength))}
+	testPepperV2 = Pepper{ID: "2", Key: []byte(strings.Repeat("b", minPepperLength))}
+)
+
+func TestPepperedHasher(t *testing.T) {
+	ctx := context.Background()
+	hasher, err := NewPepperedHasher(NewBcryptHasher(4), []Pepper{testPepperV1}, "")
+	if err != nil {
+		t.Fatalf("new hasher: %v", err)
+	}
+
+	long := strings.Repeat("x", 80)
+	hash, err := hasher.Hash(ctx, long)
+	if err != nil {
+		t.Fatalf("Hashing failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$pepper$k=1$2a$04$") {
+		t.Fatalf("unexpected hash: %s", hash)
+	}
+	if !hasher.Recognizes(hash) || hasher.NeedsRehash(hash) {
+		t.Fatalf("fresh hash must be recognized and not need a rehash")
+	}
+
+	if err := hasher.Compare(ctx, hash, long); err != nil {
+		t.Fatalf("compare err: %v", err)
+	}
+	if err := hasher.Compare(ctx, hash, long[:72]); !errors.Is(err, user.ErrPasswordIncorrect) {
+		t.Fatalf("bytes past 72 must count, got: %v", err)
+	}
+
+	otherPepper, _ := NewPepperedHasher(NewBcryptHasher(4), []Pepper{{ID: "1", Key: testPepperV2.Key}}, "")
+	if err := otherPepper.Compare(ctx, hash, long); !errors.Is(err, user.ErrPasswordIncorrect) {
+		t.Fatalf("hash must not verify with another pepper, got: %v", err)
+	}
+
+	plain, _ := NewBcryptHasher(4).Hash(ctx, long)
+	if hasher.Recognizes(plain) {
+		t.Fatalf("hash without pepper must not be recognized")
+	}
+}
+
+// TestLongPasswords checks that the hashers for which the policy lifts the
+// bcrypt limit tell apart passwords that differ only after byte 72.
+func TestLongPasswords(t *testing.T) {
+	ctx := context.Background()
+	peppered, _ := NewPepperedHasher(NewBcryptHasher(4), []Pepper{testPepperV1}, "")
+	hashers := map[string]Scheme{
+		"peppered bcrypt": peppered,
+		"argon2id":        NewArgon2idHasher(testArgon2Params),
+	}
+
+	long := strings.Repeat("correct horse battery staple ", 4)
+	policy := &user.PasswordPolicy{MinLength: 8, LongPasswords: true}
+	if err := policy.Check(ctx, long); err != nil {
+		t.Fatalf("%d byte password must pass the policy, got: %v", len(long), err)
+	}
+	changed := []byte(long)
+	changed[100] = 'X'
+
+	for name, hasher := range hashers {
+		hash, err := hasher.Hash(ctx, long)
+		if err != nil {
+			t.Fatalf("%s: Hashing failed: %v", name, err)
+		}
+		if err := hasher.Compare(ctx, hash, long); err != nil {
+			t.Fatalf("%s: compare err: %v", name, err)
+		}
+		if err := hasher.Compare(ctx, hash, string(changed)); !errors.Is(err, user.ErrPasswordIncorrect) {
+			t.Fatalf("%s: a byte past 72 must count, got: %v", name, err)
+		}
+	}
+}
+
+func TestPepperedHasher_Rotation(t *testing.T) {
+	ctx := context.Background()
+	old, _ := NewPepperedHasher(NewBcryptHasher(4), []Pepper{testPepperV1}, "")
+	hash, _ := old.Hash(ctx, "mysecret123")
+
+	rotated, err := NewPepperedHasher(NewBcryptHasher(4), []Pepper{testPepperV1, testPepperV2}, "2")
+	if err != nil {
+		t.Fatalf("new hasher: %v", err)
+	}
+	if err := rotated.Compare(ctx, hash, "mysecret123"); err != nil {
+		t.Fatalf("hash of a retired pepper must still verify, got: %v", err)
+	}
+	if !rotated.NeedsRehash(hash) {
+		t.Fatalf("hash of a retired pepper must need a rehash")
+	}
+
+	dropped, _ := NewPepperedHasher(NewBcryptHasher(4), []Pepper{testPepperV2}, "")
+	if err := dropped.Compare(ctx, hash, "mysecret123"); !errors.Is(err, ErrUnknownPepper) {
+		t.Fatalf("expected ErrUnknownPepper, got: %v", err)
+	}
+}
+
+func TestNewPepperedHasher_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		peppers []Pepper
+		active  string
+		want    error
+	}{
+		{nil, "", ErrNoPeppers},
+		{[]Pepper{testPepperV1}, "2", ErrUnknownPepper},
+		{[]Pepper{testPepperV1, testPepperV1}, "", ErrDuplicatePepper},
+		{[]Pepper{{ID: "a$b", Key: testPepperV1.Key}}, "", ErrInvalidPepperID},
+		{[]Pepper{{ID: "1", Key: []byte("short")}}, "", ErrPepperTooShort},
+	} {
+		if _, err := NewPepperedHasher(NewBcryptHasher(4), tt.peppers, tt.active); !errors.Is(err, tt.want) {
+			t.Errorf("expected %v, got: %v", tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"crypto/md5"
+	"fmt"
+	"strings"
+)
+
+const (
+	phpassHashLength = 34
+	phpassSaltLength = 8
+)
+
+// PHPassVerifier checks the portable $P$ hashes of phpass, as WordPress and
+// older Drupal sites store them, and the $H$ variant of phpBB.
+type PHPassVerifier struct{}
+
+func (PHPassVerifier) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$P$") || strings.HasPrefix(hash, "$H$")
+}
+
+func (PHPassVerifier) Compare(ctx context.Context, encoded string, plaintext string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(encoded) != phpassHashLength {
+		return fmt.Errorf("%w: phpass", ErrInvalidHash)
+	}
+	countLog2 := strings.IndexByte(cryptB64, encoded[3])
+	if countLog2 < 7 || countLog2 > 30 {
+		return fmt.Errorf("%w: phpass iteration count", ErrInvalidHash)
+	}
+	salt := encoded[4 : 4+phpassSaltLength]
+
+	sum := md5.Sum([]byte(salt + plaintext))
+	for range 1 << countLog2 {
+		sum = md5.Sum(append(sum[:], plaintext...))
+	}
+
+	var b strings.Builder
+	b.WriteString(encoded[:4+phpassSaltLength])
+	for i := 0; i < len(sum); i += 3 {
+		var b1, b2 byte
+		n := 2
+		if i+1 < len(sum) {
+			b1, n = sum[i+1], 3
+		}
+		if i+2 < len(sum) {
+			b2, n = sum[i+2], 4
+		}
+		writeCryptB64(&b, b2, b1, sum[i], n)
+	}
+
+	if !equalHashes(b.String(), encoded) {
+		return user.ErrPasswordIncorrect
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// defaultQueueFactor sizes the queue of a Pool without an explicit depth, per
+// unit of concurrency.
+const defaultQueueFactor = 8
+
+// Pool runs Hash and Compare of another hasher on at most Concurrency
+// goroutines at a time, so that a burst of logins cannot take every CPU.
+// Up to QueueDepth calls wait for a slot; beyond that calls fail at once
+// with user.ErrHashingOverloaded.
+type Pool struct {
+	inner      user.PasswordHasher
+	slots      chan struct{}
+	queueDepth int64
+
+	queued    atomic.Int64
+	acquired  atomic.Uint64
+	rejected  atomic.Uint64
+	canceled  atomic.Uint64
+	totalWait atomic.Int64
+	maxWait   atomic.Int64
+}
+
+// PoolStats is a snapshot of a Pool. Waits cover the calls that got a slot.
+type PoolStats struct {
+	Concurrency int           `json:"concurrency"`
+	QueueDepth  int           `json:"queue_depth"`
+	Running     int           `json:"running"`
+	Queued      int           `json:"queued"`
+	Acquired    uint64        `json:"acquired"`
+	Rejected    uint64        `json:"rejected"`
+	Canceled    uint64        `json:"canceled"`
+	TotalWait   time.Duration `json:"total_wait_ns"`
+	MaxWait     time.Duration `json:"max_wait_ns"`
+}
+
+// AverageWait is the mean time calls queued before they got a slot.
+func (s PoolStats) AverageWait() time.Duration {
+	if s.Acquired == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Acquired)
+}
+
+// NewPool defaults concurrency to GOMAXPROCS and queueDepth to eight calls
+// per unit of concurrency.
+func NewPool(inner user.PasswordHasher, concurrency int, queueDepth int) *Pool {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	if queueDepth <= 0 {
+		queueDepth = defaultQueueFactor * concurrency
+	}
+	return &Pool{
+		inner:      inner,
+		slots:      make(chan struct{}, concurrency),
+		queueDepth: int64(queueDepth),
+	}
+}
+
+func (p *Pool) Hash(ctx context.Context, plaintext string) (string, error) {
+	if err := p.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer p.release()
+	return p.inner.Hash(ctx, plaintext)
+}
+
+func (p *Pool) Compare(ctx context.Context, hash string, plaintext string) error {
+	if err := p.acquire(ctx); err != nil {
+		return err
+	}
+	defer p.release()
+	return p.inner.Compare(ctx, hash, plaintext)
+}
+
+// NeedsRehash only parses the hash and does not take a slot.
+func (p *Pool) NeedsRehash(hash string) bool {
+	return p.inner.NeedsRehash(hash)
+}
+
+func (p *Pool) Stats() PoolStats {
+	return PoolStats{
+		Concurrency: cap(p.slots),
+		QueueDepth:  int(p.queueDepth),
+		Running:     len(p.slots),
+		Queued:      int(p.queued.Load()),
+		Acquired:    p.acquired.Load(),
+		Rejected:    p.rejected.Load(),
+		Canceled:    p.canceled.Load(),
+		TotalWait:   time.Duration(p.totalWait.Load()),
+		MaxWait:     time.Duration(p.maxWait.Load()),
+	}
+}
+
+func (p *Pool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		p.acquired.Add(1)
+		return nil
+	default:
+	}
+
+	if p.queued.Add(1) > p.queueDepth {
+		p.queued.Add(-1)
+		p.rejected.Add(1)
+		return user.ErrHashingOverloaded
+	}
+	defer p.queued.Add(-1)
+
+	start := time.Now()
+	select {
+	case p.slots <- struct{}{}:
+		p.recordWait(time.Since(start))
+		return nil
+	case <-ctx.Done():
+		p.canceled.Add(1)
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) release() {
+	<-p.slots
+}
+
+func (p *Pool) recordWait(wait time.Duration) {
+	p.acquired.Add(1)
+	p.totalWait.Add(int64(wait))
+	for {
+		current := p.maxWait.Load()
+		if int64(wait) <= current || p.maxWait.CompareAndSwap(current, int64(wait)) {
+			return
+		}
+	}
+}
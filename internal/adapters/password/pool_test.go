@@ -0,0 +1,88 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingHasher holds every call until release is closed.
+type blockingHasher struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHasher) Hash(ctx context.Context, plaintext string) (string, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return "hashed", nil
+}
+
+func (h *blockingHasher) Compare(ctx context.Context, hash string, plaintext string) error {
+	_, err := h.Hash(ctx, plaintext)
+	return err
+}
+
+func (h *blockingHasher) NeedsRehash(hash string) bool { return false }
+
+func TestPool_QueuesAndRejects(t *testing.T) {
+	inner := &blockingHasher{started: make(chan struct{}, 4), release: make(chan struct{})}
+	pool := NewPool(inner, 1, 1)
+	ctx := context.Background()
+
+	results := make(chan error, 2)
+	go func() { _, err := pool.Hash(ctx, "a"); results <- err }()
+	<-inner.started
+	go func() { results <- pool.Compare(ctx, "hashed", "b") }()
+	waitFor(t, func() bool { return pool.Stats().Queued == 1 })
+
+	if _, err := pool.Hash(ctx, "c"); !errors.Is(err, user.ErrHashingOverloaded) {
+		t.Fatalf("expected ErrHashingOverloaded, got: %v", err)
+	}
+
+	close(inner.release)
+	for range 2 {
+		if err := <-results; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	stats := pool.Stats()
+	if stats.Acquired != 2 || stats.Rejected != 1 || stats.Running != 0 || stats.Queued != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.MaxWait <= 0 || stats.AverageWait() <= 0 {
+		t.Fatalf("queued call must record its wait: %+v", stats)
+	}
+}
+
+func TestPool_CanceledWhileWaiting(t *testing.T) {
+	inner := &blockingHasher{started: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(inner.release)
+	pool := NewPool(inner, 1, 1)
+
+	go func() { _, _ = pool.Hash(context.Background(), "a") }()
+	<-inner.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Hash(ctx, "b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
+	}
+	if stats := pool.Stats(); stats.Canceled != 1 || stats.Queued != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
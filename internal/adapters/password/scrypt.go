@@ -0,0 +1,90 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// maxScryptMemory bounds the 128·N·r bytes a stored hash can make scrypt
+// allocate (1 GiB).
+const maxScryptMemory = 1 << 30
+
+// passlibB64 is base64 with "." for "+" and no padding, as passlib writes it.
+var passlibB64 = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").WithPadding(base64.NoPadding)
+
+// ScryptVerifier checks Django's scrypt$<salt>$<N>$<r>$<p>$<hash> and
+// passlib's $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>.
+type ScryptVerifier struct{}
+
+func (ScryptVerifier) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "scrypt$") || strings.HasPrefix(hash, "$scrypt$")
+}
+
+func (ScryptVerifier) Compare(ctx context.Context, encoded string, plaintext string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var salt, want []byte
+	var n, r, p int
+	var err error
+	if strings.HasPrefix(encoded, "$scrypt$") {
+		salt, want, n, r, p, err = parsePasslibScrypt(encoded)
+	} else {
+		salt, want, n, r, p, err = parseDjangoScrypt(encoded)
+	}
+	if err != nil {
+		return err
+	}
+	if n < 2 || n&(n-1) != 0 || r <= 0 || p <= 0 || 128*n*r > maxScryptMemory || r*p >= 1<<30 {
+		return fmt.Errorf("%w: scrypt parameters N=%d r=%d p=%d", ErrInvalidHash, n, r, p)
+	}
+
+	got, err := scrypt.Key([]byte(plaintext), salt, n, r, p, len(want))
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return user.ErrPasswordIncorrect
+	}
+	return nil
+}
+
+func parseDjangoScrypt(encoded string) (salt, key []byte, n, r, p int, err error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 6 {
+		return nil, nil, 0, 0, 0, fmt.Errorf("%w: django scrypt", ErrInvalidHash)
+	}
+	n, errN := strconv.Atoi(fields[2])
+	r, errR := strconv.Atoi(fields[3])
+	p, errP := strconv.Atoi(fields[4])
+	key, errKey := base64.StdEncoding.DecodeString(fields[5])
+	if errN != nil || errR != nil || errP != nil || errKey != nil || len(key) == 0 {
+		return nil, nil, 0, 0, 0, fmt.Errorf("%w: django scrypt", ErrInvalidHash)
+	}
+	return []byte(fields[1]), key, n, r, p, nil
+}
+
+func parsePasslibScrypt(encoded string) (salt, key []byte, n, r, p int, err error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 5 {
+		return nil, nil, 0, 0, 0, fmt.Errorf("%w: passlib scrypt", ErrInvalidHash)
+	}
+	var ln int
+	if _, err := fmt.Sscanf(fields[2], "ln=%d,r=%d,p=%d", &ln, &r, &p); err != nil || ln <= 0 || ln > 30 {
+		return nil, nil, 0, 0, 0, fmt.Errorf("%w: passlib scrypt parameters %q", ErrInvalidHash, fields[2])
+	}
+	salt, errSalt := passlibB64.DecodeString(fields[3])
+	key, errKey := passlibB64.DecodeString(fields[4])
+	if errSalt != nil || errKey != nil || len(key) == 0 {
+		return nil, nil, 0, 0, 0, fmt.Errorf("%w: passlib scrypt", ErrInvalidHash)
+	}
+	return salt, key, 1 << ln, r, p, nil
+}
@@ -0,0 +1,186 @@
+package password
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+const (
+	shaCryptDefaultRounds = 5000
+	shaCryptMinRounds     = 1000
+	// shaCryptMaxRounds is well below the 999999999 the format allows, which
+	// would keep a core busy for minutes per login.
+	shaCryptMaxRounds    = 10_000_000
+	shaCryptMaxSaltChars = 16
+)
+
+// SHACryptVerifier checks the SHA-256 ($5$) and SHA-512 ($6$) crypt(3)
+// hashes of glibc, with or without a rounds=<n>$ field.
+type SHACryptVerifier struct{}
+
+func (SHACryptVerifier) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$5$") || strings.HasPrefix(hash, "$6$")
+}
+
+func (SHACryptVerifier) Compare(ctx context.Context, encoded string, plaintext string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var newHash func() hash.Hash
+	var order [][3]int
+	switch {
+	case strings.HasPrefix(encoded, "$5$"):
+		newHash, order = sha256.New, sha256CryptOrder
+	case strings.HasPrefix(encoded, "$6$"):
+		newHash, order = sha512.New, sha512CryptOrder
+	default:
+		return ErrUnknownHashFormat
+	}
+
+	rest := encoded[3:]
+	rounds, customRounds := shaCryptDefaultRounds, false
+	if value, ok := strings.CutPrefix(rest, "rounds="); ok {
+		digits, after, found := strings.Cut(value, "$")
+		n, err := strconv.Atoi(digits)
+		if !found || err != nil || n > shaCryptMaxRounds {
+			return fmt.Errorf("%w: sha-crypt rounds", ErrInvalidHash)
+		}
+		rounds, customRounds, rest = max(n, shaCryptMinRounds), true, after
+	}
+	salt, _, found := strings.Cut(rest, "$")
+	if !found {
+		return fmt.Errorf("%w: sha-crypt", ErrInvalidHash)
+	}
+	if len(salt) > shaCryptMaxSaltChars {
+		salt = salt[:shaCryptMaxSaltChars]
+	}
+
+	sum := shaCrypt(newHash, []byte(plaintext), []byte(salt), rounds)
+	var b strings.Builder
+	b.WriteString(encoded[:3])
+	if customRounds {
+		fmt.Fprintf(&b, "rounds=%d$", rounds)
+	}
+	b.WriteString(salt)
+	b.WriteByte('$')
+	for _, group := range order {
+		var bytes [3]byte
+		chars := 4
+		for i, idx := range group {
+			if idx < 0 {
+				chars--
+				continue
+			}
+			bytes[i] = sum[idx]
+		}
+		writeCryptB64(&b, bytes[0], bytes[1], bytes[2], chars)
+	}
+
+	if !equalHashes(b.String(), encoded) {
+		return user.ErrPasswordIncorrect
+	}
+	return nil
+}
+
+// shaCrypt is the digest of Ulrich Drepper's "Unix crypt using SHA-256 and
+// SHA-512" specification.
+func shaCrypt(newHash func() hash.Hash, password, salt []byte, rounds int) []byte {
+	h := newHash()
+	size := h.Size()
+
+	h.Write(password)
+	h.Write(salt)
+	h.Write(password)
+	b := h.Sum(nil)
+
+	h.Reset()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(repeatTo(b, len(password)))
+	for n := len(password); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			h.Write(b)
+		} else {
+			h.Write(password)
+		}
+	}
+	a := h.Sum(nil)
+
+	h.Reset()
+	for range len(password) {
+		h.Write(password)
+	}
+	p := repeatTo(h.Sum(nil), len(password))
+
+	h.Reset()
+	for range 16 + int(a[0]) {
+		h.Write(salt)
+	}
+	s := repeatTo(h.Sum(nil), len(salt))
+
+	c := a
+	for i := range rounds {
+		h.Reset()
+		if i%2 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i%2 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(c[:0:size])
+	}
+	return c
+}
+
+// repeatTo repeats b until it is n bytes long.
+func repeatTo(b []byte, n int) []byte {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		out = append(out, b[:min(len(b), n-len(out))]...)
+	}
+	return out
+}
+
+// writeCryptB64 writes the n low 6-bit groups of the 24 bits b2 b1 b0, least
+// significant first.
+func writeCryptB64(b *strings.Builder, b2, b1, b0 byte, n int) {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for range n {
+		b.WriteByte(cryptB64[w&0x3f])
+		w >>= 6
+	}
+}
+
+// The byte triples the digests are encoded in. -1 stands for a zero byte,
+// and every one of them drops a character from the group.
+var (
+	sha256CryptOrder = [][3]int{
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+		{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+		{-1, 31, 30},
+	}
+	sha512CryptOrder = [][3]int{
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+		{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+		{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+		{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+		{62, 20, 41}, {-1, -1, 63},
+	}
+)
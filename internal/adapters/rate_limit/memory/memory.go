@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/transport/http/middleware/ratelimit"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many calls pass between drops of idle quotas.
+const sweepEvery = 1024
+
+type bucket struct {
+	state ratelimit.BucketState
+	// expiresAt is when the bucket is full again and can be forgotten.
+	expiresAt time.Time
+}
+
+type window struct {
+	hits      []time.Time
+	expiresAt time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucket
+	windows map[string]window
+	calls   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]bucket),
+		windows: make(map[string]window),
+	}
+}
+
+func (s *MemoryStore) Allow(ctx context.Context, key string, rule ratelimit.Rule, now time.Time) (ratelimit.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	var res ratelimit.Result
+	switch rule.Algorithm {
+	case ratelimit.TokenBucket:
+		b := s.buckets[key]
+		if now.After(b.expiresAt) {
+			b = bucket{}
+		}
+		b.state, res = rule.TakeToken(b.state, now)
+		b.expiresAt = now.Add(res.Reset)
+		s.buckets[key] = b
+	case ratelimit.SlidingWindow:
+		w := s.windows[key]
+		w.hits, res = rule.TakeSlot(w.hits, now)
+		w.expiresAt = now.Add(rule.Period)
+		s.windows[key] = w
+	default:
+		return ratelimit.Result{}, ratelimit.ErrUnknownAlgorithm
+	}
+	return res, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.After(b.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, w := range s.windows {
+		if now.After(w.expiresAt) {
+			delete(s.windows, key)
+		}
+	}
+}
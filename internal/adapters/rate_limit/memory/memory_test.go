@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/transport/http/middleware/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_KeysAreSeparate(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	rule := ratelimit.Rule{Algorithm: ratelimit.TokenBucket, Limit: 1, Period: time.Hour}
+	now := time.Now()
+
+	if res, _ := store.Allow(ctx, "a", rule, now); !res.Allowed {
+		t.Fatalf("first request must pass: %+v", res)
+	}
+	if res, _ := store.Allow(ctx, "a", rule, now); res.Allowed {
+		t.Fatalf("second request must be refused: %+v", res)
+	}
+	if res, _ := store.Allow(ctx, "b", rule, now); !res.Allowed {
+		t.Fatalf("other key must have its own bucket: %+v", res)
+	}
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	rule := ratelimit.Rule{Algorithm: ratelimit.SlidingWindow, Limit: 1, Period: time.Minute}
+	now := time.Now()
+
+	_, _ = store.Allow(ctx, "a", rule, now)
+	if res, _ := store.Allow(ctx, "a", rule, now.Add(59*time.Second)); res.Allowed {
+		t.Fatalf("request within the window must be refused: %+v", res)
+	}
+	if res, _ := store.Allow(ctx, "a", rule, now.Add(time.Minute)); !res.Allowed {
+		t.Fatalf("request after the window must pass: %+v", res)
+	}
+}
@@ -0,0 +1,128 @@
+package redis
+
+import (
+	"context"
+	"crud/internal/transport/http/middleware/ratelimit"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills the bucket in KEYS[1] at ARGV[2] tokens per
+// millisecond up to ARGV[3] as of ARGV[1] and takes a token. The key expires
+// once the bucket would be full again.
+var tokenBucketScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+// slidingWindowScript keeps the times of requests of the last ARGV[2]
+// milliseconds in the sorted set KEYS[1] and adds ARGV[4] at ARGV[1] if fewer
+// than ARGV[3] are left.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - period)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[4])
+  count = count + 1
+  allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], period)
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return {allowed, count, oldest[2]}
+`)
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func rateLimitKey(key string) string {
+	return fmt.Sprintf("rate_limit:%s", key)
+}
+
+func (s *RedisStore) Allow(ctx context.Context, key string, rule ratelimit.Rule, now time.Time) (ratelimit.Result, error) {
+	if ctx.Err() != nil {
+		return ratelimit.Result{}, ctx.Err()
+	}
+
+	switch rule.Algorithm {
+	case ratelimit.TokenBucket:
+		return s.takeToken(ctx, rateLimitKey(key), rule, now)
+	case ratelimit.SlidingWindow:
+		return s.takeSlot(ctx, rateLimitKey(key), rule, now)
+	default:
+		return ratelimit.Result{}, ratelimit.ErrUnknownAlgorithm
+	}
+}
+
+func (s *RedisStore) takeToken(ctx context.Context, key string, rule ratelimit.Rule, now time.Time) (ratelimit.Result, error) {
+	reply, err := tokenBucketScript.Run(ctx, s.client, []string{key},
+		now.UnixMilli(), strconv.FormatFloat(rule.TokensPerMilli(), 'g', -1, 64), rule.Capacity()).Slice()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	if len(reply) != 2 {
+		return ratelimit.Result{}, fmt.Errorf("rate limit: unexpected reply %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(reply[1]), 64)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return rule.BucketResult(tokens, allowed == 1), nil
+}
+
+func (s *RedisStore) takeSlot(ctx context.Context, key string, rule ratelimit.Rule, now time.Time) (ratelimit.Result, error) {
+	// Members have to be unique, or requests in the same millisecond would
+	// count once.
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return ratelimit.Result{}, err
+	}
+	member := strconv.FormatInt(now.UnixMilli(), 10) + "-" + hex.EncodeToString(suffix)
+
+	reply, err := slidingWindowScript.Run(ctx, s.client, []string{key},
+		now.UnixMilli(), rule.Period.Milliseconds(), rule.Limit, member).Slice()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	if len(reply) != 3 {
+		return ratelimit.Result{}, fmt.Errorf("rate limit: unexpected reply %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	count, _ := reply[1].(int64)
+	oldest, err := strconv.ParseInt(fmt.Sprint(reply[2]), 10, 64)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return rule.WindowResult(int(count), time.UnixMilli(oldest), allowed == 1, now), nil
+}
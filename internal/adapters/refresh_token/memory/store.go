@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	token user.RefreshToken
+	used  bool
+}
+
+type MemoryStore struct {
+	mu       sync.Mutex
+	tokens   map[string]*entry
+	families map[string]map[string]struct{}
+	byUser   map[string]map[string]struct{}
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens:   make(map[string]*entry),
+		families: make(map[string]map[string]struct{}),
+		byUser:   make(map[string]map[string]struct{}),
+	}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, token user.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token.Hash] = &entry{token: token}
+	addToSet(s.families, token.FamilyID, token.Hash)
+	addToSet(s.byUser, token.UserID, token.FamilyID)
+	return nil
+}
+
+func (s *MemoryStore) Consume(ctx context.Context, hash string) (user.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return user.RefreshToken{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.tokens[hash]
+	if !ok {
+		return user.RefreshToken{}, user.ErrRefreshTokenInvalid
+	}
+	if time.Now().UTC().After(e.token.ExpiresAt) {
+		delete(s.tokens, hash)
+		delete(s.families[e.token.FamilyID], hash)
+		return user.RefreshToken{}, user.ErrRefreshTokenInvalid
+	}
+	if e.used {
+		return e.token, user.ErrRefreshTokenReused
+	}
+
+	e.used = true
+	return e.token, nil
+}
+
+func (s *MemoryStore) RevokeFamily(ctx context.Context, familyID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeFamily(familyID)
+	return nil
+}
+
+func (s *MemoryStore) DeleteByUser(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for familyID := range s.byUser[userID] {
+		s.revokeFamily(familyID)
+	}
+	delete(s.byUser, userID)
+	return nil
+}
+
+// revokeFamily drops every token of the family. Callers must hold s.mu.
+func (s *MemoryStore) revokeFamily(familyID string) {
+	for hash := range s.families[familyID] {
+		delete(s.tokens, hash)
+	}
+	delete(s.families, familyID)
+}
+
+func addToSet(sets map[string]map[string]struct{}, key string, member string) {
+	set, ok := sets[key]
+	if !ok {
+		set = make(map[string]struct{})
+		sets[key] = set
+	}
+	set[member] = struct{}{}
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_ConsumeOnce(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	token := user.RefreshToken{Hash: "h1", FamilyID: "f1", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := store.Create(ctx, token); err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	consumed, err := store.Consume(ctx, "h1")
+	if err != nil || consumed != token {
+		t.Fatalf("unexpected consume result: %+v, %v", consumed, err)
+	}
+
+	consumed, err = store.Consume(ctx, "h1")
+	if err != user.ErrRefreshTokenReused || consumed.FamilyID != "f1" {
+		t.Fatalf("expected reuse with family, got: %+v, %v", consumed, err)
+	}
+
+	if _, err := store.Consume(ctx, "missing"); err != user.ErrRefreshTokenInvalid {
+		t.Fatalf("expected ErrRefreshTokenInvalid, got: %v", err)
+	}
+}
+
+func TestMemoryStore_Expired(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_ = store.Create(ctx, user.RefreshToken{Hash: "h1", FamilyID: "f1", UserID: "1", ExpiresAt: time.Now().Add(-time.Second)})
+	if _, err := store.Consume(ctx, "h1"); err != user.ErrRefreshTokenInvalid {
+		t.Fatalf("expected ErrRefreshTokenInvalid, got: %v", err)
+	}
+}
+
+func TestMemoryStore_DeleteByUser(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	_ = store.Create(ctx, user.RefreshToken{Hash: "h1", FamilyID: "f1", UserID: "1", ExpiresAt: expiresAt})
+	_ = store.Create(ctx, user.RefreshToken{Hash: "h2", FamilyID: "f2", UserID: "1", ExpiresAt: expiresAt})
+	_ = store.Create(ctx, user.RefreshToken{Hash: "h3", FamilyID: "f3", UserID: "2", ExpiresAt: expiresAt})
+
+	if err := store.DeleteByUser(ctx, "1"); err != nil {
+		t.Fatalf("failed to delete tokens: %v", err)
+	}
+	for _, hash := range []string{"h1", "h2"} {
+		if _, err := store.Consume(ctx, hash); err != user.ErrRefreshTokenInvalid {
+			t.Fatalf("expected %s to be revoked, got: %v", hash, err)
+		}
+	}
+	if _, err := store.Consume(ctx, "h3"); err != nil {
+		t.Fatalf("expected other user's token to survive, got: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func tokenKey(hash string) string {
+	return fmt.Sprintf("refresh_token:%s", hash)
+}
+
+// usedKey marks a token as rotated. It is kept for the remaining lifetime of
+// the token so that a replay can be told apart from an unknown token.
+func usedKey(hash string) string {
+	return fmt.Sprintf("refresh_token_used:%s", hash)
+}
+
+func familyKey(familyID string) string {
+	return fmt.Sprintf("refresh_family:%s", familyID)
+}
+
+func userFamiliesKey(userID string) string {
+	return fmt.Sprintf("user_refresh_families:%s", userID)
+}
+
+func (s *RedisStore) Create(ctx context.Context, token user.RefreshToken) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return user.ErrRefreshTokenInvalid
+	}
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, tokenKey(token.Hash), payload, ttl)
+		pipe.SAdd(ctx, familyKey(token.FamilyID), token.Hash)
+		pipe.Expire(ctx, familyKey(token.FamilyID), ttl)
+		pipe.SAdd(ctx, userFamiliesKey(token.UserID), token.FamilyID)
+		pipe.Expire(ctx, userFamiliesKey(token.UserID), ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) Consume(ctx context.Context, hash string) (user.RefreshToken, error) {
+	if ctx.Err() != nil {
+		return user.RefreshToken{}, ctx.Err()
+	}
+
+	data, err := s.client.Get(ctx, tokenKey(hash)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return user.RefreshToken{}, user.ErrRefreshTokenInvalid
+		}
+		return user.RefreshToken{}, err
+	}
+
+	var token user.RefreshToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return user.RefreshToken{}, err
+	}
+
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return user.RefreshToken{}, user.ErrRefreshTokenInvalid
+	}
+
+	first, err := s.client.SetNX(ctx, usedKey(hash), 1, ttl).Result()
+	if err != nil {
+		return user.RefreshToken{}, err
+	}
+	if !first {
+		return token, user.ErrRefreshTokenReused
+	}
+
+	return token, nil
+}
+
+func (s *RedisStore) RevokeFamily(ctx context.Context, familyID string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	hashes, err := s.client.SMembers(ctx, familyKey(familyID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, 2*len(hashes)+1)
+	for _, hash := range hashes {
+		keys = append(keys, tokenKey(hash), usedKey(hash))
+	}
+	keys = append(keys, familyKey(familyID))
+
+	return s.client.Del(ctx, keys...).Err()
+}
+
+func (s *RedisStore) DeleteByUser(ctx context.Context, userID string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	familyIDs, err := s.client.SMembers(ctx, userFamiliesKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, familyID := range familyIDs {
+		if err := s.RevokeFamily(ctx, familyID); err != nil {
+			return err
+		}
+	}
+
+	return s.client.Del(ctx, userFamiliesKey(userID)).Err()
+}
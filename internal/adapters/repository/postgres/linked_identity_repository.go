@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LinkedIdentityRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewLinkedIdentityRepository(pool *pgxpool.Pool) *LinkedIdentityRepository {
+	return &LinkedIdentityRepository{pool: pool}
+}
+
+func (r *LinkedIdentityRepository) Create(ctx context.Context, identity entities.LinkedIdentity) error {
+	const insert = `
+		INSERT INTO linked_identities (provider, subject, user_id, email, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	_, err := r.pool.Exec(ctx, insert, identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create linked identity: %w", err)
+	}
+	return nil
+}
+
+func (r *LinkedIdentityRepository) FindOne(ctx context.Context, provider string, subject string, ent *entities.LinkedIdentity) error {
+	const query = `
+		SELECT provider, subject, user_id, email, created_at
+		FROM linked_identities WHERE provider = $1 AND subject = $2`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err := r.pool.QueryRow(ctx, query, provider, subject).Scan(&ent.Provider, &ent.Subject, &ent.UserID, &ent.Email, &ent.CreatedAt)
+	if err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return user.ErrLinkedIdentityNotFound
+		}
+		return err
+	}
+	return nil
+}
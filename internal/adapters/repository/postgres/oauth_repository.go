@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/oidc"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/samber/mo"
+)
+
+const oauthClientColumns = `id, owner_id, name, secret_hash, redirect_uris, created_at`
+
+type OAuthClientRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOAuthClientRepository(pool *pgxpool.Pool) *OAuthClientRepository {
+	return &OAuthClientRepository{pool: pool}
+}
+
+func (r *OAuthClientRepository) Create(ctx context.Context, client entities.OAuthClient) error {
+	const insert = `
+		INSERT INTO oauth_clients (id, owner_id, name, secret_hash, redirect_uris, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	_, err := r.pool.Exec(ctx, insert, client.ID, client.OwnerID, client.Name, client.SecretHash.ToPointer(), client.RedirectURIs, client.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	return nil
+}
+
+func (r *OAuthClientRepository) FindOne(ctx context.Context, id string, ent *entities.OAuthClient) error {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE id = $1`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err := scanOAuthClient(r.pool.QueryRow(ctx, query, id), ent); err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return oidc.ErrClientNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *OAuthClientRepository) FindByOwner(ctx context.Context, ownerID string) ([]entities.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE owner_id = $1 ORDER BY created_at DESC`
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	rows, err := r.pool.Query(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ents := []entities.OAuthClient{}
+	for rows.Next() {
+		var ent entities.OAuthClient
+		if err = scanOAuthClient(rows, &ent); err != nil {
+			return nil, err
+		}
+		ents = append(ents, ent)
+	}
+	return ents, rows.Err()
+}
+
+func (r *OAuthClientRepository) Delete(ctx context.Context, id string, ownerID string) error {
+	const del = `DELETE FROM oauth_clients WHERE id = $1 AND owner_id = $2`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	tag, err := r.pool.Exec(ctx, del, id, ownerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return oidc.ErrClientNotFound
+	}
+	return nil
+}
+
+func scanOAuthClient(row pgx.Row, ent *entities.OAuthClient) error {
+	var secretHash *string
+	err := row.Scan(&ent.ID, &ent.OwnerID, &ent.Name, &secretHash, &ent.RedirectURIs, &ent.CreatedAt)
+	if err != nil {
+		return err
+	}
+	ent.SecretHash = mo.PointerToOption(secretHash)
+	return nil
+}
+
+type OAuthConsentRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOAuthConsentRepository(pool *pgxpool.Pool) *OAuthConsentRepository {
+	return &OAuthConsentRepository{pool: pool}
+}
+
+func (r *OAuthConsentRepository) FindOne(ctx context.Context, userID string, clientID string, ent *entities.OAuthConsent) error {
+	const query = `
+		SELECT user_id, client_id, scopes, granted_at
+		FROM oauth_consents WHERE user_id = $1 AND client_id = $2`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err := r.pool.QueryRow(ctx, query, userID, clientID).Scan(&ent.UserID, &ent.ClientID, &ent.Scopes, &ent.GrantedAt)
+	if err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return oidc.ErrConsentNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *OAuthConsentRepository) Save(ctx context.Context, consent entities.OAuthConsent) error {
+	const upsert = `
+		INSERT INTO oauth_consents (user_id, client_id, scopes, granted_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, client_id) DO UPDATE
+		SET scopes = EXCLUDED.scopes, granted_at = EXCLUDED.granted_at`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	_, err := r.pool.Exec(ctx, upsert, consent.UserID, consent.ClientID, consent.Scopes, consent.GrantedAt)
+	return err
+}
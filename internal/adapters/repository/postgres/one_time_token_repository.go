@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OneTimeTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOneTimeTokenRepository(pool *pgxpool.Pool) *OneTimeTokenRepository {
+	return &OneTimeTokenRepository{pool: pool}
+}
+
+func (r *OneTimeTokenRepository) Create(ctx context.Context, token entities.OneTimeToken) error {
+	const insert = `
+		INSERT INTO one_time_tokens (token_hash, user_id, purpose, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	_, err := r.pool.Exec(ctx, insert, token.Hash, token.UserID, token.Purpose, token.CreatedAt, token.ExpiresAt)
+	return err
+}
+
+// Consume deletes the token and returns it, so that it can be used only once
+// even under concurrent requests. Expiry is checked by the caller.
+func (r *OneTimeTokenRepository) Consume(ctx context.Context, hash string, purpose string, ent *entities.OneTimeToken) error {
+	const del = `
+		DELETE FROM one_time_tokens WHERE token_hash = $1 AND purpose = $2
+		RETURNING token_hash, user_id, purpose, created_at, expires_at`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err := r.pool.QueryRow(ctx, del, hash, purpose).Scan(&ent.Hash, &ent.UserID, &ent.Purpose, &ent.CreatedAt, &ent.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return user.ErrOneTimeTokenInvalid
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *OneTimeTokenRepository) DeleteByUser(ctx context.Context, userID string, purpose string) error {
+	const del = `DELETE FROM one_time_tokens WHERE user_id = $1 AND purpose = $2`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	_, err := r.pool.Exec(ctx, del, userID, purpose)
+	return err
+}
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/samber/mo"
+)
+
+const personalAccessTokenColumns = `id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at`
+
+type PersonalAccessTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPersonalAccessTokenRepository(pool *pgxpool.Pool) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{pool: pool}
+}
+
+func (r *PersonalAccessTokenRepository) Create(ctx context.Context, attrs entities.PersonalAccessTokenAttrs, ent *entities.PersonalAccessToken) error {
+	insert := `
+		INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + personalAccessTokenColumns
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	scopes := attrs.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	row := r.pool.QueryRow(ctx, insert, attrs.ID, attrs.UserID, attrs.Name, attrs.TokenHash, scopes, attrs.ExpiresAt.ToPointer())
+	if err := scanPersonalAccessToken(row, ent); err != nil {
+		return fmt.Errorf("failed to create personal access token: %w", err)
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) FindOne(ctx context.Context, filterAttrs entities.PersonalAccessTokenFilterAttrs, ent *entities.PersonalAccessToken) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	clauses, args := personalAccessTokenFilter(filterAttrs)
+	if len(clauses) == 0 {
+		return ErrEmptyFilterAttrs
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM personal_access_tokens WHERE %s LIMIT 1`, personalAccessTokenColumns, strings.Join(clauses, " AND "))
+
+	row := r.pool.QueryRow(ctx, query, args...)
+	if err := scanPersonalAccessToken(row, ent); err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return user.ErrPersonalTokenNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) Find(ctx context.Context, filterAttrs entities.PersonalAccessTokenFilterAttrs) ([]entities.PersonalAccessToken, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	clauses, args := personalAccessTokenFilter(filterAttrs)
+	if len(clauses) == 0 {
+		return nil, ErrEmptyFilterAttrs
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM personal_access_tokens WHERE %s ORDER BY created_at DESC`, personalAccessTokenColumns, strings.Join(clauses, " AND "))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ents := []entities.PersonalAccessToken{}
+	for rows.Next() {
+		var ent entities.PersonalAccessToken
+		if err = scanPersonalAccessToken(rows, &ent); err != nil {
+			return nil, err
+		}
+		ents = append(ents, ent)
+	}
+	return ents, rows.Err()
+}
+
+func (r *PersonalAccessTokenRepository) Delete(ctx context.Context, filterAttrs entities.PersonalAccessTokenFilterAttrs) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	clauses, args := personalAccessTokenFilter(filterAttrs)
+	if len(clauses) == 0 {
+		return ErrEmptyFilterAttrs
+	}
+
+	query := fmt.Sprintf(`DELETE FROM personal_access_tokens WHERE %s`, strings.Join(clauses, " AND "))
+	tag, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return user.ErrPersonalTokenNotFound
+	}
+	return nil
+}
+
+func (r *PersonalAccessTokenRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	const update = `UPDATE personal_access_tokens SET last_used_at = $2 WHERE id = $1`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	_, err := r.pool.Exec(ctx, update, id, usedAt)
+	return err
+}
+
+func personalAccessTokenFilter(filterAttrs entities.PersonalAccessTokenFilterAttrs) ([]string, []any) {
+	clauses := []string{}
+	args := []any{}
+
+	if v, ok := filterAttrs.ID.Get(); ok {
+		args = append(args, v)
+		clauses = append(clauses, fmt.Sprintf("id = $%d", len(args)))
+	}
+	if v, ok := filterAttrs.UserID.Get(); ok {
+		args = append(args, v)
+		clauses = append(clauses, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if v, ok := filterAttrs.TokenHash.Get(); ok {
+		args = append(args, v)
+		clauses = append(clauses, fmt.Sprintf("token_hash = $%d", len(args)))
+	}
+	return clauses, args
+}
+
+func scanPersonalAccessToken(row pgx.Row, ent *entities.PersonalAccessToken) error {
+	var expiresAt, lastUsedAt *time.Time
+	err := row.Scan(&ent.ID, &ent.UserID, &ent.Name, &ent.TokenHash, &ent.Scopes, &ent.CreatedAt, &expiresAt, &lastUsedAt)
+	if err != nil {
+		return err
+	}
+	ent.ExpiresAt = mo.PointerToOption(expiresAt)
+	ent.LastUsedAt = mo.PointerToOption(lastUsedAt)
+	return nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/samber/mo"
+)
+
+type TwoFactorRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTwoFactorRepository(pool *pgxpool.Pool) *TwoFactorRepository {
+	return &TwoFactorRepository{pool: pool}
+}
+
+func (r *TwoFactorRepository) FindOne(ctx context.Context, userID string, ent *entities.TwoFactor) error {
+	const query = `
+		SELECT user_id, secret, created_at, confirmed_at, last_used_step, recovery_codes
+		FROM two_factor WHERE user_id = $1`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var confirmedAt *time.Time
+	err := r.pool.QueryRow(ctx, query, userID).Scan(
+		&ent.UserID, &ent.Secret, &ent.CreatedAt, &confirmedAt, &ent.LastUsedStep, &ent.RecoveryCodes)
+	if err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return user.ErrTwoFactorNotEnrolled
+		}
+		return err
+	}
+	ent.ConfirmedAt = mo.PointerToOption(confirmedAt)
+	return nil
+}
+
+func (r *TwoFactorRepository) SavePending(ctx context.Context, userID string, secret string) error {
+	// Enrolling again replaces an unconfirmed secret but never a confirmed one.
+	const upsert = `
+		INSERT INTO two_factor (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = now(), last_used_step = 0, recovery_codes = '{}'
+		WHERE two_factor.confirmed_at IS NULL`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	tag, err := r.pool.Exec(ctx, upsert, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return user.ErrTwoFactorAlreadyEnabled
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) Confirm(ctx context.Context, userID string, step int64, confirmedAt time.Time, recoveryCodes []string) error {
+	const update = `
+		UPDATE two_factor
+		SET confirmed_at = $2, last_used_step = $3, recovery_codes = $4
+		WHERE user_id = $1 AND confirmed_at IS NULL`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	tag, err := r.pool.Exec(ctx, update, userID, confirmedAt, step, recoveryCodes)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return user.ErrTwoFactorAlreadyEnabled
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID string, step int64) error {
+	const update = `UPDATE two_factor SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	tag, err := r.pool.Exec(ctx, update, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return user.ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash string) error {
+	const update = `
+		UPDATE two_factor SET recovery_codes = array_remove(recovery_codes, $2)
+		WHERE user_id = $1 AND $2 = ANY(recovery_codes)`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	tag, err := r.pool.Exec(ctx, update, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return user.ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) Delete(ctx context.Context, userID string) error {
+	const del = `DELETE FROM two_factor WHERE user_id = $1`
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	tag, err := r.pool.Exec(ctx, del, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return user.ErrTwoFactorNotEnrolled
+	}
+	return nil
+}
@@ -7,11 +7,16 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/samber/mo"
 )
 
+const userColumns = `id, username, email, hashed_password, email_verified_at, verification_sent_at`
+
 type UserRepository struct {
 	pool *pgxpool.Pool
 }
@@ -24,7 +29,7 @@ func (r *UserRepository) Create(ctx context.Context, attrs entities.UserAttrs, e
 	const insert = `
 		INSERT INTO users (id, username, email, hashed_password)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, username, email, hashed_password`
+		RETURNING ` + userColumns
 
 	if ctx.Err() != nil {
 		return ctx.Err()
@@ -32,7 +37,7 @@ func (r *UserRepository) Create(ctx context.Context, attrs entities.UserAttrs, e
 
 	row := r.pool.QueryRow(ctx, insert, attrs.ID, attrs.Username, attrs.Email, attrs.HashedPassword)
 
-	err := row.Scan(&ent.ID, &ent.Username, &ent.Email, &ent.HashedPassword)
+	err := scanUser(row, ent)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -74,11 +79,11 @@ func (r *UserRepository) FindOne(ctx context.Context, filterAttrs entities.UserF
 		return ErrEmptyFilterAttrs
 	}
 
-	query := fmt.Sprintf(`SELECT id, username, email, hashed_password FROM users WHERE %s LIMIT 1`, strings.Join(clauses, " AND "))
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE %s LIMIT 1`, userColumns, strings.Join(clauses, " AND "))
 
 	row := r.pool.QueryRow(ctx, query, args...)
 
-	if err := row.Scan(&ent.ID, &ent.Username, &ent.Email, &ent.HashedPassword); err != nil {
+	if err := scanUser(row, ent); err != nil {
 		if errors.Is(err, ErrNoRows) {
 			return user.ErrUserNotFound
 		}
@@ -111,7 +116,7 @@ func (r *UserRepository) Find(ctx context.Context, filterAttrs entities.UserFilt
 		return nil, ErrEmptyFilterAttrs
 	}
 
-	query := fmt.Sprintf(`SELECT id, username, email, hashed_password FROM users WHERE %s`, strings.Join(clauses, " AND "))
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE %s`, userColumns, strings.Join(clauses, " AND "))
 
 	var ents []entities.User
 	rows, err := r.pool.Query(ctx, query, args...)
@@ -121,7 +126,7 @@ func (r *UserRepository) Find(ctx context.Context, filterAttrs entities.UserFilt
 	defer rows.Close()
 	for rows.Next() {
 		var ent entities.User
-		if err = rows.Scan(&ent.ID, &ent.Username, &ent.Email, &ent.HashedPassword); err != nil {
+		if err = scanUser(rows, &ent); err != nil {
 			return nil, err
 		}
 		ents = append(ents, ent)
@@ -159,6 +164,11 @@ func (r *UserRepository) Update(ctx context.Context, attrs entities.UserUpdateAt
 	if v, ok := attrs.Email.Get(); ok {
 		args = append(args, v)
 		attrsClauses = append(attrsClauses, fmt.Sprintf("email = $%d", len(args)))
+		if attrs.EmailVerifiedAt.IsAbsent() {
+			// A verification only vouches for the address it was sent to.
+			attrsClauses = append(attrsClauses, fmt.Sprintf(
+				"email_verified_at = CASE WHEN email = $%d THEN email_verified_at END", len(args)))
+		}
 	}
 	if v, ok := attrs.Username.Get(); ok {
 		args = append(args, v)
@@ -168,18 +178,27 @@ func (r *UserRepository) Update(ctx context.Context, attrs entities.UserUpdateAt
 		args = append(args, v)
 		attrsClauses = append(attrsClauses, fmt.Sprintf("hashed_password = $%d", len(args)))
 	}
+	if v, ok := attrs.EmailVerifiedAt.Get(); ok {
+		args = append(args, v)
+		attrsClauses = append(attrsClauses, fmt.Sprintf("email_verified_at = $%d", len(args)))
+	}
+	if v, ok := attrs.VerificationSentAt.Get(); ok {
+		args = append(args, v)
+		attrsClauses = append(attrsClauses, fmt.Sprintf("verification_sent_at = $%d", len(args)))
+	}
 
 	if len(attrsClauses) == 0 {
 		return ErrNoUpdateAttrs
 	}
 
-	query := fmt.Sprintf(`UPDATE users SET %s WHERE %s RETURNING id, username, email, hashed_password`,
+	query := fmt.Sprintf(`UPDATE users SET %s WHERE %s RETURNING %s`,
 		strings.Join(attrsClauses, ", "),
-		strings.Join(filterClauses, " AND "))
+		strings.Join(filterClauses, " AND "),
+		userColumns)
 
 	row := r.pool.QueryRow(ctx, query, args...)
 
-	err := row.Scan(&ent.ID, &ent.Username, &ent.Email, &ent.HashedPassword)
+	err := scanUser(row, ent)
 	if err != nil {
 		if errors.Is(err, ErrNoRows) {
 			return user.ErrUserNotFound
@@ -234,3 +253,14 @@ func (r *UserRepository) Delete(ctx context.Context, filterAttrs entities.UserFi
 	}
 	return nil
 }
+
+func scanUser(row pgx.Row, ent *entities.User) error {
+	var emailVerifiedAt, verificationSentAt *time.Time
+	err := row.Scan(&ent.ID, &ent.Username, &ent.Email, &ent.HashedPassword, &emailVerifiedAt, &verificationSentAt)
+	if err != nil {
+		return err
+	}
+	ent.EmailVerifiedAt = mo.PointerToOption(emailVerifiedAt)
+	ent.VerificationSentAt = mo.PointerToOption(verificationSentAt)
+	return nil
+}
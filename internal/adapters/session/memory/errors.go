@@ -1,9 +1,12 @@
 package memory
 
-import "errors"
+import (
+	"crud/internal/services/user"
+	"errors"
+)
 
 var (
 	ErrInvalidTtl      = errors.New("ttl can not be 0")
-	ErrSessionNotFound = errors.New("session not found")
-	ErrSessionExpired  = errors.New("session expired")
+	ErrSessionNotFound = user.ErrSessionNotFound
+	ErrSessionExpired  = user.ErrSessionExpired
 )
@@ -12,12 +12,13 @@ import (
 type MemoryStore struct {
 	mu       sync.RWMutex
 	sessions map[string]user.Session
-	ttl      time.Duration
+	byUser   map[string]map[string]struct{}
+	lifetime user.SessionLifetime
 	idGen    func() (string, error)
 }
 
-func NewMemoryStore(ttl time.Duration, idGen func() (string, error)) (*MemoryStore, error) {
-	if ttl <= 0 {
+func NewMemoryStore(lifetime user.SessionLifetime, idGen func() (string, error)) (*MemoryStore, error) {
+	if lifetime.IdleTimeout <= 0 {
 		return nil, ErrInvalidTtl
 	}
 	if idGen == nil {
@@ -27,12 +28,13 @@ func NewMemoryStore(ttl time.Duration, idGen func() (string, error)) (*MemorySto
 	}
 	return &MemoryStore{
 		sessions: make(map[string]user.Session),
-		ttl:      ttl,
+		byUser:   make(map[string]map[string]struct{}),
+		lifetime: lifetime,
 		idGen:    idGen,
 	}, nil
 }
 
-func (s *MemoryStore) Create(ctx context.Context, userID string) (user.Session, error) {
+func (s *MemoryStore) Create(ctx context.Context, attrs user.SessionAttrs) (user.Session, error) {
 	if err := ctx.Err(); err != nil {
 		return user.Session{}, err
 	}
@@ -45,9 +47,26 @@ func (s *MemoryStore) Create(ctx context.Context, userID string) (user.Session,
 		return user.Session{}, err
 	}
 
-	session := user.Session{ID: id, UserID: userID, ExpiresAt: time.Now().UTC().Add(s.ttl)}
+	timeNow := time.Now().UTC()
+	session := user.Session{
+		ID:         id,
+		UserID:     attrs.UserID,
+		IP:         attrs.IP,
+		UserAgent:  attrs.UserAgent,
+		CreatedAt:  timeNow,
+		LastSeenAt: timeNow,
+		Persistent: attrs.Persistent,
+	}
+	session.ExpiresAt = s.lifetime.ExpiresAt(session, timeNow)
 	s.sessions[id] = session
 
+	ids, ok := s.byUser[attrs.UserID]
+	if !ok {
+		ids = make(map[string]struct{})
+		s.byUser[attrs.UserID] = ids
+	}
+	ids[id] = struct{}{}
+
 	return session, nil
 }
 
@@ -68,7 +87,7 @@ func (s *MemoryStore) Get(ctx context.Context, sessionID string) (user.Session,
 	if timeNow.After(session.ExpiresAt) {
 		s.mu.Lock()
 		if sess, ok := s.sessions[sessionID]; ok && timeNow.After(sess.ExpiresAt) {
-			delete(s.sessions, sessionID)
+			s.remove(sess)
 		}
 		s.mu.Unlock()
 		return user.Session{}, ErrSessionExpired
@@ -77,6 +96,30 @@ func (s *MemoryStore) Get(ctx context.Context, sessionID string) (user.Session,
 	return session, nil
 }
 
+func (s *MemoryStore) Touch(ctx context.Context, sessionID string, seenAt time.Time) (user.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return user.Session{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return user.Session{}, ErrSessionNotFound
+	}
+	if seenAt.After(session.ExpiresAt) {
+		s.remove(session)
+		return user.Session{}, ErrSessionExpired
+	}
+
+	session.LastSeenAt = seenAt.UTC()
+	session.ExpiresAt = s.lifetime.ExpiresAt(session, session.LastSeenAt)
+	s.sessions[sessionID] = session
+
+	return session, nil
+}
+
 func (s *MemoryStore) Delete(ctx context.Context, sessionID string) error {
 	if err := ctx.Err(); err != nil {
 		return err
@@ -85,11 +128,63 @@ func (s *MemoryStore) Delete(ctx context.Context, sessionID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, ok := s.sessions[sessionID]
+	session, ok := s.sessions[sessionID]
 	if !ok {
 		return ErrSessionNotFound
 	}
 
-	delete(s.sessions, sessionID)
+	s.remove(session)
+	return nil
+}
+
+func (s *MemoryStore) ListByUser(ctx context.Context, userID string) ([]user.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timeNow := time.Now().UTC()
+	sessions := make([]user.Session, 0, len(s.byUser[userID]))
+	for id := range s.byUser[userID] {
+		session := s.sessions[id]
+		if timeNow.After(session.ExpiresAt) {
+			s.remove(session)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (s *MemoryStore) DeleteByUser(ctx context.Context, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.byUser[userID] {
+		delete(s.sessions, id)
+	}
+	delete(s.byUser, userID)
+
 	return nil
 }
+
+// remove drops the session and its index entry. Callers must hold s.mu.
+func (s *MemoryStore) remove(session user.Session) {
+	delete(s.sessions, session.ID)
+
+	ids, ok := s.byUser[session.UserID]
+	if !ok {
+		return
+	}
+	delete(ids, session.ID)
+	if len(ids) == 0 {
+		delete(s.byUser, session.UserID)
+	}
+}
@@ -2,19 +2,20 @@ package memory
 
 import (
 	"context"
+	"crud/internal/services/user"
 	"testing"
 	"time"
 )
 
 func TestMemoryStore(t *testing.T) {
-	sessionStore, err := NewMemoryStore(30*time.Minute, nil)
+	sessionStore, err := NewMemoryStore(user.SessionLifetime{IdleTimeout: 30 * time.Minute}, nil)
 	if err != nil {
 		t.Fatalf("failed to create MemoryStore: %v", err)
 	}
 
 	ctx := context.Background()
 
-	createdSession, err := sessionStore.Create(ctx, "1")
+	createdSession, err := sessionStore.Create(ctx, user.SessionAttrs{UserID: "1"})
 	if err != nil {
 		t.Fatalf("failed to create session: %v", err)
 	}
@@ -43,14 +44,14 @@ func TestMemoryStore(t *testing.T) {
 }
 
 func TestMemoryStore_expiredTtl(t *testing.T) {
-	sessionStore, err := NewMemoryStore(20*time.Millisecond, nil)
+	sessionStore, err := NewMemoryStore(user.SessionLifetime{IdleTimeout: 20 * time.Millisecond}, nil)
 	if err != nil {
 		t.Fatalf("failed to create MemoryStore: %v", err)
 	}
 
 	ctx := context.Background()
 
-	createdSession, err := sessionStore.Create(ctx, "1")
+	createdSession, err := sessionStore.Create(ctx, user.SessionAttrs{UserID: "1"})
 	if err != nil {
 		t.Fatalf("failed to create session: %v", err)
 	}
@@ -66,7 +67,7 @@ func TestMemoryStore_expiredTtl(t *testing.T) {
 }
 
 func TestMemoryStore_contextCanceled(t *testing.T) {
-	sessionStore, err := NewMemoryStore(30*time.Minute, nil)
+	sessionStore, err := NewMemoryStore(user.SessionLifetime{IdleTimeout: 30 * time.Minute}, nil)
 	if err != nil {
 		t.Fatalf("failed to create MemoryStore: %v", err)
 	}
@@ -74,14 +75,14 @@ func TestMemoryStore_contextCanceled(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	_, err = sessionStore.Create(ctx, "1")
+	_, err = sessionStore.Create(ctx, user.SessionAttrs{UserID: "1"})
 	if err == nil {
 		t.Fatalf("expected error when creating session with canceled context, got nil")
 	}
 }
 
 func TestMemoryStore_NoSession(t *testing.T) {
-	sessionStore, err := NewMemoryStore(30*time.Minute, nil)
+	sessionStore, err := NewMemoryStore(user.SessionLifetime{IdleTimeout: 30 * time.Minute}, nil)
 	if err != nil {
 		t.Fatalf("failed to create MemoryStore: %v", err)
 	}
@@ -96,3 +97,131 @@ func TestMemoryStore_NoSession(t *testing.T) {
 		t.Fatalf("expected ErrSessionNotFound, got: %v", err)
 	}
 }
+
+func TestMemoryStore_ListAndDeleteByUser(t *testing.T) {
+	sessionStore, err := NewMemoryStore(user.SessionLifetime{IdleTimeout: 30 * time.Minute}, nil)
+	if err != nil {
+		t.Fatalf("failed to create MemoryStore: %v", err)
+	}
+
+	ctx := context.Background()
+
+	for _, userID := range []string{"1", "1", "2"} {
+		if _, err := sessionStore.Create(ctx, user.SessionAttrs{UserID: userID}); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+	}
+
+	sessions, err := sessionStore.ListByUser(ctx, "1")
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got: %d", len(sessions))
+	}
+
+	err = sessionStore.Delete(ctx, sessions[0].ID)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+
+	sessions, err = sessionStore.ListByUser(ctx, "1")
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 session after delete, got: %d", len(sessions))
+	}
+
+	err = sessionStore.DeleteByUser(ctx, "1")
+	if err != nil {
+		t.Fatalf("failed to delete user sessions: %v", err)
+	}
+
+	_, err = sessionStore.Get(ctx, sessions[0].ID)
+	if err != ErrSessionNotFound {
+		t.Fatalf("expected ErrSessionNotFound, got: %v", err)
+	}
+
+	sessions, err = sessionStore.ListByUser(ctx, "2")
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("expected other user's session to survive, got: %d", len(sessions))
+	}
+}
+
+func TestMemoryStore_Touch(t *testing.T) {
+	sessionStore, err := NewMemoryStore(user.SessionLifetime{IdleTimeout: 30 * time.Minute}, nil)
+	if err != nil {
+		t.Fatalf("failed to create MemoryStore: %v", err)
+	}
+
+	ctx := context.Background()
+
+	createdSession, err := sessionStore.Create(ctx, user.SessionAttrs{
+		UserID:    "1",
+		IP:        "203.0.113.7",
+		UserAgent: "test-agent",
+	})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if createdSession.CreatedAt.IsZero() || createdSession.LastSeenAt != createdSession.CreatedAt {
+		t.Fatalf("unexpected timestamps: %+v", createdSession)
+	}
+
+	seenAt := createdSession.CreatedAt.Add(time.Minute)
+	touchedSession, err := sessionStore.Touch(ctx, createdSession.ID, seenAt)
+	if err != nil {
+		t.Fatalf("failed to touch session: %v", err)
+	}
+
+	retrievedSession, err := sessionStore.Get(ctx, createdSession.ID)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if retrievedSession != touchedSession || !retrievedSession.LastSeenAt.Equal(seenAt) {
+		t.Fatalf("last seen was not persisted: %+v", retrievedSession)
+	}
+	if retrievedSession.IP != "203.0.113.7" || retrievedSession.UserAgent != "test-agent" {
+		t.Fatalf("metadata was not persisted: %+v", retrievedSession)
+	}
+}
+
+func TestMemoryStore_SlidingExpiration(t *testing.T) {
+	sessionStore, err := NewMemoryStore(user.SessionLifetime{
+		IdleTimeout: 30 * time.Minute,
+		MaxLifetime: time.Hour,
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create MemoryStore: %v", err)
+	}
+
+	ctx := context.Background()
+
+	createdSession, err := sessionStore.Create(ctx, user.SessionAttrs{UserID: "1"})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if !createdSession.ExpiresAt.Equal(createdSession.CreatedAt.Add(30 * time.Minute)) {
+		t.Fatalf("unexpected initial expiry: %+v", createdSession)
+	}
+
+	touchedSession, err := sessionStore.Touch(ctx, createdSession.ID, createdSession.CreatedAt.Add(20*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to touch session: %v", err)
+	}
+	if !touchedSession.ExpiresAt.Equal(createdSession.CreatedAt.Add(50 * time.Minute)) {
+		t.Fatalf("expiry was not extended: %+v", touchedSession)
+	}
+
+	touchedSession, err = sessionStore.Touch(ctx, createdSession.ID, createdSession.CreatedAt.Add(45*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to touch session: %v", err)
+	}
+	if !touchedSession.ExpiresAt.Equal(createdSession.CreatedAt.Add(time.Hour)) {
+		t.Fatalf("expiry was not capped by max lifetime: %+v", touchedSession)
+	}
+}
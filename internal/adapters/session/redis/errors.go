@@ -0,0 +1,7 @@
+package redis
+
+import "errors"
+
+var (
+	ErrInvalidTtl = errors.New("ttl can not be 0")
+)
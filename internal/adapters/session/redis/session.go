@@ -11,20 +11,40 @@ import (
 )
 
 type RedisStore struct {
-	client *redis.Client
-	ttl    time.Duration
-	idGen  func() (string, error)
+	client   *redis.Client
+	lifetime user.SessionLifetime
+	indexTTL time.Duration
+	idGen    func() (string, error)
 }
 
-func NewRedisStore(client *redis.Client, ttl time.Duration, idGen func() (string, error)) *RedisStore {
-	return &RedisStore{
-		client: client,
-		ttl:    ttl,
-		idGen:  idGen,
+func NewRedisStore(client *redis.Client, lifetime user.SessionLifetime, idGen func() (string, error)) (*RedisStore, error) {
+	if lifetime.IdleTimeout <= 0 {
+		return nil, ErrInvalidTtl
+	}
+	indexTTL := lifetime.IdleTimeout
+	if lifetime.RememberMeTTL > indexTTL {
+		indexTTL = lifetime.RememberMeTTL
 	}
+	return &RedisStore{
+		client:   client,
+		lifetime: lifetime,
+		indexTTL: indexTTL,
+		idGen:    idGen,
+	}, nil
+}
+
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("session:%s", sessionID)
 }
 
-func (s *RedisStore) Create(ctx context.Context, userID string) (user.Session, error) {
+// userSessionsKey holds the set of session IDs issued to a user. Members may
+// outlive their session keys and are pruned lazily in ListByUser. The set
+// expires indexTTL after the last write, which no member can outlive.
+func userSessionsKey(userID string) string {
+	return fmt.Sprintf("user_sessions:%s", userID)
+}
+
+func (s *RedisStore) Create(ctx context.Context, attrs user.SessionAttrs) (user.Session, error) {
 	if err := ctx.Err(); err != nil {
 		return user.Session{}, err
 	}
@@ -32,12 +52,27 @@ func (s *RedisStore) Create(ctx context.Context, userID string) (user.Session, e
 	if err != nil {
 		return user.Session{}, err
 	}
-	session := user.Session{ID: id, UserID: userID, ExpiresAt: time.Now().UTC().Add(s.ttl)}
+	timeNow := time.Now().UTC()
+	session := user.Session{
+		ID:         id,
+		UserID:     attrs.UserID,
+		IP:         attrs.IP,
+		UserAgent:  attrs.UserAgent,
+		CreatedAt:  timeNow,
+		LastSeenAt: timeNow,
+		Persistent: attrs.Persistent,
+	}
+	session.ExpiresAt = s.lifetime.ExpiresAt(session, timeNow)
 	payload, err := json.Marshal(session)
 	if err != nil {
 		return user.Session{}, err
 	}
-	err = s.client.Set(ctx, fmt.Sprintf("session:%s", session.ID), payload, s.ttl).Err()
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(session.ID), payload, session.ExpiresAt.Sub(timeNow))
+		pipe.SAdd(ctx, userSessionsKey(attrs.UserID), session.ID)
+		pipe.Expire(ctx, userSessionsKey(attrs.UserID), s.indexTTL)
+		return nil
+	})
 	if err != nil {
 		return user.Session{}, err
 	}
@@ -49,7 +84,7 @@ func (s *RedisStore) Get(ctx context.Context, sessionID string) (user.Session, e
 		return user.Session{}, ctx.Err()
 	}
 
-	data, err := s.client.Get(ctx, fmt.Sprintf("session:%s", sessionID)).Bytes()
+	data, err := s.client.Get(ctx, sessionKey(sessionID)).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return user.Session{}, user.ErrSessionNotFound
@@ -69,17 +104,136 @@ func (s *RedisStore) Get(ctx context.Context, sessionID string) (user.Session, e
 	return session, nil
 }
 
+func (s *RedisStore) Touch(ctx context.Context, sessionID string, seenAt time.Time) (user.Session, error) {
+	session, err := s.Get(ctx, sessionID)
+	if err != nil {
+		return user.Session{}, err
+	}
+
+	session.LastSeenAt = seenAt.UTC()
+	session.ExpiresAt = s.lifetime.ExpiresAt(session, session.LastSeenAt)
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return user.Session{}, user.ErrSessionExpired
+	}
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return user.Session{}, err
+	}
+
+	// XX keeps a concurrently revoked session from being resurrected.
+	err = s.client.SetArgs(ctx, sessionKey(sessionID), payload, redis.SetArgs{
+		Mode: "XX",
+		TTL:  ttl,
+	}).Err()
+	if err != nil {
+		if err == redis.Nil {
+			return user.Session{}, user.ErrSessionNotFound
+		}
+		return user.Session{}, err
+	}
+
+	err = s.client.Expire(ctx, userSessionsKey(session.UserID), s.indexTTL).Err()
+	if err != nil {
+		return user.Session{}, err
+	}
+
+	return session, nil
+}
+
 func (s *RedisStore) Delete(ctx context.Context, sessionID string) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
 
-	if err := s.client.Del(ctx, fmt.Sprintf("session:%s", sessionID)).Err(); err != nil {
+	data, err := s.client.Get(ctx, sessionKey(sessionID)).Bytes()
+	if err != nil {
 		if err == redis.Nil {
 			return user.ErrSessionNotFound
 		}
 		return err
 	}
 
-	return nil
+	var session user.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(session.ID))
+		pipe.SRem(ctx, userSessionsKey(session.UserID), session.ID)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) ListByUser(ctx context.Context, userID string) ([]user.Session, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []user.Session{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = sessionKey(id)
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	timeNow := time.Now().UTC()
+	sessions := make([]user.Session, 0, len(ids))
+	stale := []any{}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var session user.Session
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			return nil, err
+		}
+		if timeNow.After(session.ExpiresAt) {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	if len(stale) > 0 {
+		if err := s.client.SRem(ctx, userSessionsKey(userID), stale...).Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+func (s *RedisStore) DeleteByUser(ctx context.Context, userID string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+	keys = append(keys, userSessionsKey(userID))
+
+	return s.client.Del(ctx, keys...).Err()
 }
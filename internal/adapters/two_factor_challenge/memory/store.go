@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	challenge user.TwoFactorChallenge
+	attempts  int
+}
+
+type MemoryStore struct {
+	mu         sync.Mutex
+	challenges map[string]*entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{challenges: make(map[string]*entry)}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, challenge user.TwoFactorChallenge) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.challenges[challenge.Hash] = &entry{challenge: challenge}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, hash string) (user.TwoFactorChallenge, error) {
+	if err := ctx.Err(); err != nil {
+		return user.TwoFactorChallenge{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.lookup(hash)
+	if err != nil {
+		return user.TwoFactorChallenge{}, err
+	}
+	return e.challenge, nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, hash string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.lookup(hash)
+	if err != nil {
+		return 0, err
+	}
+	e.attempts++
+	return e.attempts, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, hash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.lookup(hash); err != nil {
+		return err
+	}
+	delete(s.challenges, hash)
+	return nil
+}
+
+// lookup must be called with mu held. Expired challenges are dropped.
+func (s *MemoryStore) lookup(hash string) (*entry, error) {
+	e, ok := s.challenges[hash]
+	if !ok {
+		return nil, user.ErrTwoFactorChallengeInvalid
+	}
+	if time.Now().After(e.challenge.ExpiresAt) {
+		delete(s.challenges, hash)
+		return nil, user.ErrTwoFactorChallengeInvalid
+	}
+	return e, nil
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Lifecycle(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	err := store.Create(ctx, user.TwoFactorChallenge{Hash: "h", UserID: "1", ExpiresAt: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if challenge, err := store.Get(ctx, "h"); err != nil || challenge.UserID != "1" {
+		t.Fatalf("Get failed: %+v %v", challenge, err)
+	}
+	for want := 1; want <= 2; want++ {
+		if attempts, _ := store.Fail(ctx, "h"); attempts != want {
+			t.Fatalf("expected %d attempts, got %d", want, attempts)
+		}
+	}
+	if err := store.Delete(ctx, "h"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete(ctx, "h"); !errors.Is(err, user.ErrTwoFactorChallengeInvalid) {
+		t.Fatalf("second Delete must fail, got: %v", err)
+	}
+}
+
+func TestMemoryStore_Expired(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_ = store.Create(ctx, user.TwoFactorChallenge{Hash: "h", ExpiresAt: time.Now().Add(-time.Second)})
+	if _, err := store.Get(ctx, "h"); !errors.Is(err, user.ErrTwoFactorChallengeInvalid) {
+		t.Fatalf("expected ErrTwoFactorChallengeInvalid, got: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package redis
+
+import (
+	"context"
+	"crud/internal/services/user"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func challengeKey(hash string) string {
+	return fmt.Sprintf("two_factor_challenge:%s", hash)
+}
+
+func attemptsKey(hash string) string {
+	return fmt.Sprintf("two_factor_attempts:%s", hash)
+}
+
+func (s *RedisStore) Create(ctx context.Context, challenge user.TwoFactorChallenge) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	ttl := time.Until(challenge.ExpiresAt)
+	if ttl <= 0 {
+		return user.ErrTwoFactorChallengeInvalid
+	}
+	payload, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, challengeKey(challenge.Hash), payload, ttl).Err()
+}
+
+func (s *RedisStore) Get(ctx context.Context, hash string) (user.TwoFactorChallenge, error) {
+	if ctx.Err() != nil {
+		return user.TwoFactorChallenge{}, ctx.Err()
+	}
+
+	payload, err := s.client.Get(ctx, challengeKey(hash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return user.TwoFactorChallenge{}, user.ErrTwoFactorChallengeInvalid
+	}
+	if err != nil {
+		return user.TwoFactorChallenge{}, err
+	}
+
+	var challenge user.TwoFactorChallenge
+	if err := json.Unmarshal(payload, &challenge); err != nil {
+		return user.TwoFactorChallenge{}, err
+	}
+	return challenge, nil
+}
+
+func (s *RedisStore) Fail(ctx context.Context, hash string) (int, error) {
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	ttl, err := s.client.PTTL(ctx, challengeKey(hash)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl <= 0 {
+		return 0, user.ErrTwoFactorChallengeInvalid
+	}
+
+	var incr *redis.IntCmd
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, attemptsKey(hash))
+		pipe.PExpire(ctx, attemptsKey(hash), ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(incr.Val()), nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, hash string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var del *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		del = pipe.Del(ctx, challengeKey(hash))
+		pipe.Del(ctx, attemptsKey(hash))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if del.Val() == 0 {
+		return user.ErrTwoFactorChallengeInvalid
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -9,8 +10,10 @@ import (
 
 type Config struct {
 	Server struct {
-		Host string `yaml:"host"`
-		Port int    `yaml:"port"`
+		Host           string   `yaml:"host"`
+		Port           int      `yaml:"port"`
+		TrustedProxies []string `yaml:"trusted_proxies"`
+		DebugAddr      string   `yaml:"debug_addr"`
 	} `yaml:"server"`
 	Postgres struct {
 		Host     string `yaml:"host"`
@@ -25,8 +28,143 @@ type Config struct {
 		Port     int    `yaml:"port"`
 	} `yaml:"redis"`
 	Session struct {
+		IdleTimeout   time.Duration `yaml:"idle_timeout"`
+		MaxLifetime   time.Duration `yaml:"max_lifetime"`
+		RememberMeTTL time.Duration `yaml:"remember_me_ttl"`
+		TouchInterval time.Duration `yaml:"touch_interval"`
+		// TTL is the fixed session lifetime of older configs. It stands in
+		// for idle_timeout and max_lifetime when they are not set.
 		TTL time.Duration `yaml:"ttl"`
 	}
+	Auth struct {
+		Sources []string `yaml:"sources"`
+	} `yaml:"auth"`
+	Registration struct {
+		ConcealExistingEmail bool `yaml:"conceal_existing_email"`
+	} `yaml:"registration"`
+	CSRF struct {
+		Enabled        bool     `yaml:"enabled"`
+		TrustedOrigins []string `yaml:"trusted_origins"`
+		DoubleSubmit   bool     `yaml:"double_submit"`
+	} `yaml:"csrf"`
+	JWT struct {
+		Issuer     string        `yaml:"issuer"`
+		AccessTTL  time.Duration `yaml:"access_ttl"`
+		RefreshTTL time.Duration `yaml:"refresh_ttl"`
+		ActiveKey  string        `yaml:"active_key"`
+		Keys       []struct {
+			ID   string `yaml:"id"`
+			File string `yaml:"file"`
+		} `yaml:"keys"`
+	} `yaml:"jwt"`
+	TwoFactor struct {
+		Issuer string `yaml:"issuer"`
+	} `yaml:"two_factor"`
+	PasswordReset struct {
+		URL      string        `yaml:"url"`
+		TokenTTL time.Duration `yaml:"token_ttl"`
+	} `yaml:"password_reset"`
+	EmailVerification struct {
+		Required       bool          `yaml:"required"`
+		URL            string        `yaml:"url"`
+		Secret         string        `yaml:"secret"`
+		TokenTTL       time.Duration `yaml:"token_ttl"`
+		ResendInterval time.Duration `yaml:"resend_interval"`
+	} `yaml:"email_verification"`
+	MagicLink struct {
+		URL      string        `yaml:"url"`
+		TokenTTL time.Duration `yaml:"token_ttl"`
+	} `yaml:"magic_link"`
+	ExternalLogin struct {
+		CallbackURL string        `yaml:"callback_url"`
+		StateTTL    time.Duration `yaml:"state_ttl"`
+		Providers   []struct {
+			Name         string   `yaml:"name"`
+			Issuer       string   `yaml:"issuer"`
+			ClientID     string   `yaml:"client_id"`
+			ClientSecret string   `yaml:"client_secret"`
+			Scopes       []string `yaml:"scopes"`
+		} `yaml:"providers"`
+	} `yaml:"external_login"`
+	OIDC struct {
+		CodeTTL    time.Duration `yaml:"code_ttl"`
+		AccessTTL  time.Duration `yaml:"access_ttl"`
+		IDTokenTTL time.Duration `yaml:"id_token_ttl"`
+	} `yaml:"oidc"`
+	LoginThrottle struct {
+		Enabled bool           `yaml:"enabled"`
+		Window  time.Duration  `yaml:"window"`
+		Account ThrottlePolicy `yaml:"account"`
+		IP      ThrottlePolicy `yaml:"ip"`
+	} `yaml:"login_throttle"`
+	PasswordHashing struct {
+		Algorithm  string `yaml:"algorithm"`
+		BcryptCost int    `yaml:"bcrypt_cost"`
+		Argon2     struct {
+			Memory      uint32 `yaml:"memory"`
+			Iterations  uint32 `yaml:"iterations"`
+			Parallelism uint8  `yaml:"parallelism"`
+			SaltLength  uint32 `yaml:"salt_length"`
+			KeyLength   uint32 `yaml:"key_length"`
+		} `yaml:"argon2"`
+		BcryptCalibration struct {
+			Target  time.Duration `yaml:"target"`
+			MinCost int           `yaml:"min_cost"`
+		} `yaml:"bcrypt_calibration"`
+		LegacyFormats []string `yaml:"legacy_formats"`
+		Pepper        struct {
+			Active string `yaml:"active"`
+			Keys   []struct {
+				ID   string `yaml:"id"`
+				File string `yaml:"file"`
+			} `yaml:"keys"`
+		} `yaml:"pepper"`
+		Pool struct {
+			Concurrency int `yaml:"concurrency"`
+			QueueDepth  int `yaml:"queue_depth"`
+		} `yaml:"pool"`
+	} `yaml:"password_hashing"`
+	PasswordPolicy struct {
+		MinLength     int      `yaml:"min_length"`
+		MaxBytes      int      `yaml:"max_bytes"`
+		RequireLower  bool     `yaml:"require_lower"`
+		RequireUpper  bool     `yaml:"require_upper"`
+		RequireDigit  bool     `yaml:"require_digit"`
+		RequireSymbol bool     `yaml:"require_symbol"`
+		MinClasses    int      `yaml:"min_classes"`
+		BannedWords   []string `yaml:"banned_words"`
+		MinStrength   int      `yaml:"min_strength"`
+	} `yaml:"password_policy"`
+	BreachCheck struct {
+		RangeDir    string `yaml:"range_dir"`
+		BloomFilter string `yaml:"bloom_filter"`
+	} `yaml:"breach_check"`
+	RateLimit struct {
+		Public        RateLimitRule `yaml:"public"`
+		Authenticated RateLimitRule `yaml:"authenticated"`
+	} `yaml:"rate_limit"`
+	Notifier struct {
+		File string `yaml:"file"`
+	} `yaml:"notifier"`
+}
+
+// RateLimitRule configures the limiter of a route group; a zero limit turns
+// it off.
+type RateLimitRule struct {
+	Algorithm string        `yaml:"algorithm"`
+	Limit     int           `yaml:"limit"`
+	Period    time.Duration `yaml:"period"`
+	Burst     int           `yaml:"burst"`
+	Key       string        `yaml:"key"`
+}
+
+// ThrottlePolicy overrides the non-zero fields of the built-in policy.
+type ThrottlePolicy struct {
+	FreeAttempts     int           `yaml:"free_attempts"`
+	BaseDelay        time.Duration `yaml:"base_delay"`
+	MaxDelay         time.Duration `yaml:"max_delay"`
+	LockoutThreshold int           `yaml:"lockout_threshold"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration"`
 }
 
 func Load(path string) (Config, error) {
@@ -39,8 +177,22 @@ func Load(path string) (Config, error) {
 	if err != nil {
 		return Config{}, err
 	}
+	if cfg.Session.IdleTimeout == 0 && cfg.Session.TTL > 0 {
+		cfg.Session.IdleTimeout = cfg.Session.TTL
+		if cfg.Session.MaxLifetime == 0 {
+			cfg.Session.MaxLifetime = cfg.Session.TTL
+		}
+	}
 	if v:= os.Getenv("POSTGRES_PASSWORD"); v != "" {
 		cfg.Postgres.Password = v
 	}
+	if v := os.Getenv("EMAIL_VERIFICATION_SECRET"); v != "" {
+		cfg.EmailVerification.Secret = v
+	}
+	for i, provider := range cfg.ExternalLogin.Providers {
+		if v := os.Getenv("EXTERNAL_LOGIN_" + strings.ToUpper(provider.Name) + "_CLIENT_SECRET"); v != "" {
+			cfg.ExternalLogin.Providers[i].ClientSecret = v
+		}
+	}
 	return cfg, nil
 }
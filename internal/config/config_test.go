@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadYAML(t *testing.T, data string) Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return cfg
+}
+
+func TestLoad_SessionTTLFallback(t *testing.T) {
+	cfg := loadYAML(t, "session:\n  ttl: \"12h\"\n")
+	if cfg.Session.IdleTimeout != 12*time.Hour || cfg.Session.MaxLifetime != 12*time.Hour {
+		t.Fatalf("expected ttl to stand in for both timeouts, got %+v", cfg.Session)
+	}
+
+	cfg = loadYAML(t, "session:\n  ttl: \"12h\"\n  idle_timeout: \"30m\"\n")
+	if cfg.Session.IdleTimeout != 30*time.Minute || cfg.Session.MaxLifetime != 0 {
+		t.Fatalf("expected the new keys to win over ttl, got %+v", cfg.Session)
+	}
+}
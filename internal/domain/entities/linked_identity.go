@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// LinkedIdentity ties an account at an external OpenID Connect provider,
+// identified by its subject, to a local user.
+type LinkedIdentity struct {
+	Provider  string
+	Subject   string
+	UserID    string
+	Email     string
+	CreatedAt time.Time
+}
@@ -0,0 +1,25 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type OAuthClient struct {
+	ID      string
+	OwnerID string
+	Name    string
+	// SecretHash is absent for public clients, which cannot keep a secret
+	// and authenticate with PKCE alone.
+	SecretHash   mo.Option[string]
+	RedirectURIs []string
+	CreatedAt    time.Time
+}
+
+type OAuthConsent struct {
+	UserID    string
+	ClientID  string
+	Scopes    []string
+	GrantedAt time.Time
+}
@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// OneTimeToken is a single-use secret mailed to a user, such as a password
+// reset link. Only the hash of the secret is stored.
+type OneTimeToken struct {
+	Hash      string
+	UserID    string
+	Purpose   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,33 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type PersonalAccessToken struct {
+	ID         string
+	UserID     string
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  mo.Option[time.Time]
+	LastUsedAt mo.Option[time.Time]
+}
+
+type PersonalAccessTokenAttrs struct {
+	ID        string
+	UserID    string
+	Name      string
+	TokenHash string
+	Scopes    []string
+	ExpiresAt mo.Option[time.Time]
+}
+
+type PersonalAccessTokenFilterAttrs struct {
+	ID        mo.Option[string]
+	UserID    mo.Option[string]
+	TokenHash mo.Option[string]
+}
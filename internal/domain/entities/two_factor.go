@@ -0,0 +1,20 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type TwoFactor struct {
+	UserID string
+	// Secret is the base32 TOTP key shared with the authenticator app.
+	Secret      string
+	CreatedAt   time.Time
+	ConfirmedAt mo.Option[time.Time]
+	// LastUsedStep is the last accepted TOTP time step; codes for it and
+	// earlier steps are rejected to prevent replays.
+	LastUsedStep int64
+	// RecoveryCodes holds the hashes of the unused recovery codes.
+	RecoveryCodes []string
+}
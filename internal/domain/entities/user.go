@@ -1,12 +1,18 @@
 package entities
 
-import "github.com/samber/mo"
+import (
+	"time"
+
+	"github.com/samber/mo"
+)
 
 type User struct {
-	ID             string
-	Username       string
-	Email          string
-	HashedPassword string
+	ID                 string
+	Username           string
+	Email              string
+	HashedPassword     string
+	EmailVerifiedAt    mo.Option[time.Time]
+	VerificationSentAt mo.Option[time.Time]
 }
 
 type UserAttrs struct {
@@ -17,9 +23,12 @@ type UserAttrs struct {
 }
 
 type UserUpdateAttrs struct {
-	Email          mo.Option[string]
-	Username       mo.Option[string]
-	HashedPassword mo.Option[string]
+	// Changing Email clears EmailVerifiedAt unless the address stays the same.
+	Email              mo.Option[string]
+	Username           mo.Option[string]
+	HashedPassword     mo.Option[string]
+	EmailVerifiedAt    mo.Option[time.Time]
+	VerificationSentAt mo.Option[time.Time]
 }
 type UserFilterAttrs struct {
 	ID       mo.Option[string]
@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+)
+
+type LinkedIdentityRepository interface {
+	Create(ctx context.Context, identity entities.LinkedIdentity) error
+	FindOne(ctx context.Context, provider string, subject string, ent *entities.LinkedIdentity) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+)
+
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client entities.OAuthClient) error
+	FindOne(ctx context.Context, id string, ent *entities.OAuthClient) error
+	FindByOwner(ctx context.Context, ownerID string) ([]entities.OAuthClient, error)
+	Delete(ctx context.Context, id string, ownerID string) error
+}
+
+type OAuthConsentRepository interface {
+	FindOne(ctx context.Context, userID string, clientID string, ent *entities.OAuthConsent) error
+	Save(ctx context.Context, consent entities.OAuthConsent) error
+}
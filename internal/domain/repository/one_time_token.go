@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+)
+
+type OneTimeTokenRepository interface {
+	Create(ctx context.Context, token entities.OneTimeToken) error
+	Consume(ctx context.Context, hash string, purpose string, ent *entities.OneTimeToken) error
+	DeleteByUser(ctx context.Context, userID string, purpose string) error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"time"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(context.Context, entities.PersonalAccessTokenAttrs, *entities.PersonalAccessToken) error
+	Find(context.Context, entities.PersonalAccessTokenFilterAttrs) ([]entities.PersonalAccessToken, error)
+	FindOne(context.Context, entities.PersonalAccessTokenFilterAttrs, *entities.PersonalAccessToken) error
+	Delete(context.Context, entities.PersonalAccessTokenFilterAttrs) error
+	TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
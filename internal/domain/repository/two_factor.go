@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"time"
+)
+
+type TwoFactorRepository interface {
+	FindOne(ctx context.Context, userID string, ent *entities.TwoFactor) error
+	SavePending(ctx context.Context, userID string, secret string) error
+	Confirm(ctx context.Context, userID string, step int64, confirmedAt time.Time, recoveryCodes []string) error
+	UseStep(ctx context.Context, userID string, step int64) error
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) error
+	Delete(ctx context.Context, userID string) error
+}
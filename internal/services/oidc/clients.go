@@ -0,0 +1,144 @@
+package oidc
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type ClientRepository interface {
+	Create(ctx context.Context, client entities.OAuthClient) error
+	// FindOne returns ErrClientNotFound for unknown clients.
+	FindOne(ctx context.Context, id string, ent *entities.OAuthClient) error
+	FindByOwner(ctx context.Context, ownerID string) ([]entities.OAuthClient, error)
+	// Delete returns ErrClientNotFound unless ownerID owns the client.
+	Delete(ctx context.Context, id string, ownerID string) error
+}
+
+type RegisterClientRequest struct {
+	OwnerID      string
+	Name         string
+	RedirectURIs []string
+	// Public registers a client without a secret, such as a single-page or
+	// native app. Public clients are authenticated by PKCE alone.
+	Public bool
+}
+
+type RegisterClientResponse struct {
+	Client entities.OAuthClient
+	// Secret is absent for public clients. It is not stored and cannot be
+	// shown again.
+	Secret mo.Option[string]
+}
+
+type ListClientsRequest struct {
+	OwnerID string
+}
+
+type ListClientsResponse struct {
+	Clients []entities.OAuthClient
+}
+
+type DeleteClientRequest struct {
+	OwnerID  string
+	ClientID string
+}
+
+type DeleteClientResponse struct {
+	Success bool
+}
+
+type ClientService struct {
+	Repo  ClientRepository
+	IdGen user.IDGen
+}
+
+func NewClientService(repo ClientRepository, idGen user.IDGen) *ClientService {
+	return &ClientService{
+		Repo:  repo,
+		IdGen: idGen,
+	}
+}
+
+func (s *ClientService) Register(ctx context.Context, req RegisterClientRequest) (RegisterClientResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return RegisterClientResponse{}, ErrClientNameRequired
+	}
+	if len(req.RedirectURIs) == 0 {
+		return RegisterClientResponse{}, ErrRedirectURIRequired
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		if err := validateRedirectURI(redirectURI); err != nil {
+			return RegisterClientResponse{}, err
+		}
+	}
+
+	id, err := s.IdGen.NewID()
+	if err != nil {
+		return RegisterClientResponse{}, err
+	}
+
+	client := entities.OAuthClient{
+		ID:           id,
+		OwnerID:      req.OwnerID,
+		Name:         name,
+		RedirectURIs: req.RedirectURIs,
+		CreatedAt:    time.Now().UTC(),
+	}
+	var secret mo.Option[string]
+	if !req.Public {
+		value, hash, err := newSecret()
+		if err != nil {
+			return RegisterClientResponse{}, err
+		}
+		client.SecretHash = mo.Some(hash)
+		secret = mo.Some(value)
+	}
+
+	if err := s.Repo.Create(ctx, client); err != nil {
+		return RegisterClientResponse{}, err
+	}
+	return RegisterClientResponse{Client: client, Secret: secret}, nil
+}
+
+func (s *ClientService) List(ctx context.Context, req ListClientsRequest) (ListClientsResponse, error) {
+	clients, err := s.Repo.FindByOwner(ctx, req.OwnerID)
+	if err != nil {
+		return ListClientsResponse{}, err
+	}
+	return ListClientsResponse{Clients: clients}, nil
+}
+
+func (s *ClientService) Delete(ctx context.Context, req DeleteClientRequest) (DeleteClientResponse, error) {
+	if err := s.Repo.Delete(ctx, req.ClientID, req.OwnerID); err != nil {
+		return DeleteClientResponse{}, err
+	}
+	return DeleteClientResponse{Success: true}, nil
+}
+
+// validateRedirectURI accepts absolute https URIs, and plain http only on
+// loopback addresses for local development and native apps.
+func validateRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return fmt.Errorf("%w: %q", ErrRedirectURIInvalid, raw)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrRedirectURIInvalid, raw)
+}
@@ -0,0 +1,76 @@
+package oidc
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"testing"
+)
+
+type clientRepoStub struct {
+	created []entities.OAuthClient
+}
+
+func (r *clientRepoStub) Create(ctx context.Context, client entities.OAuthClient) error {
+	r.created = append(r.created, client)
+	return nil
+}
+
+func (r *clientRepoStub) FindOne(ctx context.Context, id string, ent *entities.OAuthClient) error {
+	return ErrClientNotFound
+}
+
+func (r *clientRepoStub) FindByOwner(ctx context.Context, ownerID string) ([]entities.OAuthClient, error) {
+	return r.created, nil
+}
+
+func (r *clientRepoStub) Delete(ctx context.Context, id string, ownerID string) error {
+	return ErrClientNotFound
+}
+
+type idGenStub struct{}
+
+func (idGenStub) NewID() (string, error) { return "client-1", nil }
+
+func TestClientService_Register(t *testing.T) {
+	repo := &clientRepoStub{}
+	service := NewClientService(repo, idGenStub{})
+	ctx := context.Background()
+
+	resp, err := service.Register(ctx, RegisterClientRequest{OwnerID: "1", Name: "app", RedirectURIs: []string{"https://app.example/cb"}})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	secret, ok := resp.Secret.Get()
+	if !ok || resp.Client.SecretHash.OrEmpty() != hashSecret(secret) {
+		t.Fatalf("confidential client must store the hash of its secret: %+v", resp)
+	}
+
+	resp, err = service.Register(ctx, RegisterClientRequest{OwnerID: "1", Name: "spa", RedirectURIs: []string{"http://localhost:3000/cb"}, Public: true})
+	if err != nil || resp.Secret.IsPresent() || resp.Client.SecretHash.IsPresent() {
+		t.Fatalf("public client must not get a secret: %+v %v", resp, err)
+	}
+}
+
+func TestClientService_RegisterValidation(t *testing.T) {
+	service := NewClientService(&clientRepoStub{}, idGenStub{})
+
+	cases := []struct {
+		name string
+		req  RegisterClientRequest
+		want error
+	}{
+		{"no name", RegisterClientRequest{Name: " ", RedirectURIs: []string{"https://app.example/cb"}}, ErrClientNameRequired},
+		{"no redirect uris", RegisterClientRequest{Name: "app"}, ErrRedirectURIRequired},
+		{"relative uri", RegisterClientRequest{Name: "app", RedirectURIs: []string{"/cb"}}, ErrRedirectURIInvalid},
+		{"fragment", RegisterClientRequest{Name: "app", RedirectURIs: []string{"https://app.example/cb#x"}}, ErrRedirectURIInvalid},
+		{"plain http", RegisterClientRequest{Name: "app", RedirectURIs: []string{"http://app.example/cb"}}, ErrRedirectURIInvalid},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := service.Register(context.Background(), tc.req); !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got: %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package oidc
+
+import (
+	"context"
+	"time"
+)
+
+type AuthorizationCode struct {
+	Hash          string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        []string
+	Nonce         string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+type CodeStore interface {
+	Create(ctx context.Context, code AuthorizationCode) error
+	// Consume returns the code and deletes it in one step, so that a code is
+	// exchanged at most once. Unknown or expired codes give ErrInvalidGrant.
+	Consume(ctx context.Context, hash string) (AuthorizationCode, error)
+}
@@ -0,0 +1,45 @@
+package oidc
+
+import "errors"
+
+var (
+	ErrClientNotFound      = errors.New("client not found")
+	ErrConsentNotFound     = errors.New("consent not found")
+	ErrClientNameRequired  = errors.New("client name is required")
+	ErrRedirectURIRequired = errors.New("at least one redirect uri is required")
+	ErrRedirectURIInvalid  = errors.New("redirect uri is invalid")
+	ErrAccessTokenInvalid  = errors.New("access token is invalid")
+
+	// Protocol errors of RFC 6749, reported to clients by their ErrorCode.
+	ErrInvalidRequest          = errors.New("invalid request")
+	ErrInvalidClient           = errors.New("client authentication failed")
+	ErrInvalidGrant            = errors.New("authorization code is invalid or expired")
+	ErrInvalidScope            = errors.New("invalid scope")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant type")
+	ErrAccessDenied            = errors.New("access denied by the user")
+)
+
+// ErrorCode returns the OAuth 2.0 error code that err is reported with.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidClient):
+		return "invalid_client"
+	case errors.Is(err, ErrInvalidGrant):
+		return "invalid_grant"
+	case errors.Is(err, ErrInvalidScope):
+		return "invalid_scope"
+	case errors.Is(err, ErrUnsupportedResponseType):
+		return "unsupported_response_type"
+	case errors.Is(err, ErrUnsupportedGrantType):
+		return "unsupported_grant_type"
+	case errors.Is(err, ErrAccessDenied):
+		return "access_denied"
+	case errors.Is(err, ErrAccessTokenInvalid):
+		return "invalid_token"
+	case errors.Is(err, ErrInvalidRequest), errors.Is(err, ErrRedirectURIInvalid):
+		return "invalid_request"
+	default:
+		return "server_error"
+	}
+}
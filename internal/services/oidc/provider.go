@@ -0,0 +1,349 @@
+package oidc
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+const (
+	ResponseTypeCode       = "code"
+	GrantTypeAuthorization = "authorization_code"
+	CodeChallengeS256      = "S256"
+)
+
+const defaultCodeTTL = time.Minute
+
+type ConsentRepository interface {
+	// FindOne returns ErrConsentNotFound if the user never consented to the
+	// client.
+	FindOne(ctx context.Context, userID string, clientID string, ent *entities.OAuthConsent) error
+	// Save creates or replaces the consent of a user for a client.
+	Save(ctx context.Context, consent entities.OAuthConsent) error
+}
+
+type UserRepository interface {
+	FindOne(context.Context, entities.UserFilterAttrs, *entities.User) error
+}
+
+type AuthorizeRequest struct {
+	// UserID is the signed-in user the client asks authorization from.
+	UserID              string
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+type AuthorizeResponse struct {
+	// RedirectTo is where the user agent is sent next: back to the client
+	// with either a code or an error. It is empty when consent is required.
+	RedirectTo string
+	// ConsentRequired asks the user to approve Scopes for Client first.
+	ConsentRequired bool
+	Client          entities.OAuthClient
+	Scopes          []string
+}
+
+type ConsentRequest struct {
+	AuthorizeRequest
+	Approve bool
+}
+
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+type TokenResponse struct {
+	AccessToken user.AccessToken
+	IDToken     string
+	Scopes      []string
+}
+
+type UserInfoRequest struct {
+	AccessToken string
+}
+
+type UserInfoResponse struct {
+	Claims UserClaims
+}
+
+// Provider implements the authorization code flow of OpenID Connect with
+// mandatory PKCE on top of the user repository.
+type Provider struct {
+	Clients  ClientRepository
+	Consents ConsentRepository
+	Codes    CodeStore
+	Users    UserRepository
+	Tokens   TokenIssuer
+	CodeTTL  time.Duration
+}
+
+func NewProvider(clients ClientRepository, consents ConsentRepository, codes CodeStore, users UserRepository, tokens TokenIssuer) *Provider {
+	return &Provider{
+		Clients:  clients,
+		Consents: consents,
+		Codes:    codes,
+		Users:    users,
+		Tokens:   tokens,
+		CodeTTL:  defaultCodeTTL,
+	}
+}
+
+// Authorize handles an authorization request of a signed-in user. Errors are
+// returned only when the client or redirect URI cannot be trusted; every
+// other problem is reported to the client through RedirectTo.
+func (p *Provider) Authorize(ctx context.Context, req AuthorizeRequest) (AuthorizeResponse, error) {
+	client, err := p.client(ctx, req.ClientID, req.RedirectURI)
+	if err != nil {
+		return AuthorizeResponse{}, err
+	}
+	scopes, err := validateAuthorizeRequest(req)
+	if err != nil {
+		return AuthorizeResponse{RedirectTo: errorRedirect(req, err)}, nil
+	}
+
+	var consent entities.OAuthConsent
+	err = p.Consents.FindOne(ctx, req.UserID, client.ID, &consent)
+	if err != nil && !errors.Is(err, ErrConsentNotFound) {
+		return AuthorizeResponse{}, err
+	}
+	if err != nil || !coversScopes(consent.Scopes, scopes) {
+		return AuthorizeResponse{ConsentRequired: true, Client: client, Scopes: scopes}, nil
+	}
+
+	return p.issueCode(ctx, req, scopes)
+}
+
+// Consent records the decision of the user on a pending authorization
+// request and finishes it the same way Authorize does.
+func (p *Provider) Consent(ctx context.Context, req ConsentRequest) (AuthorizeResponse, error) {
+	client, err := p.client(ctx, req.ClientID, req.RedirectURI)
+	if err != nil {
+		return AuthorizeResponse{}, err
+	}
+	scopes, err := validateAuthorizeRequest(req.AuthorizeRequest)
+	if err != nil {
+		return AuthorizeResponse{RedirectTo: errorRedirect(req.AuthorizeRequest, err)}, nil
+	}
+	if !req.Approve {
+		return AuthorizeResponse{RedirectTo: errorRedirect(req.AuthorizeRequest, ErrAccessDenied)}, nil
+	}
+
+	granted := scopes
+	var consent entities.OAuthConsent
+	err = p.Consents.FindOne(ctx, req.UserID, client.ID, &consent)
+	switch {
+	case err == nil:
+		granted = append(slices.Clone(consent.Scopes), scopes...)
+		slices.Sort(granted)
+		granted = slices.Compact(granted)
+	case !errors.Is(err, ErrConsentNotFound):
+		return AuthorizeResponse{}, err
+	}
+
+	err = p.Consents.Save(ctx, entities.OAuthConsent{
+		UserID:    req.UserID,
+		ClientID:  client.ID,
+		Scopes:    granted,
+		GrantedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return AuthorizeResponse{}, err
+	}
+
+	return p.issueCode(ctx, req.AuthorizeRequest, scopes)
+}
+
+func (p *Provider) Exchange(ctx context.Context, req TokenRequest) (TokenResponse, error) {
+	if req.GrantType != GrantTypeAuthorization {
+		return TokenResponse{}, fmt.Errorf("%w: %q", ErrUnsupportedGrantType, req.GrantType)
+	}
+	client, err := p.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if req.Code == "" || req.CodeVerifier == "" {
+		return TokenResponse{}, fmt.Errorf("%w: code and code_verifier are required", ErrInvalidRequest)
+	}
+
+	code, err := p.Codes.Consume(ctx, hashSecret(req.Code))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI || !verifyPKCE(code.CodeChallenge, req.CodeVerifier) {
+		return TokenResponse{}, ErrInvalidGrant
+	}
+
+	var u entities.User
+	err = p.Users.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(code.UserID)}, &u)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return TokenResponse{}, ErrInvalidGrant
+		}
+		return TokenResponse{}, err
+	}
+
+	accessToken, err := p.Tokens.IssueAccessToken(ctx, AccessTokenClaims{
+		UserID:   u.ID,
+		ClientID: client.ID,
+		Scopes:   code.Scopes,
+	})
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	idToken, err := p.Tokens.IssueIDToken(ctx, IDTokenClaims{
+		UserClaims: newUserClaims(u, code.Scopes),
+		ClientID:   client.ID,
+		Nonce:      code.Nonce,
+	})
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{AccessToken: accessToken, IDToken: idToken, Scopes: code.Scopes}, nil
+}
+
+func (p *Provider) UserInfo(ctx context.Context, req UserInfoRequest) (UserInfoResponse, error) {
+	claims, err := p.Tokens.VerifyAccessToken(ctx, req.AccessToken)
+	if err != nil {
+		return UserInfoResponse{}, err
+	}
+	if !slices.Contains(claims.Scopes, ScopeOpenID) {
+		return UserInfoResponse{}, ErrAccessTokenInvalid
+	}
+
+	var u entities.User
+	err = p.Users.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(claims.UserID)}, &u)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return UserInfoResponse{}, ErrAccessTokenInvalid
+		}
+		return UserInfoResponse{}, err
+	}
+	return UserInfoResponse{Claims: newUserClaims(u, claims.Scopes)}, nil
+}
+
+// client looks up the client of an authorization request and checks the
+// redirect URI against the registered ones by exact match.
+func (p *Provider) client(ctx context.Context, clientID string, redirectURI string) (entities.OAuthClient, error) {
+	var client entities.OAuthClient
+	err := p.Clients.FindOne(ctx, clientID, &client)
+	if err != nil {
+		if errors.Is(err, ErrClientNotFound) {
+			return entities.OAuthClient{}, ErrInvalidClient
+		}
+		return entities.OAuthClient{}, err
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return entities.OAuthClient{}, fmt.Errorf("%w: %q", ErrRedirectURIInvalid, redirectURI)
+	}
+	return client, nil
+}
+
+// authenticateClient checks the client secret of confidential clients.
+// Public clients must not send one.
+func (p *Provider) authenticateClient(ctx context.Context, clientID string, secret string) (entities.OAuthClient, error) {
+	var client entities.OAuthClient
+	err := p.Clients.FindOne(ctx, clientID, &client)
+	if err != nil {
+		if errors.Is(err, ErrClientNotFound) {
+			return entities.OAuthClient{}, ErrInvalidClient
+		}
+		return entities.OAuthClient{}, err
+	}
+
+	hash, confidential := client.SecretHash.Get()
+	if !confidential {
+		if secret != "" {
+			return entities.OAuthClient{}, ErrInvalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(hash)) != 1 {
+		return entities.OAuthClient{}, ErrInvalidClient
+	}
+	return client, nil
+}
+
+func (p *Provider) issueCode(ctx context.Context, req AuthorizeRequest, scopes []string) (AuthorizeResponse, error) {
+	code, hash, err := newSecret()
+	if err != nil {
+		return AuthorizeResponse{}, err
+	}
+
+	err = p.Codes.Create(ctx, AuthorizationCode{
+		Hash:          hash,
+		ClientID:      req.ClientID,
+		UserID:        req.UserID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(p.CodeTTL),
+	})
+	if err != nil {
+		return AuthorizeResponse{}, err
+	}
+
+	return AuthorizeResponse{RedirectTo: redirect(req, url.Values{"code": {code}})}, nil
+}
+
+func validateAuthorizeRequest(req AuthorizeRequest) ([]string, error) {
+	if req.ResponseType != ResponseTypeCode {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedResponseType, req.ResponseType)
+	}
+	scopes, err := parseScopes(req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if req.CodeChallengeMethod != CodeChallengeS256 {
+		return nil, fmt.Errorf("%w: code_challenge_method must be %s", ErrInvalidRequest, CodeChallengeS256)
+	}
+	if len(req.CodeChallenge) != 43 {
+		return nil, fmt.Errorf("%w: code_challenge is malformed", ErrInvalidRequest)
+	}
+	return scopes, nil
+}
+
+func errorRedirect(req AuthorizeRequest, err error) string {
+	return redirect(req, url.Values{
+		"error":             {ErrorCode(err)},
+		"error_description": {err.Error()},
+	})
+}
+
+// redirect adds params and the state of the request to the redirect URI,
+// keeping any query the client registered it with.
+func redirect(req AuthorizeRequest, params url.Values) string {
+	u, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		return req.RedirectURI
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
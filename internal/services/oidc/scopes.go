@@ -0,0 +1,42 @@
+package oidc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+var SupportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// parseScopes splits a space-delimited scope parameter. Every request has to
+// ask for openid: this provider only speaks OpenID Connect.
+func parseScopes(scope string) ([]string, error) {
+	scopes := []string{}
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(SupportedScopes, s) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, s)
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return nil, fmt.Errorf("%w: %q is required", ErrInvalidScope, ScopeOpenID)
+	}
+	return scopes, nil
+}
+
+func coversScopes(granted []string, requested []string) bool {
+	for _, s := range requested {
+		if !slices.Contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package oidc
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"slices"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type AccessTokenClaims struct {
+	UserID    string
+	ClientID  string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+type IDTokenClaims struct {
+	UserClaims
+	ClientID string
+	Nonce    string
+}
+
+// UserClaims are the standard claims released about a user. Optional claims
+// are only set when the matching scope was granted.
+type UserClaims struct {
+	Subject           string
+	Email             mo.Option[string]
+	EmailVerified     mo.Option[bool]
+	PreferredUsername mo.Option[string]
+}
+
+// TokenIssuer signs the tokens handed out to relying parties. Access tokens
+// issued here must not be accepted by the API itself and the other way round.
+type TokenIssuer interface {
+	IssueAccessToken(ctx context.Context, claims AccessTokenClaims) (user.AccessToken, error)
+	// VerifyAccessToken returns ErrAccessTokenInvalid for anything but a valid
+	// access token issued by IssueAccessToken.
+	VerifyAccessToken(ctx context.Context, token string) (AccessTokenClaims, error)
+	IssueIDToken(ctx context.Context, claims IDTokenClaims) (string, error)
+}
+
+func newUserClaims(u entities.User, scopes []string) UserClaims {
+	claims := UserClaims{Subject: u.ID}
+	if slices.Contains(scopes, ScopeEmail) {
+		claims.Email = mo.Some(u.Email)
+		claims.EmailVerified = mo.Some(u.EmailVerifiedAt.IsPresent())
+	}
+	if slices.Contains(scopes, ScopeProfile) {
+		claims.PreferredUsername = mo.Some(u.Username)
+	}
+	return claims
+}
@@ -20,12 +20,16 @@ type DeleteRepository interface {
 }
 
 type DeleteService struct {
-	Repo DeleteRepository
+	Repo         DeleteRepository
+	SessionStore SessionStore
+	// RefreshTokens, when set, has every refresh token of the user revoked.
+	RefreshTokens RefreshTokenStore
 }
 
-func NewDeleteService(repo DeleteRepository) *DeleteService {
+func NewDeleteService(repo DeleteRepository, sessionStore SessionStore) *DeleteService {
 	return &DeleteService{
-		Repo: repo,
+		Repo:         repo,
+		SessionStore: sessionStore,
 	}
 }
 
@@ -34,5 +38,17 @@ func (s *DeleteService) Delete(ctx context.Context, req DeleteRequest) (DeleteRe
 	if err != nil {
 		return DeleteResponse{Success: false}, err
 	}
+
+	err = s.SessionStore.DeleteByUser(ctx, req.ID)
+	if err != nil {
+		return DeleteResponse{Success: false}, err
+	}
+
+	if s.RefreshTokens != nil {
+		err = s.RefreshTokens.DeleteByUser(ctx, req.ID)
+		if err != nil {
+			return DeleteResponse{Success: false}, err
+		}
+	}
 	return DeleteResponse{Success: true}, nil
 }
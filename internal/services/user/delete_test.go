@@ -0,0 +1,47 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"testing"
+	"time"
+)
+
+type deleteRepoStub struct {
+	err error
+}
+
+func (r *deleteRepoStub) Delete(ctx context.Context, filterAttrs entities.UserFilterAttrs) error {
+	return r.err
+}
+
+func TestDelete_PurgesSessions(t *testing.T) {
+	store := newFakeSessionStore(
+		Session{ID: "a", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+		Session{ID: "b", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+		Session{ID: "c", UserID: "2", ExpiresAt: time.Now().Add(time.Hour)},
+	)
+	service := NewDeleteService(&deleteRepoStub{}, store)
+
+	resp, err := service.Delete(context.Background(), DeleteRequest{ID: "1"})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got: %+v, %v", resp, err)
+	}
+	if _, ok := store.sessions["c"]; !ok || len(store.sessions) != 1 {
+		t.Fatalf("expected only the other user's session to remain, got: %+v", store.sessions)
+	}
+}
+
+func TestDelete_KeepsSessionsOnRepoError(t *testing.T) {
+	store := newFakeSessionStore(Session{ID: "a", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)})
+	service := NewDeleteService(&deleteRepoStub{err: ErrUserNotFound}, store)
+
+	_, err := service.Delete(context.Background(), DeleteRequest{ID: "1"})
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got: %v", err)
+	}
+	if len(store.sessions) != 1 {
+		t.Fatalf("expected session to be kept, got: %+v", store.sessions)
+	}
+}
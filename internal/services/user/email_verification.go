@@ -0,0 +1,196 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+const (
+	defaultVerificationTTL            = 24 * time.Hour
+	defaultVerificationResendInterval = time.Minute
+)
+
+type VerifyEmailRequest struct {
+	Token string
+}
+
+type VerifyEmailResponse struct {
+	User entities.User
+}
+
+type ResendVerificationRequest struct {
+	Email string
+}
+
+type ResendVerificationResponse struct{}
+
+type EmailVerificationRepository interface {
+	FindOne(context.Context, entities.UserFilterAttrs, *entities.User) error
+	Update(context.Context, entities.UserUpdateAttrs, entities.UserFilterAttrs, *entities.User) error
+}
+
+// EmailVerificationService confirms that users own their email address.
+// Verification tokens are stateless: they carry the user ID, the address and
+// an expiry, signed with Secret, so changing the address voids them.
+type EmailVerificationService struct {
+	Repo     EmailVerificationRepository
+	Notifier Notifier
+	Secret   []byte
+	// VerifyURL is the page of the client that submits the token. The token
+	// is added to it as the token query parameter.
+	VerifyURL      string
+	TokenTTL       time.Duration
+	ResendInterval time.Duration
+}
+
+func NewEmailVerificationService(repo EmailVerificationRepository, notifier Notifier, secret []byte, verifyURL string) *EmailVerificationService {
+	return &EmailVerificationService{
+		Repo:           repo,
+		Notifier:       notifier,
+		Secret:         secret,
+		VerifyURL:      verifyURL,
+		TokenTTL:       defaultVerificationTTL,
+		ResendInterval: defaultVerificationResendInterval,
+	}
+}
+
+// Send mails a verification link to the current address of the user.
+func (s *EmailVerificationService) Send(ctx context.Context, user entities.User) error {
+	timeNow := time.Now().UTC()
+	token := s.sign(user.ID, user.Email, timeNow.Add(s.TokenTTL))
+
+	err := s.Notifier.Notify(ctx, Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Use the link below to confirm your email address. It expires in %s.\n\n%s",
+			s.TokenTTL, tokenLink(s.VerifyURL, token)),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Repo.Update(ctx, entities.UserUpdateAttrs{
+		VerificationSentAt: mo.Some(timeNow),
+	}, entities.UserFilterAttrs{
+		ID: mo.Some(user.ID),
+	}, &entities.User{})
+}
+
+// Resend mails a new link unless the address is unknown, already verified or
+// was mailed less than ResendInterval ago. None of these cases is reported,
+// so that callers cannot probe for registered emails.
+func (s *EmailVerificationService) Resend(ctx context.Context, req ResendVerificationRequest) (ResendVerificationResponse, error) {
+	email := NormalizeEmail(req.Email)
+	if email == "" {
+		return ResendVerificationResponse{}, ErrEmailRequired
+	}
+	if !ValidateEmail(email) {
+		return ResendVerificationResponse{}, ErrEmailIncorrect
+	}
+
+	var user entities.User
+	err := s.Repo.FindOne(ctx, entities.UserFilterAttrs{Email: mo.Some(email)}, &user)
+	if errors.Is(err, ErrUserNotFound) {
+		return ResendVerificationResponse{}, nil
+	}
+	if err != nil {
+		return ResendVerificationResponse{}, err
+	}
+
+	if user.EmailVerifiedAt.IsPresent() {
+		return ResendVerificationResponse{}, nil
+	}
+	if sentAt, ok := user.VerificationSentAt.Get(); ok && time.Since(sentAt) < s.ResendInterval {
+		return ResendVerificationResponse{}, nil
+	}
+
+	err = s.Send(ctx, user)
+	if err != nil {
+		return ResendVerificationResponse{}, err
+	}
+	return ResendVerificationResponse{}, nil
+}
+
+func (s *EmailVerificationService) Verify(ctx context.Context, req VerifyEmailRequest) (VerifyEmailResponse, error) {
+	userID, email, err := s.parse(req.Token, time.Now())
+	if err != nil {
+		return VerifyEmailResponse{}, err
+	}
+
+	var user entities.User
+	err = s.Repo.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(userID)}, &user)
+	if errors.Is(err, ErrUserNotFound) {
+		return VerifyEmailResponse{}, ErrVerificationTokenInvalid
+	}
+	if err != nil {
+		return VerifyEmailResponse{}, err
+	}
+	if user.Email != email {
+		return VerifyEmailResponse{}, ErrVerificationTokenInvalid
+	}
+	if user.EmailVerifiedAt.IsPresent() {
+		return VerifyEmailResponse{User: user}, nil
+	}
+
+	err = s.Repo.Update(ctx, entities.UserUpdateAttrs{
+		EmailVerifiedAt: mo.Some(time.Now().UTC()),
+	}, entities.UserFilterAttrs{
+		ID:    mo.Some(user.ID),
+		Email: mo.Some(email),
+	}, &user)
+	if errors.Is(err, ErrUserNotFound) {
+		return VerifyEmailResponse{}, ErrVerificationTokenInvalid
+	}
+	if err != nil {
+		return VerifyEmailResponse{}, err
+	}
+	return VerifyEmailResponse{User: user}, nil
+}
+
+// sign builds base64url(userID "\n" email "\n" expiry) "." base64url(mac).
+func (s *EmailVerificationService) sign(userID, email string, expiresAt time.Time) string {
+	payload := userID + "\n" + email + "\n" + strconv.FormatInt(expiresAt.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+func (s *EmailVerificationService) parse(token string, now time.Time) (string, string, error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrVerificationTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", "", ErrVerificationTokenInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.mac(string(payload))) {
+		return "", "", ErrVerificationTokenInvalid
+	}
+
+	parts := strings.Split(string(payload), "\n")
+	if len(parts) != 3 {
+		return "", "", ErrVerificationTokenInvalid
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return "", "", ErrVerificationTokenInvalid
+	}
+	return parts[0], parts[1], nil
+}
+
+func (s *EmailVerificationService) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.Secret)
+	h.Write([]byte("email_verification\n" + payload))
+	return h.Sum(nil)
+}
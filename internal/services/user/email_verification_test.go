@@ -0,0 +1,194 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+// fakeUserRepo keeps users in memory and mirrors the repository rule that
+// changing the email clears its verification.
+type fakeUserRepo struct {
+	users map[string]entities.User
+}
+
+func newFakeUserRepo(users ...entities.User) *fakeUserRepo {
+	repo := &fakeUserRepo{users: make(map[string]entities.User)}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+	return repo
+}
+
+func (r *fakeUserRepo) match(filter entities.UserFilterAttrs) (entities.User, bool) {
+	for _, user := range r.users {
+		if v, ok := filter.ID.Get(); ok && user.ID != v {
+			continue
+		}
+		if v, ok := filter.Email.Get(); ok && user.Email != v {
+			continue
+		}
+		if v, ok := filter.Username.Get(); ok && user.Username != v {
+			continue
+		}
+		return user, true
+	}
+	return entities.User{}, false
+}
+
+func (r *fakeUserRepo) Create(ctx context.Context, attrs entities.UserAttrs, ent *entities.User) error {
+	*ent = entities.User{ID: attrs.ID, Username: attrs.Username, Email: attrs.Email, HashedPassword: attrs.HashedPassword}
+	r.users[attrs.ID] = *ent
+	return nil
+}
+
+func (r *fakeUserRepo) FindOne(ctx context.Context, filter entities.UserFilterAttrs, ent *entities.User) error {
+	user, ok := r.match(filter)
+	if !ok {
+		return ErrUserNotFound
+	}
+	*ent = user
+	return nil
+}
+
+func (r *fakeUserRepo) Update(ctx context.Context, attrs entities.UserUpdateAttrs, filter entities.UserFilterAttrs, ent *entities.User) error {
+	user, ok := r.match(filter)
+	if !ok {
+		return ErrUserNotFound
+	}
+	if v, ok := attrs.Email.Get(); ok && v != user.Email {
+		user.Email = v
+		user.EmailVerifiedAt = attrs.EmailVerifiedAt
+	}
+	if v, ok := attrs.HashedPassword.Get(); ok {
+		user.HashedPassword = v
+	}
+	if v, ok := attrs.EmailVerifiedAt.Get(); ok {
+		user.EmailVerifiedAt = mo.Some(v)
+	}
+	if v, ok := attrs.VerificationSentAt.Get(); ok {
+		user.VerificationSentAt = mo.Some(v)
+	}
+	r.users[user.ID] = user
+	*ent = user
+	return nil
+}
+
+func TestEmailVerification_RegisterAndVerify(t *testing.T) {
+	repo := newFakeUserRepo()
+	notifier := &notifierStub{}
+	verification := NewEmailVerificationService(repo, notifier, []byte("secret"), "https://app.example.com/verify")
+	register := NewRegisterService(repo, &hasherStub{}, &idGenStub{})
+	register.Verification = verification
+	ctx := context.Background()
+
+	registered, err := register.Register(ctx, RegisterRequest{Username: "demo", Email: "demo@example.com", Password: "password1"})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if len(notifier.messages) != 1 || notifier.messages[0].To != "demo@example.com" {
+		t.Fatalf("expected a verification message, got %+v", notifier.messages)
+	}
+	token := linkToken(t, notifier.messages[0].Body)
+
+	login := NewLoginService(repo, &hasherStub{}, newFakeSessionStore())
+	login.RequireVerifiedEmail = true
+	_, err = login.Login(ctx, LoginRequest{Email: "demo@example.com", Password: "password1"})
+	if !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("expected ErrEmailNotVerified, got: %v", err)
+	}
+
+	_, err = verification.Verify(ctx, VerifyEmailRequest{Token: token + "x"})
+	if !errors.Is(err, ErrVerificationTokenInvalid) {
+		t.Fatalf("tampered token must be rejected, got: %v", err)
+	}
+
+	verified, err := verification.Verify(ctx, VerifyEmailRequest{Token: token})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verified.User.ID != registered.User.ID || verified.User.EmailVerifiedAt.IsAbsent() {
+		t.Fatalf("user must be verified: %+v", verified.User)
+	}
+
+	_, err = login.Login(ctx, LoginRequest{Email: "demo@example.com", Password: "password1"})
+	if err != nil {
+		t.Fatalf("verified user must be able to log in, got: %v", err)
+	}
+}
+
+func TestEmailVerification_EmailChangeVoidsToken(t *testing.T) {
+	user := entitiesUser()
+	repo := newFakeUserRepo(user)
+	notifier := &notifierStub{}
+	verification := NewEmailVerificationService(repo, notifier, []byte("secret"), "https://app.example.com/verify")
+	ctx := context.Background()
+
+	if err := verification.Send(ctx, user); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	oldToken := linkToken(t, notifier.messages[0].Body)
+
+	update := NewUpdateService(repo, &hasherStub{}, newFakeSessionStore())
+	update.Verification = verification
+	_, err := update.Update(ctx, UpdateRequest{ID: user.ID, Email: mo.Some("new@example.com")})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if len(notifier.messages) != 2 || notifier.messages[1].To != "new@example.com" {
+		t.Fatalf("expected a message to the new address, got %+v", notifier.messages)
+	}
+
+	_, err = verification.Verify(ctx, VerifyEmailRequest{Token: oldToken})
+	if !errors.Is(err, ErrVerificationTokenInvalid) {
+		t.Fatalf("token for the old address must be rejected, got: %v", err)
+	}
+	_, err = verification.Verify(ctx, VerifyEmailRequest{Token: linkToken(t, notifier.messages[1].Body)})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+}
+
+func TestEmailVerification_ExpiredToken(t *testing.T) {
+	user := entitiesUser()
+	verification := NewEmailVerificationService(newFakeUserRepo(user), &notifierStub{}, []byte("secret"), "")
+
+	token := verification.sign(user.ID, user.Email, time.Now().Add(-time.Second))
+	_, err := verification.Verify(context.Background(), VerifyEmailRequest{Token: token})
+	if !errors.Is(err, ErrVerificationTokenInvalid) {
+		t.Fatalf("expected ErrVerificationTokenInvalid, got: %v", err)
+	}
+
+	other := NewEmailVerificationService(newFakeUserRepo(user), &notifierStub{}, []byte("other"), "")
+	token = other.sign(user.ID, user.Email, time.Now().Add(time.Hour))
+	_, err = verification.Verify(context.Background(), VerifyEmailRequest{Token: token})
+	if !errors.Is(err, ErrVerificationTokenInvalid) {
+		t.Fatalf("token signed with another key must be rejected, got: %v", err)
+	}
+}
+
+func TestEmailVerification_ResendThrottled(t *testing.T) {
+	user := entitiesUser()
+	notifier := &notifierStub{}
+	verification := NewEmailVerificationService(newFakeUserRepo(user), notifier, []byte("secret"), "")
+	ctx := context.Background()
+
+	for range 3 {
+		_, err := verification.Resend(ctx, ResendVerificationRequest{Email: user.Email})
+		if err != nil {
+			t.Fatalf("Resend failed: %v", err)
+		}
+	}
+	if len(notifier.messages) != 1 {
+		t.Fatalf("expected one message within the resend interval, got %d", len(notifier.messages))
+	}
+
+	_, err := verification.Resend(ctx, ResendVerificationRequest{Email: "nobody@example.com"})
+	if err != nil {
+		t.Fatalf("unknown emails must not be reported, got: %v", err)
+	}
+}
@@ -11,6 +11,39 @@ var (
 	ErrPasswordRequired  = errors.New("password is required")
 	ErrEmailIncorrect    = errors.New("incorrect email")
 	ErrPasswordIncorrect = errors.New("incorrect password")
+	ErrPasswordPolicy    = errors.New("password does not meet the policy")
+	ErrHashingOverloaded = errors.New("password hashing is overloaded")
 	ErrSessionNotFound   = errors.New("session not found")
 	ErrSessionExpired    = errors.New("session is expired")
+
+	ErrAccessTokenInvalid  = errors.New("access token is invalid")
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrStatelessDisabled   = errors.New("stateless tokens are not enabled")
+
+	ErrTokenNameRequired     = errors.New("token name is required")
+	ErrUnknownScope          = errors.New("unknown scope")
+	ErrTokenExpiryInPast     = errors.New("token expiry is in the past")
+	ErrPersonalTokenNotFound = errors.New("personal access token not found")
+	ErrPersonalTokenInvalid  = errors.New("personal access token is invalid")
+
+	ErrTwoFactorNotEnrolled      = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorCodeRequired     = errors.New("two-factor code is required")
+	ErrTwoFactorCodeInvalid      = errors.New("two-factor code is invalid")
+	ErrTwoFactorChallengeInvalid = errors.New("two-factor challenge is invalid or expired")
+
+	ErrOneTimeTokenInvalid = errors.New("token is invalid or expired")
+
+	ErrEmailNotVerified         = errors.New("email is not verified")
+	ErrVerificationTokenInvalid = errors.New("verification token is invalid or expired")
+
+	ErrLoginThrottled = errors.New("too many failed login attempts")
+
+	ErrUnknownIdentityProvider   = errors.New("unknown identity provider")
+	ErrExternalLoginStateInvalid = errors.New("external login state is invalid or expired")
+	ErrExternalLoginFailed       = errors.New("external login failed")
+	ErrExternalEmailNotVerified  = errors.New("identity provider did not confirm the email")
+	ErrExternalAccountConflict   = errors.New("an account with this email exists but its email is not verified")
+	ErrLinkedIdentityNotFound    = errors.New("linked identity not found")
 )
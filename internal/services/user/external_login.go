@@ -0,0 +1,281 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+const defaultExternalLoginStateTTL = 10 * time.Minute
+
+// usernameAttempts bounds how many suffixed usernames are tried when the one
+// suggested by the provider is taken.
+const usernameAttempts = 3
+
+// ExternalIdentity is what an identity provider asserts about a user in a
+// verified ID token.
+type ExternalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+}
+
+// IdentityProvider is an external OpenID Connect provider users sign in with.
+type IdentityProvider interface {
+	// AuthURL returns the address the user is sent to for signing in.
+	AuthURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error)
+	// Exchange redeems the code of the callback and returns the identity from
+	// the verified ID token, which must carry nonce.
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (ExternalIdentity, error)
+}
+
+type ExternalLoginState struct {
+	Hash         string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type ExternalLoginStateStore interface {
+	Create(ctx context.Context, state ExternalLoginState) error
+	// Consume returns the state and deletes it in one step. Unknown or
+	// expired states give ErrExternalLoginStateInvalid.
+	Consume(ctx context.Context, hash string) (ExternalLoginState, error)
+}
+
+type LinkedIdentityRepository interface {
+	Create(ctx context.Context, identity entities.LinkedIdentity) error
+	// FindOne returns ErrLinkedIdentityNotFound for unknown identities.
+	FindOne(ctx context.Context, provider string, subject string, ent *entities.LinkedIdentity) error
+}
+
+type ExternalLoginRepository interface {
+	FindOne(context.Context, entities.UserFilterAttrs, *entities.User) error
+	Update(context.Context, entities.UserUpdateAttrs, entities.UserFilterAttrs, *entities.User) error
+}
+
+type StartExternalLoginRequest struct {
+	Provider string
+}
+
+type StartExternalLoginResponse struct {
+	RedirectURL string
+	// State has to come back with the callback. Callers bind it to the
+	// browser that started the login, e.g. with a cookie.
+	State     string
+	ExpiresAt time.Time
+}
+
+type ExternalLoginCallbackRequest struct {
+	Provider  string
+	State     string
+	Code      string
+	Stateless bool
+	IP        string
+	UserAgent string
+}
+
+type ExternalLoginService struct {
+	Providers  map[string]IdentityProvider
+	Identities LinkedIdentityRepository
+	States     ExternalLoginStateStore
+	Repo       ExternalLoginRepository
+	Register   *RegisterService
+	Logins     *LoginService
+	StateTTL   time.Duration
+}
+
+func NewExternalLoginService(providers map[string]IdentityProvider, identities LinkedIdentityRepository, states ExternalLoginStateStore, repo ExternalLoginRepository, register *RegisterService, logins *LoginService) *ExternalLoginService {
+	return &ExternalLoginService{
+		Providers:  providers,
+		Identities: identities,
+		States:     states,
+		Repo:       repo,
+		Register:   register,
+		Logins:     logins,
+		StateTTL:   defaultExternalLoginStateTTL,
+	}
+}
+
+// Start begins an authorization code flow with PKCE at the provider.
+func (s *ExternalLoginService) Start(ctx context.Context, req StartExternalLoginRequest) (StartExternalLoginResponse, error) {
+	provider, ok := s.Providers[req.Provider]
+	if !ok {
+		return StartExternalLoginResponse{}, ErrUnknownIdentityProvider
+	}
+
+	state, hash, err := newOpaqueToken("")
+	if err != nil {
+		return StartExternalLoginResponse{}, err
+	}
+	nonce, _, err := newOpaqueToken("")
+	if err != nil {
+		return StartExternalLoginResponse{}, err
+	}
+	verifier, _, err := newOpaqueToken("")
+	if err != nil {
+		return StartExternalLoginResponse{}, err
+	}
+
+	expiresAt := time.Now().Add(s.StateTTL)
+	err = s.States.Create(ctx, ExternalLoginState{
+		Hash:         hash,
+		Provider:     req.Provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		return StartExternalLoginResponse{}, err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	redirectURL, err := provider.AuthURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return StartExternalLoginResponse{}, err
+	}
+	return StartExternalLoginResponse{RedirectURL: redirectURL, State: state, ExpiresAt: expiresAt}, nil
+}
+
+// Callback finishes the flow begun by Start. The identity is looked up by
+// its link first. Unlinked identities are linked to the user with the same
+// email, or to a new user, but only if the provider vouches for the email.
+func (s *ExternalLoginService) Callback(ctx context.Context, req ExternalLoginCallbackRequest) (LoginResponse, error) {
+	provider, ok := s.Providers[req.Provider]
+	if !ok {
+		return LoginResponse{}, ErrUnknownIdentityProvider
+	}
+
+	state, err := s.States.Consume(ctx, hashOpaqueToken(req.State))
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if state.Provider != req.Provider {
+		return LoginResponse{}, ErrExternalLoginStateInvalid
+	}
+
+	identity, err := provider.Exchange(ctx, req.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return LoginResponse{}, fmt.Errorf("%w: %v", ErrExternalLoginFailed, err)
+	}
+	if identity.Subject == "" {
+		return LoginResponse{}, fmt.Errorf("%w: identity has no subject", ErrExternalLoginFailed)
+	}
+
+	user, err := s.linkedUser(ctx, req.Provider, identity)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	// The provider vouches for its own email only, which need not be the one
+	// of the account an existing link points to.
+	if s.Logins.RequireVerifiedEmail && user.EmailVerifiedAt.IsAbsent() {
+		return LoginResponse{}, ErrEmailNotVerified
+	}
+
+	return s.Logins.firstFactorPassed(ctx, user, LoginRequest{
+		Stateless: req.Stateless,
+		IP:        req.IP,
+		UserAgent: req.UserAgent,
+	})
+}
+
+func (s *ExternalLoginService) linkedUser(ctx context.Context, provider string, identity ExternalIdentity) (entities.User, error) {
+	var user entities.User
+	var link entities.LinkedIdentity
+	err := s.Identities.FindOne(ctx, provider, identity.Subject, &link)
+	if err == nil {
+		err = s.Repo.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(link.UserID)}, &user)
+		return user, err
+	}
+	if !errors.Is(err, ErrLinkedIdentityNotFound) {
+		return entities.User{}, err
+	}
+
+	email := NormalizeEmail(identity.Email)
+	if email == "" || !identity.EmailVerified {
+		return entities.User{}, ErrExternalEmailNotVerified
+	}
+
+	err = s.Repo.FindOne(ctx, entities.UserFilterAttrs{Email: mo.Some(email)}, &user)
+	switch {
+	case err == nil:
+		// Whoever registered an unverified address may not own it; linking
+		// would hand the account of the real owner to them.
+		if user.EmailVerifiedAt.IsAbsent() {
+			return entities.User{}, ErrExternalAccountConflict
+		}
+	case errors.Is(err, ErrUserNotFound):
+		user, err = s.createUser(ctx, identity, email)
+		if err != nil {
+			return entities.User{}, err
+		}
+	default:
+		return entities.User{}, err
+	}
+
+	err = s.Identities.Create(ctx, entities.LinkedIdentity{
+		Provider:  provider,
+		Subject:   identity.Subject,
+		UserID:    user.ID,
+		Email:     email,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return entities.User{}, err
+	}
+	return user, nil
+}
+
+// createUser registers a user with a random password, which can be replaced
+// through a password reset, and an email the provider already verified.
+func (s *ExternalLoginService) createUser(ctx context.Context, identity ExternalIdentity, email string) (entities.User, error) {
+	password, _, err := newOpaqueToken("")
+	if err != nil {
+		return entities.User{}, err
+	}
+
+	// No verification mail: the provider has confirmed the address. The
+	// random password need not meet character class rules meant for people,
+	// and a taken email has to be reported rather than concealed.
+	register := *s.Register
+	register.Verification = nil
+	register.PasswordPolicy = &PasswordPolicy{}
+	register.ConcealExistingEmail = false
+
+	base := strings.TrimSpace(identity.Username)
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	username := base
+	for attempt := 0; ; attempt++ {
+		resp, err := register.Register(ctx, RegisterRequest{Username: username, Email: email, Password: password})
+		if errors.Is(err, ErrUsernameTaken) && attempt < usernameAttempts {
+			suffix := make([]byte, 3)
+			if _, err := rand.Read(suffix); err != nil {
+				return entities.User{}, err
+			}
+			username = base + "-" + hex.EncodeToString(suffix)
+			continue
+		}
+		if err != nil {
+			return entities.User{}, err
+		}
+
+		var user entities.User
+		err = s.Repo.Update(ctx, entities.UserUpdateAttrs{
+			EmailVerifiedAt: mo.Some(time.Now().UTC()),
+		}, entities.UserFilterAttrs{ID: mo.Some(resp.User.ID)}, &user)
+		return user, err
+	}
+}
@@ -0,0 +1,204 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+// identityProviderStub plays the provider side of the flow: it checks PKCE
+// and the nonce the way a real provider and ID token would.
+type identityProviderStub struct {
+	identity  ExternalIdentity
+	challenge string
+	nonce     string
+}
+
+func (p *identityProviderStub) AuthURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	p.challenge, p.nonce = codeChallenge, nonce
+	return "https://idp.example.com/authorize?" + url.Values{"state": {state}}.Encode(), nil
+}
+
+func (p *identityProviderStub) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (ExternalIdentity, error) {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	if code != "good" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge || nonce != p.nonce {
+		return ExternalIdentity{}, errors.New("invalid_grant")
+	}
+	return p.identity, nil
+}
+
+type fakeExternalLoginStateStore struct {
+	states map[string]ExternalLoginState
+}
+
+func (s *fakeExternalLoginStateStore) Create(ctx context.Context, state ExternalLoginState) error {
+	s.states[state.Hash] = state
+	return nil
+}
+
+func (s *fakeExternalLoginStateStore) Consume(ctx context.Context, hash string) (ExternalLoginState, error) {
+	state, ok := s.states[hash]
+	delete(s.states, hash)
+	if !ok || time.Now().After(state.ExpiresAt) {
+		return ExternalLoginState{}, ErrExternalLoginStateInvalid
+	}
+	return state, nil
+}
+
+type fakeLinkedIdentityRepo struct {
+	identities map[string]entities.LinkedIdentity
+}
+
+func (r *fakeLinkedIdentityRepo) Create(ctx context.Context, identity entities.LinkedIdentity) error {
+	r.identities[identity.Provider+"/"+identity.Subject] = identity
+	return nil
+}
+
+func (r *fakeLinkedIdentityRepo) FindOne(ctx context.Context, provider string, subject string, ent *entities.LinkedIdentity) error {
+	identity, ok := r.identities[provider+"/"+subject]
+	if !ok {
+		return ErrLinkedIdentityNotFound
+	}
+	*ent = identity
+	return nil
+}
+
+func newExternalLoginService(users ...entities.User) (*ExternalLoginService, *identityProviderStub, *fakeUserRepo, *notifierStub) {
+	repo := newFakeUserRepo(users...)
+	notifier := &notifierStub{}
+	register := NewRegisterService(repo, &hasherStub{}, &idGenStub{})
+	register.Verification = NewEmailVerificationService(repo, notifier, []byte("secret"), "https://app.example.com/verify")
+	login := NewLoginService(repo, &hasherStub{}, newFakeSessionStore())
+	provider := &identityProviderStub{identity: ExternalIdentity{
+		Subject:       "external-1",
+		Email:         "Demo@Example.com",
+		EmailVerified: true,
+		Username:      "demo",
+	}}
+	service := NewExternalLoginService(map[string]IdentityProvider{"idp": provider},
+		&fakeLinkedIdentityRepo{identities: map[string]entities.LinkedIdentity{}},
+		&fakeExternalLoginStateStore{states: map[string]ExternalLoginState{}},
+		repo, register, login)
+	return service, provider, repo, notifier
+}
+
+// externalLogin runs the whole flow the way the browser would.
+func externalLogin(t *testing.T, service *ExternalLoginService) (LoginResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	started, err := service.Start(ctx, StartExternalLoginRequest{Provider: "idp"})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !strings.Contains(started.RedirectURL, url.QueryEscape(started.State)) {
+		t.Fatalf("state missing from %q", started.RedirectURL)
+	}
+	return service.Callback(ctx, ExternalLoginCallbackRequest{Provider: "idp", State: started.State, Code: "good"})
+}
+
+func TestExternalLogin_CreatesAndLinksUser(t *testing.T) {
+	service, provider, repo, notifier := newExternalLoginService()
+
+	resp, err := externalLogin(t, service)
+	if err != nil {
+		t.Fatalf("external login failed: %v", err)
+	}
+	created := resp.User
+	if created.Email != "demo@example.com" || created.Username != "demo" || created.EmailVerifiedAt.IsAbsent() {
+		t.Fatalf("unexpected user: %+v", created)
+	}
+	if resp.Session.UserID != created.ID {
+		t.Fatalf("expected a session for the new user, got %+v", resp.Session)
+	}
+	if len(notifier.messages) != 0 {
+		t.Fatalf("email confirmed by the provider must not be verified again: %+v", notifier.messages)
+	}
+
+	// The link wins over the email from now on.
+	provider.identity.Email = "renamed@example.com"
+	resp, err = externalLogin(t, service)
+	if err != nil || resp.User.ID != created.ID || len(repo.users) != 1 {
+		t.Fatalf("expected the linked user, got %+v %v", resp.User, err)
+	}
+}
+
+func TestExternalLogin_LinksVerifiedUser(t *testing.T) {
+	existing := entities.User{ID: "1", Email: "demo@example.com", Username: "demo", EmailVerifiedAt: mo.Some(time.Now())}
+	service, _, repo, _ := newExternalLoginService(existing)
+
+	resp, err := externalLogin(t, service)
+	if err != nil || resp.User.ID != "1" || len(repo.users) != 1 {
+		t.Fatalf("expected the existing user, got %+v %v", resp.User, err)
+	}
+}
+
+func TestExternalLogin_RefusesUnverifiedEmails(t *testing.T) {
+	unverified := entities.User{ID: "1", Email: "demo@example.com", Username: "demo"}
+	service, provider, _, _ := newExternalLoginService(unverified)
+
+	if _, err := externalLogin(t, service); !errors.Is(err, ErrExternalAccountConflict) {
+		t.Fatalf("must not link to an unverified account, got: %v", err)
+	}
+
+	provider.identity.Subject, provider.identity.EmailVerified = "external-2", false
+	if _, err := externalLogin(t, service); !errors.Is(err, ErrExternalEmailNotVerified) {
+		t.Fatalf("expected ErrExternalEmailNotVerified, got: %v", err)
+	}
+}
+
+func TestExternalLogin_LinkedUserRequiresVerifiedEmail(t *testing.T) {
+	unverified := entities.User{ID: "1", Email: "demo@example.com", Username: "demo"}
+	service, _, _, _ := newExternalLoginService(unverified)
+	service.Identities.(*fakeLinkedIdentityRepo).identities["idp/external-1"] = entities.LinkedIdentity{
+		Provider: "idp", Subject: "external-1", UserID: "1", Email: "demo@example.com",
+	}
+
+	if _, err := externalLogin(t, service); err != nil {
+		t.Fatalf("verification is not required, got: %v", err)
+	}
+
+	service.Logins.RequireVerifiedEmail = true
+	resp, err := externalLogin(t, service)
+	if !errors.Is(err, ErrEmailNotVerified) || resp.Session.ID != "" {
+		t.Fatalf("expected ErrEmailNotVerified without a session, got %+v %v", resp, err)
+	}
+}
+
+func TestExternalLogin_UsernameTaken(t *testing.T) {
+	other := entities.User{ID: "1", Email: "other@example.com", Username: "demo"}
+	service, _, _, _ := newExternalLoginService(other)
+
+	resp, err := externalLogin(t, service)
+	if err != nil {
+		t.Fatalf("external login failed: %v", err)
+	}
+	if !strings.HasPrefix(resp.User.Username, "demo-") {
+		t.Fatalf("expected a suffixed username, got %q", resp.User.Username)
+	}
+}
+
+func TestExternalLogin_State(t *testing.T) {
+	service, _, _, _ := newExternalLoginService()
+	ctx := context.Background()
+
+	if _, err := service.Start(ctx, StartExternalLoginRequest{Provider: "unknown"}); !errors.Is(err, ErrUnknownIdentityProvider) {
+		t.Fatalf("expected ErrUnknownIdentityProvider, got: %v", err)
+	}
+
+	started, _ := service.Start(ctx, StartExternalLoginRequest{Provider: "idp"})
+	if _, err := service.Callback(ctx, ExternalLoginCallbackRequest{Provider: "idp", State: started.State, Code: "bad"}); !errors.Is(err, ErrExternalLoginFailed) {
+		t.Fatalf("expected ErrExternalLoginFailed, got: %v", err)
+	}
+	if _, err := service.Callback(ctx, ExternalLoginCallbackRequest{Provider: "idp", State: started.State, Code: "good"}); !errors.Is(err, ErrExternalLoginStateInvalid) {
+		t.Fatalf("state must be single use, got: %v", err)
+	}
+}
@@ -3,28 +3,70 @@ package user
 import (
 	"context"
 	"crud/internal/domain/entities"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/samber/mo"
 )
 
 type LoginRequest struct {
-	Email    string
-	Password string
+	Email      string
+	Password   string
+	RememberMe bool
+	// Stateless asks for a JWT access token and a refresh token instead of
+	// a server-side session.
+	Stateless bool
+	IP        string
+	UserAgent string
 }
 
 type LoginResponse struct {
-	User entities.User
+	User    entities.User
 	Session Session
+	Tokens  TokenPair
+	// Pending is set instead of credentials when the user has two-factor
+	// authentication enabled and has to complete the login with a code.
+	Pending mo.Option[PendingLogin]
+}
+
+type PendingLogin struct {
+	Challenge string
+	ExpiresAt time.Time
+}
+
+type CompleteTwoFactorRequest struct {
+	Challenge string
+	Code      string
+	Stateless bool
+	IP        string
+	UserAgent string
 }
 
 type LoginRepository interface {
 	FindOne(context.Context, entities.UserFilterAttrs, *entities.User) error
+	Update(context.Context, entities.UserUpdateAttrs, entities.UserFilterAttrs, *entities.User) error
 }
 
 type LoginService struct {
 	Repo   LoginRepository
 	Hasher PasswordHasher
 	SessionStore SessionStore
+	// Tokens issues stateless credentials. Stateless logins fail with
+	// ErrStatelessDisabled when it is nil.
+	Tokens *TokenService
+	// TwoFactor adds a second login step for users who enabled it. Logins
+	// are completed after the password alone when it is nil.
+	TwoFactor *TwoFactorService
+	// RequireVerifiedEmail rejects logins of users who have not confirmed
+	// their email with ErrEmailNotVerified.
+	RequireVerifiedEmail bool
+	// Throttle slows down and locks out repeated password and second factor
+	// failures. Logins are not throttled when it is nil.
+	Throttle *LoginThrottle
+
+	dummyMu   sync.Mutex
+	dummyHash string
 }
 
 func NewLoginService(repo LoginRepository,hasher PasswordHasher,sessionStore SessionStore) *LoginService {
@@ -52,11 +94,22 @@ func (s *LoginService) Login(ctx context.Context, req LoginRequest) (LoginRespon
 		return LoginResponse{}, ErrEmailIncorrect
 	}
 
+	if s.Throttle != nil {
+		if err := s.Throttle.Check(ctx, email, req.IP); err != nil {
+			return LoginResponse{}, err
+		}
+	}
+
 	var user entities.User
 	err := s.Repo.FindOne(ctx, entities.UserFilterAttrs{Email: mo.Some(email)}, &user)
 
 	if err == ErrUserNotFound {
-		return LoginResponse{}, ErrUserNotFound
+		// Compare anyway so that unknown emails take as long as wrong
+		// passwords and fail the same way when the hasher does.
+		if err := s.compareDummy(ctx, password); err != nil {
+			return LoginResponse{}, err
+		}
+		return LoginResponse{}, s.loginFailed(ctx, email, req.IP, ErrUserNotFound)
 	}
 	if err != nil {
 		return LoginResponse{}, err
@@ -64,13 +117,193 @@ func (s *LoginService) Login(ctx context.Context, req LoginRequest) (LoginRespon
 
 	err = s.Hasher.Compare(ctx, user.HashedPassword, password)
 	if err == ErrPasswordIncorrect {
-		return LoginResponse{}, ErrPasswordIncorrect
+		return LoginResponse{}, s.loginFailed(ctx, email, req.IP, ErrPasswordIncorrect)
+	}
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	if s.Hasher.NeedsRehash(user.HashedPassword) {
+		// The old hash still works, so a failed upgrade is retried at the
+		// next login instead of failing this one.
+		_ = s.rehash(ctx, &user, password)
+	}
+
+	if s.RequireVerifiedEmail && user.EmailVerifiedAt.IsAbsent() {
+		return LoginResponse{}, ErrEmailNotVerified
+	}
+
+	resp, err := s.firstFactorPassed(ctx, user, req)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	// A pending second factor is not a success yet: the failures are kept
+	// until CompleteTwoFactor accepts the code.
+	if resp.Pending.IsAbsent() {
+		if err := s.loginSucceeded(ctx, email); err != nil {
+			return LoginResponse{}, err
+		}
+	}
+	return resp, nil
+}
+
+// rehash replaces the stored hash of user with one made by the current
+// algorithm and parameters, now that the plaintext is known to match.
+func (s *LoginService) rehash(ctx context.Context, user *entities.User, password string) error {
+	hash, err := s.Hasher.Hash(ctx, password)
+	if err != nil {
+		return err
+	}
+	return s.Repo.Update(ctx, entities.UserUpdateAttrs{
+		HashedPassword: mo.Some(hash),
+	}, entities.UserFilterAttrs{ID: mo.Some(user.ID)}, user)
+}
+
+// PrepareDummyHash makes the hash unknown emails are compared against, so
+// that the first of them is not slower than the rest. It is made at the
+// first unknown email otherwise.
+func (s *LoginService) PrepareDummyHash(ctx context.Context) error {
+	s.dummyMu.Lock()
+	defer s.dummyMu.Unlock()
+	return s.prepareDummyHash(ctx)
+}
+
+func (s *LoginService) prepareDummyHash(ctx context.Context) error {
+	if s.dummyHash != "" {
+		return nil
+	}
+	secret, _, err := newOpaqueToken("")
+	if err != nil {
+		return err
+	}
+	hash, err := s.Hasher.Hash(ctx, secret)
+	if err != nil {
+		return err
+	}
+	s.dummyHash = hash
+	return nil
+}
+
+// compareDummy checks password against a hash of a random password made by
+// the current hasher, which costs as much as checking a real one. It returns
+// the errors of the hasher other than a mismatch, as the check of a real
+// password would.
+func (s *LoginService) compareDummy(ctx context.Context, password string) error {
+	s.dummyMu.Lock()
+	err := s.prepareDummyHash(ctx)
+	hash := s.dummyHash
+	s.dummyMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = s.Hasher.Compare(ctx, hash, password)
+	if err != nil && err != ErrPasswordIncorrect {
+		return err
+	}
+	return nil
+}
+
+// loginFailed counts a failed password or second factor check against the
+// throttle and returns cause, or the error of the throttle store.
+func (s *LoginService) loginFailed(ctx context.Context, email string, ip string, cause error) error {
+	if s.Throttle == nil {
+		return cause
+	}
+	if err := s.Throttle.Fail(ctx, email, ip); err != nil {
+		return err
 	}
+	return cause
+}
+
+// loginSucceeded clears the failures of the account once the login is
+// complete.
+func (s *LoginService) loginSucceeded(ctx context.Context, email string) error {
+	if s.Throttle == nil {
+		return nil
+	}
+	return s.Throttle.Succeed(ctx, email)
+}
+
+// firstFactorPassed continues a login whose first factor, a password, a
+// mailed link or an external identity provider, has been checked. It asks
+// for the second factor if the user enabled one and issues credentials
+// otherwise.
+func (s *LoginService) firstFactorPassed(ctx context.Context, user entities.User, req LoginRequest) (LoginResponse, error) {
+	if s.TwoFactor != nil {
+		enabled, err := s.TwoFactor.enabled(ctx, user.ID)
+		if err != nil {
+			return LoginResponse{}, err
+		}
+		if enabled {
+			pending, err := s.TwoFactor.startChallenge(ctx, user.ID, req.RememberMe)
+			if err != nil {
+				return LoginResponse{}, err
+			}
+			return LoginResponse{Pending: mo.Some(pending)}, nil
+		}
+	}
+
+	return s.issue(ctx, user, req)
+}
+
+// CompleteTwoFactor finishes a login started by Login with a TOTP or recovery
+// code and issues the credentials.
+func (s *LoginService) CompleteTwoFactor(ctx context.Context, req CompleteTwoFactorRequest) (LoginResponse, error) {
+	if s.TwoFactor == nil {
+		return LoginResponse{}, ErrTwoFactorChallengeInvalid
+	}
+
+	challenge, codeErr := s.TwoFactor.completeChallenge(ctx, req.Challenge, req.Code)
+	if codeErr != nil && !errors.Is(codeErr, ErrTwoFactorCodeInvalid) {
+		return LoginResponse{}, codeErr
+	}
+
+	var user entities.User
+	err := s.Repo.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(challenge.UserID)}, &user)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if codeErr != nil {
+		// Wrong codes count like wrong passwords, otherwise whoever knows
+		// the password could keep guessing codes with new challenges.
+		return LoginResponse{}, s.loginFailed(ctx, user.Email, req.IP, codeErr)
+	}
+
+	resp, err := s.issue(ctx, user, LoginRequest{
+		RememberMe: challenge.RememberMe,
+		Stateless:  req.Stateless,
+		IP:         req.IP,
+		UserAgent:  req.UserAgent,
+	})
 	if err != nil {
 		return LoginResponse{}, err
 	}
+	if err := s.loginSucceeded(ctx, user.Email); err != nil {
+		return LoginResponse{}, err
+	}
+	return resp, nil
+}
+
+// issue hands out credentials to a user whose identity has been verified.
+func (s *LoginService) issue(ctx context.Context, user entities.User, req LoginRequest) (LoginResponse, error) {
+	if req.Stateless {
+		if s.Tokens == nil {
+			return LoginResponse{}, ErrStatelessDisabled
+		}
+		resp, err := s.Tokens.Issue(ctx, IssueTokensRequest{UserID: user.ID})
+		if err != nil {
+			return LoginResponse{}, err
+		}
+		return LoginResponse{User: user, Tokens: resp.Tokens}, nil
+	}
 
-	session, err := s.SessionStore.Create(ctx, user.ID)
+	session, err := s.SessionStore.Create(ctx, SessionAttrs{
+		UserID:     user.ID,
+		IP:         req.IP,
+		UserAgent:  req.UserAgent,
+		Persistent: req.RememberMe,
+	})
 	if err != nil {
 		return LoginResponse{}, err
 	}
@@ -9,8 +9,9 @@ import (
 )
 
 type loginRepoStub struct {
-	user entities.User
-	err  error
+	user    entities.User
+	err     error
+	updates []entities.UserUpdateAttrs
 }
 
 func (r *loginRepoStub) FindOne(ctx context.Context, attrs entities.UserFilterAttrs, ent *entities.User) error {
@@ -19,7 +20,7 @@ func (r *loginRepoStub) FindOne(ctx context.Context, attrs entities.UserFilterAt
 	}
 
 	email, ok := attrs.Email.Get()
-	if !ok || email == "" {
+	if (!ok || email == "") && attrs.ID.IsAbsent() {
 		return ErrEmailRequired
 	}
 
@@ -27,33 +28,62 @@ func (r *loginRepoStub) FindOne(ctx context.Context, attrs entities.UserFilterAt
 	return nil
 }
 
+func (r *loginRepoStub) Update(ctx context.Context, attrs entities.UserUpdateAttrs, filterAttrs entities.UserFilterAttrs, ent *entities.User) error {
+	r.updates = append(r.updates, attrs)
+	if v, ok := attrs.HashedPassword.Get(); ok {
+		r.user.HashedPassword = v
+	}
+	*ent = r.user
+	return nil
+}
+
 type hasherStub struct {
-	compareErr error
+	hashErr     error
+	compareErr  error
+	needsRehash bool
+	// hashes and compares count the calls, compared holds the hashes
+	// passed to Compare.
+	hashes   int
+	compares int
+	compared []string
 }
 
 func (h *hasherStub) Hash(ctx context.Context, plaintext string) (string, error) {
+	h.hashes++
+	if h.hashErr != nil {
+		return "", h.hashErr
+	}
 	return "hashed", nil
 }
 
 func (h *hasherStub) Compare(ctx context.Context, hash string, plaintext string) error {
+	h.compares++
+	h.compared = append(h.compared, hash)
 	return h.compareErr
 }
 
+func (h *hasherStub) NeedsRehash(hash string) bool {
+	return h.needsRehash
+}
+
 type sessionStoreStub struct {
 	session   Session
 	createErr error
 }
 
-func (s *sessionStoreStub) Create(ctx context.Context, userID string) (Session, error) {
+func (s *sessionStoreStub) Create(ctx context.Context, attrs SessionAttrs) (Session, error) {
 	if s.createErr != nil {
 		return Session{}, s.createErr
 	}
 
 	if s.session.ID == "" {
 		s.session = Session{
-			ID:        "session-1",
-			UserID:    userID,
-			ExpiresAt: time.Time{},
+			ID:         "session-1",
+			UserID:     attrs.UserID,
+			IP:         attrs.IP,
+			UserAgent:  attrs.UserAgent,
+			Persistent: attrs.Persistent,
+			ExpiresAt:  time.Time{},
 		}
 	}
 	return s.session, nil
@@ -63,10 +93,22 @@ func (s *sessionStoreStub) Get(ctx context.Context, sessionID string) (Session,
 	return Session{}, nil
 }
 
+func (s *sessionStoreStub) Touch(ctx context.Context, sessionID string, seenAt time.Time) (Session, error) {
+	return Session{}, nil
+}
+
 func (s *sessionStoreStub) Delete(ctx context.Context, sessionID string) error {
 	return nil
 }
 
+func (s *sessionStoreStub) ListByUser(ctx context.Context, userID string) ([]Session, error) {
+	return nil, nil
+}
+
+func (s *sessionStoreStub) DeleteByUser(ctx context.Context, userID string) error {
+	return nil
+}
+
 func TestLogin_Success(t *testing.T) {
 	repo := &loginRepoStub{
 		user: entities.User{
@@ -81,14 +123,15 @@ func TestLogin_Success(t *testing.T) {
 	loginService := NewLoginService(repo, hasher, store)
 
 	ctx := context.Background()
-	request := LoginRequest{Email: "islam@gmail.com", Password: "secret"}
+	request := LoginRequest{Email: "islam@gmail.com", Password: "secret", IP: "203.0.113.7", UserAgent: "test-agent"}
 
 	response, err := loginService.Login(ctx, request)
 	if err != nil {
 		t.Fatalf("expected nil, got: %v", err)
 	}
 
-	if response.Session.ID == "" || response.Session.UserID != repo.user.ID {
+	if response.Session.ID == "" || response.Session.UserID != repo.user.ID ||
+		response.Session.IP != request.IP || response.Session.UserAgent != request.UserAgent {
 		t.Fatalf("unexpected session: %+v", response.Session)
 	}
 
@@ -136,6 +179,72 @@ func TestLogin_UserNotFound(t *testing.T) {
 	}
 }
 
+func TestLogin_UnknownEmailComparesLikeWrongPassword(t *testing.T) {
+	ctx := context.Background()
+
+	wrongHasher := &hasherStub{compareErr: ErrPasswordIncorrect}
+	wrong := NewLoginService(&loginRepoStub{user: entitiesUser()}, wrongHasher, &sessionStoreStub{})
+	_, wrongErr := wrong.Login(ctx, LoginRequest{Email: "islam@gmail.com", Password: "wrong"})
+
+	unknownHasher := &hasherStub{compareErr: ErrPasswordIncorrect}
+	unknown := NewLoginService(&loginRepoStub{err: ErrUserNotFound}, unknownHasher, &sessionStoreStub{})
+	_, unknownErr := unknown.Login(ctx, LoginRequest{Email: "missing@example.com", Password: "wrong"})
+
+	if !errors.Is(wrongErr, ErrPasswordIncorrect) || !errors.Is(unknownErr, ErrUserNotFound) {
+		t.Fatalf("unexpected errors: %v, %v", wrongErr, unknownErr)
+	}
+	if wrongHasher.compares != 1 || unknownHasher.compares != 1 {
+		t.Fatalf("both paths must compare once, got %d and %d", wrongHasher.compares, unknownHasher.compares)
+	}
+	if unknownHasher.compared[0] != "hashed" {
+		t.Fatalf("unknown emails must be compared against a hash of the current hasher, got %q", unknownHasher.compared[0])
+	}
+
+	_, _ = unknown.Login(ctx, LoginRequest{Email: "missing@example.com", Password: "wrong"})
+	if unknownHasher.hashes != 1 || unknownHasher.compares != 2 {
+		t.Fatalf("the dummy hash must be made once and reused, got %d hashes", unknownHasher.hashes)
+	}
+}
+
+func TestLogin_PrepareDummyHash(t *testing.T) {
+	ctx := context.Background()
+	hasher := &hasherStub{compareErr: ErrPasswordIncorrect}
+	service := NewLoginService(&loginRepoStub{err: ErrUserNotFound}, hasher, &sessionStoreStub{})
+
+	if err := service.PrepareDummyHash(ctx); err != nil {
+		t.Fatalf("PrepareDummyHash failed: %v", err)
+	}
+	if hasher.hashes != 1 {
+		t.Fatalf("expected the dummy hash to be made up front, got %d hashes", hasher.hashes)
+	}
+	_, _ = service.Login(ctx, LoginRequest{Email: "missing@example.com", Password: "wrong"})
+	if hasher.hashes != 1 || hasher.compares != 1 {
+		t.Fatalf("login must only compare with the prepared hash, got %d hashes", hasher.hashes)
+	}
+
+	failing := NewLoginService(&loginRepoStub{err: ErrUserNotFound}, &hasherStub{hashErr: ErrHashingOverloaded}, &sessionStoreStub{})
+	if err := failing.PrepareDummyHash(ctx); !errors.Is(err, ErrHashingOverloaded) {
+		t.Fatalf("expected the hasher error, got: %v", err)
+	}
+	if _, err := failing.Login(ctx, LoginRequest{Email: "missing@example.com", Password: "wrong"}); !errors.Is(err, ErrHashingOverloaded) {
+		t.Fatalf("a missing dummy hash must not skip the comparison, got: %v", err)
+	}
+}
+
+func TestLogin_UnknownEmailHasherErrors(t *testing.T) {
+	ctx := context.Background()
+
+	known := NewLoginService(&loginRepoStub{user: entitiesUser()}, &hasherStub{compareErr: ErrHashingOverloaded}, &sessionStoreStub{})
+	_, knownErr := known.Login(ctx, LoginRequest{Email: "islam@gmail.com", Password: "wrong"})
+
+	unknown := NewLoginService(&loginRepoStub{err: ErrUserNotFound}, &hasherStub{compareErr: ErrHashingOverloaded}, &sessionStoreStub{})
+	_, unknownErr := unknown.Login(ctx, LoginRequest{Email: "missing@example.com", Password: "wrong"})
+
+	if !errors.Is(knownErr, ErrHashingOverloaded) || !errors.Is(unknownErr, ErrHashingOverloaded) {
+		t.Fatalf("both paths must report the overloaded hasher, got %v and %v", knownErr, unknownErr)
+	}
+}
+
 func TestLogin_SessionCreationFailure(t *testing.T) {
 	repo := &loginRepoStub{
 		user: entities.User{
@@ -158,3 +267,57 @@ func TestLogin_SessionCreationFailure(t *testing.T) {
 		t.Fatalf("expected session create error, got: %v", err)
 	}
 }
+
+func TestLogin_RememberMe(t *testing.T) {
+	repo := &loginRepoStub{
+		user: entities.User{
+			ID:             "1",
+			Email:          "islam@gmail.com",
+			Username:       "islam",
+			HashedPassword: "hashed",
+		},
+	}
+	store := &sessionStoreStub{}
+	loginService := NewLoginService(repo, &hasherStub{}, store)
+
+	response, err := loginService.Login(context.Background(), LoginRequest{
+		Email:      "islam@gmail.com",
+		Password:   "secret",
+		RememberMe: true,
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if !response.Session.Persistent {
+		t.Fatalf("expected persistent session, got: %+v", response.Session)
+	}
+}
+
+func entitiesUser() entities.User {
+	return entities.User{
+		ID:             "1",
+		Email:          "islam@gmail.com",
+		Username:       "islam",
+		HashedPassword: "hashed",
+	}
+}
+
+func TestLogin_RehashesOutdatedHash(t *testing.T) {
+	repo := &loginRepoStub{user: entitiesUser()}
+	repo.user.HashedPassword = "outdated"
+	hasher := &hasherStub{needsRehash: true}
+	service := NewLoginService(repo, hasher, &sessionStoreStub{})
+
+	if _, err := service.Login(context.Background(), LoginRequest{Email: "islam@gmail.com", Password: "password123"}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if len(repo.updates) != 1 || repo.user.HashedPassword != "hashed" {
+		t.Fatalf("expected the hash to be upgraded, got %+v", repo.updates)
+	}
+
+	hasher.needsRehash = false
+	_, _ = service.Login(context.Background(), LoginRequest{Email: "islam@gmail.com", Password: "password123"})
+	if len(repo.updates) != 1 {
+		t.Fatalf("current hashes must be left alone")
+	}
+}
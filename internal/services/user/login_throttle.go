@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ThrottlePolicy says how failed logins slow down further attempts. The first
+// FreeAttempts failures cost nothing; each one after that doubles the wait
+// before the next attempt, starting at BaseDelay and capped at MaxDelay. At
+// LockoutThreshold failures attempts are refused for LockoutDuration.
+type ThrottlePolicy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+}
+
+var (
+	DefaultAccountThrottle = ThrottlePolicy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+	}
+	// DefaultIPThrottle is looser than the account policy because many users
+	// can share an address behind NAT.
+	DefaultIPThrottle = ThrottlePolicy{
+		FreeAttempts:     10,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 100,
+		LockoutDuration:  15 * time.Minute,
+	}
+)
+
+const defaultThrottleWindow = time.Hour
+
+type LoginFailures struct {
+	Count        int
+	LastFailedAt time.Time
+}
+
+type LoginAttemptStore interface {
+	// Get returns a zero LoginFailures for keys without recorded failures.
+	Get(ctx context.Context, key string) (LoginFailures, error)
+	// Fail records a failure at failedAt and returns the updated record. The
+	// record is forgotten window after the last failure.
+	Fail(ctx context.Context, key string, failedAt time.Time, window time.Duration) (LoginFailures, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// ThrottledError is returned while logins are held back. It matches
+// ErrLoginThrottled with errors.Is.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrLoginThrottled, e.RetryAfter)
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
+
+// LoginThrottle counts failed password logins per account and per client IP.
+type LoginThrottle struct {
+	Store   LoginAttemptStore
+	Account ThrottlePolicy
+	IP      ThrottlePolicy
+	// Window is how long failures are remembered after the last one. It
+	// should be at least as long as the lockout durations.
+	Window time.Duration
+}
+
+func NewLoginThrottle(store LoginAttemptStore) *LoginThrottle {
+	return &LoginThrottle{
+		Store:   store,
+		Account: DefaultAccountThrottle,
+		IP:      DefaultIPThrottle,
+		Window:  defaultThrottleWindow,
+	}
+}
+
+// Check returns a ThrottledError if either the account or the client IP has
+// to wait before the next attempt.
+func (t *LoginThrottle) Check(ctx context.Context, email string, ip string) error {
+	now := time.Now()
+	var retryAfter time.Duration
+	for key, policy := range t.keys(email, ip) {
+		failures, err := t.Store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		retryAfter = max(retryAfter, policy.retryAfter(failures, now))
+	}
+	if retryAfter > 0 {
+		return &ThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (t *LoginThrottle) Fail(ctx context.Context, email string, ip string) error {
+	now := time.Now()
+	for key := range t.keys(email, ip) {
+		if _, err := t.Store.Fail(ctx, key, now, t.Window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Succeed clears the failures of the account. The IP counter is left to
+// expire, otherwise an attacker could clear it by logging into an account of
+// their own between guesses.
+func (t *LoginThrottle) Succeed(ctx context.Context, email string) error {
+	return t.Store.Reset(ctx, accountThrottleKey(email))
+}
+
+func (t *LoginThrottle) keys(email string, ip string) map[string]ThrottlePolicy {
+	keys := map[string]ThrottlePolicy{accountThrottleKey(email): t.Account}
+	if ip != "" {
+		keys["ip:"+ip] = t.IP
+	}
+	return keys
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + email
+}
+
+// retryAfter is how long the next attempt has to wait, zero if it may go
+// ahead now.
+func (p ThrottlePolicy) retryAfter(failures LoginFailures, now time.Time) time.Duration {
+	var wait time.Duration
+	switch {
+	case p.LockoutThreshold > 0 && failures.Count >= p.LockoutThreshold:
+		wait = p.LockoutDuration
+	case failures.Count > p.FreeAttempts:
+		wait = p.BaseDelay
+		for i := p.FreeAttempts + 1; i < failures.Count && wait < p.MaxDelay; i++ {
+			wait *= 2
+		}
+		wait = min(wait, p.MaxDelay)
+	default:
+		return 0
+	}
+	return max(failures.LastFailedAt.Add(wait).Sub(now), 0)
+}
@@ -0,0 +1,158 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeLoginAttemptStore struct {
+	failures map[string]LoginFailures
+}
+
+func newFakeLoginAttemptStore() *fakeLoginAttemptStore {
+	return &fakeLoginAttemptStore{failures: make(map[string]LoginFailures)}
+}
+
+func (s *fakeLoginAttemptStore) Get(ctx context.Context, key string) (LoginFailures, error) {
+	return s.failures[key], nil
+}
+
+func (s *fakeLoginAttemptStore) Fail(ctx context.Context, key string, failedAt time.Time, window time.Duration) (LoginFailures, error) {
+	failures := s.failures[key]
+	failures.Count++
+	failures.LastFailedAt = failedAt
+	s.failures[key] = failures
+	return failures, nil
+}
+
+func (s *fakeLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	delete(s.failures, key)
+	return nil
+}
+
+func TestThrottlePolicy_RetryAfter(t *testing.T) {
+	policy := ThrottlePolicy{FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 5 * time.Second, LockoutThreshold: 6, LockoutDuration: time.Minute}
+	now := time.Now()
+
+	cases := []struct {
+		count int
+		want  time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, time.Minute},
+	}
+	for _, c := range cases {
+		if got := policy.retryAfter(LoginFailures{Count: c.count, LastFailedAt: now}, now); got != c.want {
+			t.Errorf("count %d: expected %s, got %s", c.count, c.want, got)
+		}
+	}
+
+	policy.LockoutThreshold = 0
+	if got := policy.retryAfter(LoginFailures{Count: 50, LastFailedAt: now}, now); got != 5*time.Second {
+		t.Errorf("expected the delay to be capped, got %s", got)
+	}
+	if got := policy.retryAfter(LoginFailures{Count: 3, LastFailedAt: now.Add(-time.Minute)}, now); got != 0 {
+		t.Errorf("expected elapsed delays to be over, got %s", got)
+	}
+}
+
+func newThrottledLoginService(compareErr error) (*LoginService, *fakeLoginAttemptStore) {
+	store := newFakeLoginAttemptStore()
+	service := NewLoginService(&loginRepoStub{user: entitiesUser()}, &hasherStub{compareErr: compareErr}, &sessionStoreStub{})
+	service.Throttle = NewLoginThrottle(store)
+	service.Throttle.Account = ThrottlePolicy{FreeAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Hour, LockoutThreshold: 3, LockoutDuration: time.Hour}
+	return service, store
+}
+
+func TestLogin_ThrottlesFailures(t *testing.T) {
+	service, store := newThrottledLoginService(ErrPasswordIncorrect)
+	ctx := context.Background()
+	req := LoginRequest{Email: "islam@gmail.com", Password: "wrong-password", IP: "192.0.2.1"}
+
+	if _, err := service.Login(ctx, req); !errors.Is(err, ErrPasswordIncorrect) {
+		t.Fatalf("expected ErrPasswordIncorrect, got: %v", err)
+	}
+	if _, err := service.Login(ctx, req); !errors.Is(err, ErrPasswordIncorrect) {
+		t.Fatalf("free attempts must not be throttled, got: %v", err)
+	}
+
+	_, err := service.Login(ctx, req)
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("expected ThrottledError, got: %v", err)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Minute {
+		t.Fatalf("unexpected retry after: %s", throttled.RetryAfter)
+	}
+	if store.failures["account:islam@gmail.com"].Count != 2 || store.failures["ip:192.0.2.1"].Count != 2 {
+		t.Fatalf("throttled attempts must not be counted: %+v", store.failures)
+	}
+}
+
+func TestLogin_ThrottlesByIP(t *testing.T) {
+	service, store := newThrottledLoginService(nil)
+	service.Throttle.IP = ThrottlePolicy{LockoutThreshold: 1, LockoutDuration: time.Hour}
+	store.failures["ip:192.0.2.1"] = LoginFailures{Count: 1, LastFailedAt: time.Now()}
+
+	_, err := service.Login(context.Background(), LoginRequest{Email: "islam@gmail.com", Password: "password123", IP: "192.0.2.1"})
+	if !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("expected ErrLoginThrottled for a locked out IP, got: %v", err)
+	}
+}
+
+func TestLogin_SuccessResetsAccount(t *testing.T) {
+	service, store := newThrottledLoginService(nil)
+	store.failures["account:islam@gmail.com"] = LoginFailures{Count: 1, LastFailedAt: time.Now()}
+	store.failures["ip:192.0.2.1"] = LoginFailures{Count: 1, LastFailedAt: time.Now()}
+
+	if _, err := service.Login(context.Background(), LoginRequest{Email: "islam@gmail.com", Password: "password123", IP: "192.0.2.1"}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if _, ok := store.failures["account:islam@gmail.com"]; ok {
+		t.Fatalf("expected the account counter to be reset")
+	}
+	if store.failures["ip:192.0.2.1"].Count != 1 {
+		t.Fatalf("the IP counter must survive a successful login")
+	}
+}
+
+func TestLogin_TwoFactorFailuresAreThrottled(t *testing.T) {
+	login, twoFactor, _ := newTwoFactorLogin()
+	store := newFakeLoginAttemptStore()
+	login.Throttle = NewLoginThrottle(store)
+	ctx := context.Background()
+	user := entitiesUser()
+	secret, _ := enableTwoFactor(t, twoFactor, user.ID)
+	store.failures["account:islam@gmail.com"] = LoginFailures{Count: 1, LastFailedAt: time.Now()}
+
+	resp, err := login.Login(ctx, LoginRequest{Email: user.Email, Password: "secret", IP: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	pending, _ := resp.Pending.Get()
+	if store.failures["account:islam@gmail.com"].Count != 1 {
+		t.Fatalf("the account counter must survive until the second factor passes")
+	}
+
+	_, err = login.CompleteTwoFactor(ctx, CompleteTwoFactorRequest{Challenge: pending.Challenge, Code: "000000", IP: "192.0.2.1"})
+	if !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Fatalf("expected ErrTwoFactorCodeInvalid, got: %v", err)
+	}
+	if store.failures["account:islam@gmail.com"].Count != 2 || store.failures["ip:192.0.2.1"].Count != 1 {
+		t.Fatalf("expected the invalid code to be counted: %+v", store.failures)
+	}
+
+	code, _ := totpCode(secret, totpStep(time.Now()))
+	if _, err := login.CompleteTwoFactor(ctx, CompleteTwoFactorRequest{Challenge: pending.Challenge, Code: code, IP: "192.0.2.1"}); err != nil {
+		t.Fatalf("CompleteTwoFactor failed: %v", err)
+	}
+	if _, ok := store.failures["account:islam@gmail.com"]; ok {
+		t.Fatalf("expected the account counter to be reset by the completed login")
+	}
+}
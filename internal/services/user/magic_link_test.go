@@ -0,0 +1,101 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// magicToken extracts the token from the last path segment of the link in
+// body.
+func magicToken(t *testing.T, body string) string {
+	t.Helper()
+	for _, field := range strings.Fields(body) {
+		if strings.HasPrefix(field, "https://") {
+			return path.Base(field)
+		}
+	}
+	t.Fatalf("no link in message body:\n%s", body)
+	return ""
+}
+
+func newMagicLinkService() (*MagicLinkService, *LoginService, *notifierStub) {
+	repo := newFakeUserRepo(entitiesUser())
+	notifier := &notifierStub{}
+	login := NewLoginService(repo, &hasherStub{}, newFakeSessionStore())
+	service := NewMagicLinkService(repo, newFakeOneTimeTokenRepo(), notifier, login, "https://api.example.com/users/login/magic/")
+	return service, login, notifier
+}
+
+func TestMagicLink_Login(t *testing.T) {
+	service, login, notifier := newMagicLinkService()
+	login.RequireVerifiedEmail = true
+	ctx := context.Background()
+	user := entitiesUser()
+
+	_, err := service.Send(ctx, SendMagicLinkRequest{Email: user.Email})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(notifier.messages) != 1 || !strings.Contains(notifier.messages[0].Body, "https://api.example.com/users/login/magic/") {
+		t.Fatalf("expected a login link, got %+v", notifier.messages)
+	}
+	token := magicToken(t, notifier.messages[0].Body)
+
+	resp, err := service.Login(ctx, MagicLinkLoginRequest{Token: token, IP: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if resp.Session.UserID != user.ID || resp.Session.IP != "203.0.113.7" {
+		t.Fatalf("unexpected session: %+v", resp.Session)
+	}
+
+	_, err = service.Login(ctx, MagicLinkLoginRequest{Token: token})
+	if !errors.Is(err, ErrOneTimeTokenInvalid) {
+		t.Fatalf("link must be single use, got: %v", err)
+	}
+}
+
+func TestMagicLink_Expired(t *testing.T) {
+	service, _, notifier := newMagicLinkService()
+	service.TokenTTL = -time.Second
+	ctx := context.Background()
+
+	_, _ = service.Send(ctx, SendMagicLinkRequest{Email: entitiesUser().Email})
+	_, err := service.Login(ctx, MagicLinkLoginRequest{Token: magicToken(t, notifier.messages[0].Body)})
+	if !errors.Is(err, ErrOneTimeTokenInvalid) {
+		t.Fatalf("expected ErrOneTimeTokenInvalid, got: %v", err)
+	}
+}
+
+func TestMagicLink_UnknownEmail(t *testing.T) {
+	service, _, notifier := newMagicLinkService()
+
+	_, err := service.Send(context.Background(), SendMagicLinkRequest{Email: "nobody@example.com"})
+	if err != nil {
+		t.Fatalf("unknown emails must not be reported, got: %v", err)
+	}
+	if len(notifier.messages) != 0 {
+		t.Fatalf("no message expected for unknown email")
+	}
+}
+
+func TestMagicLink_TwoFactor(t *testing.T) {
+	service, login, notifier := newMagicLinkService()
+	challenges := newFakeChallengeStore()
+	login.TwoFactor = NewTwoFactorService(newFakeTwoFactorRepo(), service.Repo, challenges, "crud-service")
+	enableTwoFactor(t, login.TwoFactor, entitiesUser().ID)
+	ctx := context.Background()
+
+	_, _ = service.Send(ctx, SendMagicLinkRequest{Email: entitiesUser().Email})
+	resp, err := service.Login(ctx, MagicLinkLoginRequest{Token: magicToken(t, notifier.messages[0].Body)})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if resp.Pending.IsAbsent() || resp.Session.ID != "" {
+		t.Fatalf("a second factor must still be required: %+v", resp)
+	}
+}
@@ -0,0 +1,14 @@
+package user
+
+import "context"
+
+// Message is an out-of-band notification for a user, typically an email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
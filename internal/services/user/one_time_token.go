@@ -0,0 +1,83 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"net/url"
+	"time"
+)
+
+const PurposePasswordReset = "password_reset"
+
+type OneTimeTokenRepository interface {
+	Create(ctx context.Context, token entities.OneTimeToken) error
+	// Consume removes and returns the token. It fails with
+	// ErrOneTimeTokenInvalid when there is no such token for the purpose.
+	Consume(ctx context.Context, hash string, purpose string, ent *entities.OneTimeToken) error
+	DeleteByUser(ctx context.Context, userID string, purpose string) error
+}
+
+// issueOneTimeToken stores a new token for the user and returns its secret.
+func issueOneTimeToken(ctx context.Context, repo OneTimeTokenRepository, userID, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := newOpaqueToken("")
+	if err != nil {
+		return "", err
+	}
+
+	timeNow := time.Now().UTC()
+	err = repo.Create(ctx, entities.OneTimeToken{
+		Hash:      hash,
+		UserID:    userID,
+		Purpose:   purpose,
+		CreatedAt: timeNow,
+		ExpiresAt: timeNow.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeOneTimeToken redeems a token and returns the user it was issued
+// for.
+func consumeOneTimeToken(ctx context.Context, repo OneTimeTokenRepository, token, purpose string) (string, error) {
+	ent, err := redeemOneTimeToken(ctx, repo, token, purpose)
+	if err != nil {
+		return "", err
+	}
+	return ent.UserID, nil
+}
+
+// redeemOneTimeToken is consumeOneTimeToken returning the whole token, which
+// can be stored again with Create to undo the redemption.
+func redeemOneTimeToken(ctx context.Context, repo OneTimeTokenRepository, token, purpose string) (entities.OneTimeToken, error) {
+	if token == "" {
+		return entities.OneTimeToken{}, ErrOneTimeTokenInvalid
+	}
+
+	var ent entities.OneTimeToken
+	err := repo.Consume(ctx, hashOpaqueToken(token), purpose, &ent)
+	if err != nil {
+		return entities.OneTimeToken{}, err
+	}
+	if time.Now().After(ent.ExpiresAt) {
+		return entities.OneTimeToken{}, ErrOneTimeTokenInvalid
+	}
+	return ent, nil
+}
+
+// tokenLink appends the token to base as the token query parameter. The
+// bare token is returned when no base URL is configured.
+func tokenLink(base, token string) string {
+	if base == "" {
+		return token
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return token
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -5,4 +5,7 @@ import "context"
 type PasswordHasher interface {
 	Hash(ctx context.Context, plaintext string) (string, error)
 	Compare(ctx context.Context, hash string, plaintext string) error
+	// NeedsRehash reports whether hash was made with another algorithm or
+	// weaker parameters than Hash would use now.
+	NeedsRehash(hash string) bool
 }
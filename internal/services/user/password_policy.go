@@ -0,0 +1,320 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxPasswordBytes is the longest password bcrypt takes into account; the
+// bytes after it would be silently ignored.
+const MaxPasswordBytes = 72
+
+// MaxLongPasswordBytes caps passwords for hashers that read every byte, such
+// as Argon2id or a pepper in front of bcrypt. It only keeps huge passwords
+// from making hashing expensive.
+const MaxLongPasswordBytes = 4096
+
+const (
+	ViolationTooShort        = "too_short"
+	ViolationTooLong         = "too_long"
+	ViolationMissingLower    = "missing_lowercase"
+	ViolationMissingUpper    = "missing_uppercase"
+	ViolationMissingDigit    = "missing_digit"
+	ViolationMissingSymbol   = "missing_symbol"
+	ViolationTooFewClasses   = "too_few_character_classes"
+	ViolationBannedWord      = "contains_banned_word"
+	ViolationPersonalInfo    = "contains_personal_info"
+	ViolationTooWeak         = "too_weak"
+	ViolationInvalidEncoding = "invalid_encoding"
+	ViolationBreached        = "breached"
+)
+
+// personalInfoMinLength keeps short fragments such as "a" of a@b.io from
+// banning half the alphabet.
+const personalInfoMinLength = 3
+
+// commonPasswordWords weaken any password that contains them.
+var commonPasswordWords = []string{
+	"password", "passw0rd", "qwerty", "letmein", "welcome", "admin", "iloveyou",
+	"monkey", "dragon", "football", "baseball", "master", "sunshine", "login",
+	"abc123", "123456", "111111",
+}
+
+type PasswordPolicy struct {
+	// MinLength counts characters.
+	MinLength int
+	// MaxBytes counts bytes and is capped at MaxPasswordBytes, or at
+	// MaxLongPasswordBytes with LongPasswords.
+	MaxBytes int
+	// LongPasswords tells that new passwords are hashed without truncation,
+	// so that passwords longer than MaxPasswordBytes are safe to accept.
+	LongPasswords bool
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// MinClasses is how many of lowercase, uppercase, digits and symbols the
+	// password has to mix.
+	MinClasses int
+	// BannedWords may not appear in the password, ignoring case.
+	BannedWords []string
+	// MinStrength is the lowest PasswordStrength accepted, from 0 to 4.
+	MinStrength int
+	// Breaches, when set, rejects passwords found in breach corpora. It is
+	// only asked about passwords that pass every other rule.
+	Breaches BreachChecker
+}
+
+type BreachChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// DefaultPasswordPolicy is used by services without a policy of their own.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+	MaxBytes:  MaxPasswordBytes,
+}
+
+type PasswordViolation struct {
+	Code    string
+	Message string
+}
+
+// PasswordPolicyError lists every rule a password breaks. It matches
+// ErrPasswordPolicy with errors.Is.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	codes := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		codes[i] = v.Code
+	}
+	return fmt.Sprintf("%s: %s", ErrPasswordPolicy, strings.Join(codes, ", "))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+func passwordPolicyOrDefault(p *PasswordPolicy) *PasswordPolicy {
+	if p == nil {
+		return &DefaultPasswordPolicy
+	}
+	return p
+}
+
+// Check returns a PasswordPolicyError if the password breaks the policy.
+// personal is what is known about the user, such as the username and email,
+// none of which may appear in the password.
+func (p *PasswordPolicy) Check(ctx context.Context, password string, personal ...string) error {
+	var violations []PasswordViolation
+	add := func(code string, format string, args ...any) {
+		violations = append(violations, PasswordViolation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !utf8.ValidString(password) {
+		add(ViolationInvalidEncoding, "password must be valid UTF-8")
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		add(ViolationTooShort, "password must be at least %d characters long", p.MinLength)
+	}
+	limit := MaxPasswordBytes
+	if p.LongPasswords {
+		limit = MaxLongPasswordBytes
+	}
+	maxBytes := p.MaxBytes
+	if maxBytes <= 0 || maxBytes > limit {
+		maxBytes = limit
+	}
+	if len(password) > maxBytes {
+		add(ViolationTooLong, "password must be at most %d bytes long", maxBytes)
+	}
+
+	classes := passwordClasses(password)
+	if p.RequireLower && !classes.lower {
+		add(ViolationMissingLower, "password must contain a lowercase letter")
+	}
+	if p.RequireUpper && !classes.upper {
+		add(ViolationMissingUpper, "password must contain an uppercase letter")
+	}
+	if p.RequireDigit && !classes.digit {
+		add(ViolationMissingDigit, "password must contain a digit")
+	}
+	if p.RequireSymbol && !classes.symbol {
+		add(ViolationMissingSymbol, "password must contain a symbol")
+	}
+	if classes.count() < p.MinClasses {
+		add(ViolationTooFewClasses, "password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses)
+	}
+
+	lower := strings.ToLower(password)
+	for _, word := range p.BannedWords {
+		if word != "" && strings.Contains(lower, strings.ToLower(word)) {
+			add(ViolationBannedWord, "password must not contain %q", word)
+		}
+	}
+	for _, part := range personalInfoParts(personal) {
+		if strings.Contains(lower, part) {
+			add(ViolationPersonalInfo, "password must not contain your username or email")
+			break
+		}
+	}
+
+	if p.MinStrength > 0 {
+		if strength := PasswordStrength(password); strength < p.MinStrength {
+			add(ViolationTooWeak, "password is too easy to guess (strength %d of 4, at least %d required)", strength, p.MinStrength)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	if p.Breaches != nil {
+		breached, err := p.Breaches.Breached(ctx, password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			add(ViolationBreached, "password appears in a known data breach")
+			return &PasswordPolicyError{Violations: violations}
+		}
+	}
+	return nil
+}
+
+// PasswordStrength scores a password from 0 (trivial) to 4 (strong) by an
+// estimate of its entropy. Repeated characters and the third and later
+// characters of runs such as "abcd" or "4321" add nothing, and common
+// passwords are taken out.
+func PasswordStrength(password string) int {
+	lower := strings.ToLower(password)
+	for _, word := range commonPasswordWords {
+		lower = strings.ReplaceAll(lower, word, "")
+	}
+	if lower == "" {
+		return 0
+	}
+
+	effective := 0
+	prev, step := rune(-1), rune(0)
+	for _, r := range lower {
+		d := r - prev
+		switch {
+		case d == 0:
+		case (d == 1 || d == -1) && d == step:
+		default:
+			effective++
+		}
+		prev, step = r, d
+	}
+
+	bits := float64(effective) * math.Log2(float64(passwordClasses(password).pool()))
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 80:
+		return 3
+	default:
+		return 4
+	}
+}
+
+type characterClasses struct {
+	lower, upper, digit, symbol, other bool
+}
+
+func passwordClasses(password string) characterClasses {
+	var c characterClasses
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			c.lower = true
+		case r >= 'A' && r <= 'Z':
+			c.upper = true
+		case r >= '0' && r <= '9':
+			c.digit = true
+		case r < utf8.RuneSelf && (unicode.IsPunct(r) || unicode.IsSymbol(r) || r == ' '):
+			c.symbol = true
+		case unicode.IsLower(r):
+			c.lower, c.other = true, true
+		case unicode.IsUpper(r):
+			c.upper, c.other = true, true
+		default:
+			c.other = true
+		}
+	}
+	return c
+}
+
+func (c characterClasses) count() int {
+	n := 0
+	for _, ok := range []bool{c.lower, c.upper, c.digit, c.symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// pool is the size of the alphabet the password appears to be drawn from.
+func (c characterClasses) pool() int {
+	n := 0
+	if c.lower {
+		n += 26
+	}
+	if c.upper {
+		n += 26
+	}
+	if c.digit {
+		n += 10
+	}
+	if c.symbol {
+		n += 33
+	}
+	if c.other {
+		n += 100
+	}
+	return max(n, 2)
+}
+
+// personalInfoParts splits usernames and emails into the lowercase words a
+// password is checked for, e.g. "john.doe@example.com" into the whole
+// address, "john.doe", "john", "doe" and "example".
+func personalInfoParts(personal []string) []string {
+	var parts []string
+	addPart := func(part string) {
+		if utf8.RuneCountInString(part) >= personalInfoMinLength {
+			parts = append(parts, part)
+		}
+	}
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		addPart(value)
+		local, domain, isEmail := strings.Cut(value, "@")
+		if isEmail {
+			addPart(local)
+			if label, _, ok := strings.Cut(domain, "."); ok {
+				addPart(label)
+			}
+		}
+		for _, word := range strings.FieldsFunc(local, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			addPart(word)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,141 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/samber/mo"
+)
+
+func violationCodes(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) || !errors.Is(err, ErrPasswordPolicy) {
+		t.Fatalf("expected a PasswordPolicyError, got: %v", err)
+	}
+	codes := make([]string, len(policyErr.Violations))
+	for i, v := range policyErr.Violations {
+		codes[i] = v.Code
+	}
+	return codes
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireSymbol: true,
+		MinClasses:    3,
+		BannedWords:   []string{"Crud"},
+	}
+
+	cases := []struct {
+		password string
+		want     []string
+	}{
+		{"Correct-Horse-7", nil},
+		{"short", []string{ViolationTooShort, ViolationMissingUpper, ViolationMissingSymbol, ViolationTooFewClasses}},
+		{"MyCRUDaccount!", []string{ViolationBannedWord}},
+		{strings.Repeat("Aa1!", 19), []string{ViolationTooLong}},
+		{"Islam-Rocks-1", []string{ViolationPersonalInfo}},
+		{"Mail-At-Gmail-1", []string{ViolationPersonalInfo}},
+	}
+	for _, c := range cases {
+		got := violationCodes(t, policy.Check(context.Background(), c.password, "islam", "islam.k@gmail.com"))
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("%q: expected %v, got %v", c.password, c.want, got)
+		}
+	}
+}
+
+func TestPasswordPolicy_MaxBytesCapped(t *testing.T) {
+	policy := &PasswordPolicy{MaxBytes: 200}
+	if codes := violationCodes(t, policy.Check(context.Background(), strings.Repeat("ä", 40))); len(codes) != 1 || codes[0] != ViolationTooLong {
+		t.Fatalf("80 bytes must exceed the bcrypt limit, got %v", codes)
+	}
+}
+
+func TestPasswordPolicy_LongPasswords(t *testing.T) {
+	ctx := context.Background()
+	policy := &PasswordPolicy{LongPasswords: true}
+	if err := policy.Check(ctx, strings.Repeat("ä", 40)); err != nil {
+		t.Fatalf("80 bytes must be accepted for long passwords, got %v", err)
+	}
+	if codes := violationCodes(t, policy.Check(ctx, strings.Repeat("x", MaxLongPasswordBytes+1))); len(codes) != 1 || codes[0] != ViolationTooLong {
+		t.Fatalf("expected long passwords to be capped at %d bytes, got %v", MaxLongPasswordBytes, codes)
+	}
+
+	policy.MaxBytes = 100
+	if codes := violationCodes(t, policy.Check(ctx, strings.Repeat("x", 101))); len(codes) != 1 || codes[0] != ViolationTooLong {
+		t.Fatalf("expected the configured limit to apply, got %v", codes)
+	}
+}
+
+func TestPasswordStrength(t *testing.T) {
+	cases := map[string]int{
+		"aaaaaaaaaaaa":                 0,
+		"password123":                  0,
+		"abcdefghijkl":                 0,
+		"Test1234!":                    2,
+		"Tr0ub4dor&3":                  3,
+		"correct horse battery staple": 4,
+	}
+	for password, want := range cases {
+		if got := PasswordStrength(password); got != want {
+			t.Errorf("%q: expected strength %d, got %d", password, want, got)
+		}
+	}
+}
+
+func TestRegister_PasswordPolicy(t *testing.T) {
+	service := NewRegisterService(newFakeUserRepo(), &hasherStub{}, &idGenStub{})
+	service.PasswordPolicy = &PasswordPolicy{MinLength: 8, MinStrength: 3}
+
+	_, err := service.Register(context.Background(), RegisterRequest{Username: "demo", Email: "demo@example.com", Password: "demo2024"})
+	if codes := violationCodes(t, err); strings.Join(codes, ",") != ViolationPersonalInfo+","+ViolationTooWeak {
+		t.Fatalf("unexpected violations: %v", codes)
+	}
+}
+
+func TestUpdate_PasswordPolicyChecksCurrentUser(t *testing.T) {
+	repo := &updateRepoStub{user: entitiesUser()}
+	service := NewUpdateService(repo, &hasherStub{}, newFakeSessionStore())
+
+	_, err := service.Update(context.Background(), UpdateRequest{ID: "1", Password: mo.Some("islam-2024-pw")})
+	if codes := violationCodes(t, err); len(codes) != 1 || codes[0] != ViolationPersonalInfo {
+		t.Fatalf("unexpected violations: %v", codes)
+	}
+}
+
+type breachCheckerStub struct {
+	breached map[string]bool
+	calls    int
+}
+
+func (b *breachCheckerStub) Breached(ctx context.Context, password string) (bool, error) {
+	b.calls++
+	return b.breached[password], nil
+}
+
+func TestPasswordPolicy_Breaches(t *testing.T) {
+	breaches := &breachCheckerStub{breached: map[string]bool{"Summer-2019!": true}}
+	policy := &PasswordPolicy{MinLength: 8, Breaches: breaches}
+	ctx := context.Background()
+
+	if codes := violationCodes(t, policy.Check(ctx, "Summer-2019!")); len(codes) != 1 || codes[0] != ViolationBreached {
+		t.Fatalf("expected a breached violation, got %v", codes)
+	}
+	if err := policy.Check(ctx, "Winter-2031?"); err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	breaches.calls = 0
+	_ = policy.Check(ctx, "short")
+	if breaches.calls != 0 {
+		t.Fatalf("passwords failing other rules must not be looked up")
+	}
+}
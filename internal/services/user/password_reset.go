@@ -0,0 +1,168 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+const defaultPasswordResetTTL = time.Hour
+
+type ForgotPasswordRequest struct {
+	Email string
+}
+
+type ForgotPasswordResponse struct{}
+
+type ResetPasswordRequest struct {
+	Token    string
+	Password string
+}
+
+type ResetPasswordResponse struct {
+	Success bool
+}
+
+type PasswordResetRepository interface {
+	FindOne(context.Context, entities.UserFilterAttrs, *entities.User) error
+	Update(context.Context, entities.UserUpdateAttrs, entities.UserFilterAttrs, *entities.User) error
+}
+
+type PasswordResetService struct {
+	Repo         PasswordResetRepository
+	Tokens       OneTimeTokenRepository
+	Hasher       PasswordHasher
+	SessionStore SessionStore
+	Notifier     Notifier
+	// PasswordPolicy checks new passwords, DefaultPasswordPolicy when nil.
+	PasswordPolicy *PasswordPolicy
+	// ResetURL is the page of the client that completes the reset. The token
+	// is added to it as the token query parameter.
+	ResetURL string
+	TokenTTL time.Duration
+	// RefreshTokens, when set, has every refresh token of the user revoked
+	// on reset.
+	RefreshTokens RefreshTokenStore
+}
+
+func NewPasswordResetService(repo PasswordResetRepository, tokens OneTimeTokenRepository, hasher PasswordHasher, sessionStore SessionStore, notifier Notifier, resetURL string) *PasswordResetService {
+	return &PasswordResetService{
+		Repo:         repo,
+		Tokens:       tokens,
+		Hasher:       hasher,
+		SessionStore: sessionStore,
+		Notifier:     notifier,
+		ResetURL:     resetURL,
+		TokenTTL:     defaultPasswordResetTTL,
+	}
+}
+
+// Forgot mails a reset link if an account with the email exists. It succeeds
+// either way so that callers cannot probe for registered emails. It takes
+// longer for registered ones though, so callers should not wait for it
+// before answering.
+func (s *PasswordResetService) Forgot(ctx context.Context, req ForgotPasswordRequest) (ForgotPasswordResponse, error) {
+	email := NormalizeEmail(req.Email)
+	if email == "" {
+		return ForgotPasswordResponse{}, ErrEmailRequired
+	}
+	if !ValidateEmail(email) {
+		return ForgotPasswordResponse{}, ErrEmailIncorrect
+	}
+
+	var user entities.User
+	err := s.Repo.FindOne(ctx, entities.UserFilterAttrs{Email: mo.Some(email)}, &user)
+	if errors.Is(err, ErrUserNotFound) {
+		return ForgotPasswordResponse{}, nil
+	}
+	if err != nil {
+		return ForgotPasswordResponse{}, err
+	}
+
+	token, err := issueOneTimeToken(ctx, s.Tokens, user.ID, PurposePasswordReset, s.TokenTTL)
+	if err != nil {
+		return ForgotPasswordResponse{}, err
+	}
+
+	err = s.Notifier.Notify(ctx, Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Use the link below to set a new password. It expires in %s.\n\n%s\n\n"+
+			"If you did not ask for a reset, ignore this message.", s.TokenTTL, tokenLink(s.ResetURL, token)),
+	})
+	if err != nil {
+		return ForgotPasswordResponse{}, err
+	}
+	return ForgotPasswordResponse{}, nil
+}
+
+// Reset sets a new password with a token from Forgot and signs the user out
+// everywhere.
+func (s *PasswordResetService) Reset(ctx context.Context, req ResetPasswordRequest) (ResetPasswordResponse, error) {
+	if req.Password == "" {
+		return ResetPasswordResponse{}, ErrPasswordRequired
+	}
+	policy := passwordPolicyOrDefault(s.PasswordPolicy)
+	if err := policy.Check(ctx, req.Password); err != nil {
+		return ResetPasswordResponse{}, err
+	}
+
+	token, err := redeemOneTimeToken(ctx, s.Tokens, req.Token, PurposePasswordReset)
+	if err != nil {
+		return ResetPasswordResponse{}, err
+	}
+	userID := token.UserID
+
+	var current entities.User
+	err = s.Repo.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(userID)}, &current)
+	if err != nil {
+		return ResetPasswordResponse{}, err
+	}
+	if err := policy.Check(ctx, req.Password, current.Username, current.Email); err != nil {
+		// Put the token back so the link still works for a better password.
+		if restoreErr := s.Tokens.Create(ctx, token); restoreErr != nil {
+			return ResetPasswordResponse{}, restoreErr
+		}
+		return ResetPasswordResponse{}, err
+	}
+
+	hash, err := s.Hasher.Hash(ctx, req.Password)
+	if err != nil {
+		// The hasher may only be overloaded; let the link be used again.
+		if restoreErr := s.Tokens.Create(ctx, token); restoreErr != nil {
+			return ResetPasswordResponse{}, restoreErr
+		}
+		return ResetPasswordResponse{}, err
+	}
+
+	var user entities.User
+	err = s.Repo.Update(ctx, entities.UserUpdateAttrs{
+		HashedPassword: mo.Some(hash),
+	}, entities.UserFilterAttrs{
+		ID: mo.Some(userID),
+	}, &user)
+	if err != nil {
+		return ResetPasswordResponse{}, err
+	}
+
+	err = s.Tokens.DeleteByUser(ctx, userID, PurposePasswordReset)
+	if err != nil {
+		return ResetPasswordResponse{}, err
+	}
+	err = revokeOtherSessions(ctx, s.SessionStore, userID, "")
+	if err != nil {
+		return ResetPasswordResponse{}, err
+	}
+	if s.RefreshTokens != nil {
+		err = s.RefreshTokens.DeleteByUser(ctx, userID)
+		if err != nil {
+			return ResetPasswordResponse{}, err
+		}
+	}
+
+	return ResetPasswordResponse{Success: true}, nil
+}
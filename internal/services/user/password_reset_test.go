@@ -0,0 +1,182 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeOneTimeTokenRepo struct {
+	tokens map[string]entities.OneTimeToken
+}
+
+func newFakeOneTimeTokenRepo() *fakeOneTimeTokenRepo {
+	return &fakeOneTimeTokenRepo{tokens: make(map[string]entities.OneTimeToken)}
+}
+
+func (r *fakeOneTimeTokenRepo) Create(ctx context.Context, token entities.OneTimeToken) error {
+	r.tokens[token.Hash] = token
+	return nil
+}
+
+func (r *fakeOneTimeTokenRepo) Consume(ctx context.Context, hash string, purpose string, ent *entities.OneTimeToken) error {
+	token, ok := r.tokens[hash]
+	if !ok || token.Purpose != purpose {
+		return ErrOneTimeTokenInvalid
+	}
+	delete(r.tokens, hash)
+	*ent = token
+	return nil
+}
+
+func (r *fakeOneTimeTokenRepo) DeleteByUser(ctx context.Context, userID string, purpose string) error {
+	for hash, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(r.tokens, hash)
+		}
+	}
+	return nil
+}
+
+type notifierStub struct {
+	messages []Message
+}
+
+func (n *notifierStub) Notify(ctx context.Context, msg Message) error {
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+// linkToken extracts the token query parameter from the first link in body.
+func linkToken(t *testing.T, body string) string {
+	t.Helper()
+	for _, field := range strings.Fields(body) {
+		if u, err := url.Parse(field); err == nil && u.Query().Has("token") {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no link in message body:\n%s", body)
+	return ""
+}
+
+type resetRepoStub struct {
+	loginRepoStub
+	updateRepoStub
+}
+
+func (r *resetRepoStub) FindOne(ctx context.Context, attrs entities.UserFilterAttrs, ent *entities.User) error {
+	return r.loginRepoStub.FindOne(ctx, attrs, ent)
+}
+
+func (r *resetRepoStub) Update(ctx context.Context, attrs entities.UserUpdateAttrs, filterAttrs entities.UserFilterAttrs, ent *entities.User) error {
+	return r.updateRepoStub.Update(ctx, attrs, filterAttrs, ent)
+}
+
+func newResetService() (*PasswordResetService, *resetRepoStub, *fakeSessionStore, *notifierStub) {
+	user := entitiesUser()
+	repo := &resetRepoStub{
+		loginRepoStub:  loginRepoStub{user: user},
+		updateRepoStub: updateRepoStub{user: user},
+	}
+	store := newFakeSessionStore(Session{ID: "s1", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	notifier := &notifierStub{}
+	service := NewPasswordResetService(repo, newFakeOneTimeTokenRepo(), &hasherStub{}, store, notifier, "https://app.example.com/reset")
+	return service, repo, store, notifier
+}
+
+func TestPasswordReset_Flow(t *testing.T) {
+	service, repo, store, notifier := newResetService()
+	ctx := context.Background()
+	user := entitiesUser()
+
+	_, err := service.Forgot(ctx, ForgotPasswordRequest{Email: " " + strings.ToUpper(user.Email)})
+	if err != nil {
+		t.Fatalf("Forgot failed: %v", err)
+	}
+	if len(notifier.messages) != 1 || notifier.messages[0].To != user.Email {
+		t.Fatalf("expected one message to %s, got %+v", user.Email, notifier.messages)
+	}
+	token := linkToken(t, notifier.messages[0].Body)
+
+	_, err = service.Reset(ctx, ResetPasswordRequest{Token: token, Password: "short"})
+	if !errors.Is(err, ErrPasswordPolicy) {
+		t.Fatalf("expected ErrPasswordPolicy, got: %v", err)
+	}
+
+	_, err = service.Reset(ctx, ResetPasswordRequest{Token: token, Password: "new-password"})
+	if err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if repo.updateRepoStub.user.HashedPassword != "hashed" {
+		t.Fatalf("password must be re-hashed, got %q", repo.updateRepoStub.user.HashedPassword)
+	}
+	if len(store.sessions) != 0 {
+		t.Fatalf("sessions must be revoked after reset, %d left", len(store.sessions))
+	}
+
+	_, err = service.Reset(ctx, ResetPasswordRequest{Token: token, Password: "new-password"})
+	if !errors.Is(err, ErrOneTimeTokenInvalid) {
+		t.Fatalf("token must be single use, got: %v", err)
+	}
+}
+
+func TestPasswordReset_HasherOverloaded(t *testing.T) {
+	service, _, _, _ := newResetService()
+	tokens := service.Tokens.(*fakeOneTimeTokenRepo)
+	hasher := service.Hasher.(*hasherStub)
+	hasher.hashErr = ErrHashingOverloaded
+	ctx := context.Background()
+
+	token, err := issueOneTimeToken(ctx, tokens, "1", PurposePasswordReset, time.Hour)
+	if err != nil {
+		t.Fatalf("issueOneTimeToken failed: %v", err)
+	}
+	_, err = service.Reset(ctx, ResetPasswordRequest{Token: token, Password: "new-password"})
+	if !errors.Is(err, ErrHashingOverloaded) {
+		t.Fatalf("expected ErrHashingOverloaded, got: %v", err)
+	}
+
+	hasher.hashErr = nil
+	_, err = service.Reset(ctx, ResetPasswordRequest{Token: token, Password: "new-password"})
+	if err != nil {
+		t.Fatalf("token must survive an overloaded hasher, got: %v", err)
+	}
+}
+
+func TestPasswordReset_UnknownEmail(t *testing.T) {
+	service, repo, _, notifier := newResetService()
+	repo.loginRepoStub.err = ErrUserNotFound
+
+	_, err := service.Forgot(context.Background(), ForgotPasswordRequest{Email: "nobody@example.com"})
+	if err != nil {
+		t.Fatalf("unknown emails must not be reported, got: %v", err)
+	}
+	if len(notifier.messages) != 0 {
+		t.Fatalf("no message expected for unknown email")
+	}
+}
+
+func TestPasswordReset_ExpiredToken(t *testing.T) {
+	service, _, _, _ := newResetService()
+	tokens := service.Tokens.(*fakeOneTimeTokenRepo)
+	ctx := context.Background()
+
+	token, err := issueOneTimeToken(ctx, tokens, "1", PurposePasswordReset, -time.Minute)
+	if err != nil {
+		t.Fatalf("issueOneTimeToken failed: %v", err)
+	}
+	_, err = service.Reset(ctx, ResetPasswordRequest{Token: token, Password: "new-password"})
+	if !errors.Is(err, ErrOneTimeTokenInvalid) {
+		t.Fatalf("expected ErrOneTimeTokenInvalid, got: %v", err)
+	}
+
+	token, _ = issueOneTimeToken(ctx, tokens, "1", "other", time.Hour)
+	_, err = service.Reset(ctx, ResetPasswordRequest{Token: token, Password: "new-password"})
+	if !errors.Is(err, ErrOneTimeTokenInvalid) {
+		t.Fatalf("tokens for other purposes must be rejected, got: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+// PersonalTokenPrefix marks personal access tokens so that they can be told
+// apart from session IDs and JWTs without a lookup.
+const PersonalTokenPrefix = "pat_"
+
+const (
+	ScopeProfileWrite  = "profile:write"
+	ScopeSessionsRead  = "sessions:read"
+	ScopeSessionsWrite = "sessions:write"
+)
+
+var PersonalTokenScopes = []string{ScopeProfileWrite, ScopeSessionsRead, ScopeSessionsWrite}
+
+// lastUsedInterval throttles last-used writes for busy tokens.
+const lastUsedInterval = time.Minute
+
+type PersonalTokenRepository interface {
+	Create(context.Context, entities.PersonalAccessTokenAttrs, *entities.PersonalAccessToken) error
+	Find(context.Context, entities.PersonalAccessTokenFilterAttrs) ([]entities.PersonalAccessToken, error)
+	FindOne(context.Context, entities.PersonalAccessTokenFilterAttrs, *entities.PersonalAccessToken) error
+	Delete(context.Context, entities.PersonalAccessTokenFilterAttrs) error
+	TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
+
+type CreatePersonalTokenRequest struct {
+	UserID    string
+	Name      string
+	Scopes    []string
+	ExpiresAt mo.Option[time.Time]
+}
+
+type CreatePersonalTokenResponse struct {
+	Token entities.PersonalAccessToken
+	// Secret is the bearer value. It is not stored and cannot be shown again.
+	Secret string
+}
+
+type ListPersonalTokensRequest struct {
+	UserID string
+}
+
+type ListPersonalTokensResponse struct {
+	Tokens []entities.PersonalAccessToken
+}
+
+type RevokePersonalTokenRequest struct {
+	UserID  string
+	TokenID string
+}
+
+type RevokePersonalTokenResponse struct {
+	Success bool
+}
+
+type AuthenticatePersonalTokenRequest struct {
+	Secret string
+}
+
+type AuthenticatePersonalTokenResponse struct {
+	Token entities.PersonalAccessToken
+}
+
+type PersonalTokenService struct {
+	Repo  PersonalTokenRepository
+	IdGen IDGen
+}
+
+func NewPersonalTokenService(repo PersonalTokenRepository, idGen IDGen) *PersonalTokenService {
+	return &PersonalTokenService{
+		Repo:  repo,
+		IdGen: idGen,
+	}
+}
+
+func (s *PersonalTokenService) Create(ctx context.Context, req CreatePersonalTokenRequest) (CreatePersonalTokenResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return CreatePersonalTokenResponse{}, ErrTokenNameRequired
+	}
+
+	scopes := []string{}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(PersonalTokenScopes, scope) {
+			return CreatePersonalTokenResponse{}, fmt.Errorf("%w: %q", ErrUnknownScope, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if expiresAt, ok := req.ExpiresAt.Get(); ok && !expiresAt.After(time.Now()) {
+		return CreatePersonalTokenResponse{}, ErrTokenExpiryInPast
+	}
+
+	id, err := s.IdGen.NewID()
+	if err != nil {
+		return CreatePersonalTokenResponse{}, err
+	}
+
+	secret, hash, err := newOpaqueToken(PersonalTokenPrefix)
+	if err != nil {
+		return CreatePersonalTokenResponse{}, err
+	}
+
+	var token entities.PersonalAccessToken
+	err = s.Repo.Create(ctx, entities.PersonalAccessTokenAttrs{
+		ID:        id,
+		UserID:    req.UserID,
+		Name:      name,
+		TokenHash: hash,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, &token)
+	if err != nil {
+		return CreatePersonalTokenResponse{}, err
+	}
+
+	return CreatePersonalTokenResponse{Token: token, Secret: secret}, nil
+}
+
+func (s *PersonalTokenService) List(ctx context.Context, req ListPersonalTokensRequest) (ListPersonalTokensResponse, error) {
+	tokens, err := s.Repo.Find(ctx, entities.PersonalAccessTokenFilterAttrs{
+		UserID: mo.Some(req.UserID),
+	})
+	if err != nil {
+		return ListPersonalTokensResponse{}, err
+	}
+	return ListPersonalTokensResponse{Tokens: tokens}, nil
+}
+
+func (s *PersonalTokenService) Revoke(ctx context.Context, req RevokePersonalTokenRequest) (RevokePersonalTokenResponse, error) {
+	err := s.Repo.Delete(ctx, entities.PersonalAccessTokenFilterAttrs{
+		ID:     mo.Some(req.TokenID),
+		UserID: mo.Some(req.UserID),
+	})
+	if err != nil {
+		return RevokePersonalTokenResponse{Success: false}, err
+	}
+	return RevokePersonalTokenResponse{Success: true}, nil
+}
+
+func (s *PersonalTokenService) Authenticate(ctx context.Context, req AuthenticatePersonalTokenRequest) (AuthenticatePersonalTokenResponse, error) {
+	if !strings.HasPrefix(req.Secret, PersonalTokenPrefix) {
+		return AuthenticatePersonalTokenResponse{}, ErrPersonalTokenInvalid
+	}
+
+	var token entities.PersonalAccessToken
+	err := s.Repo.FindOne(ctx, entities.PersonalAccessTokenFilterAttrs{
+		TokenHash: mo.Some(hashOpaqueToken(req.Secret)),
+	}, &token)
+	if errors.Is(err, ErrPersonalTokenNotFound) {
+		return AuthenticatePersonalTokenResponse{}, ErrPersonalTokenInvalid
+	}
+	if err != nil {
+		return AuthenticatePersonalTokenResponse{}, err
+	}
+
+	timeNow := time.Now().UTC()
+	if expiresAt, ok := token.ExpiresAt.Get(); ok && timeNow.After(expiresAt) {
+		return AuthenticatePersonalTokenResponse{}, ErrPersonalTokenInvalid
+	}
+
+	lastUsedAt, ok := token.LastUsedAt.Get()
+	if !ok || timeNow.Sub(lastUsedAt) >= lastUsedInterval {
+		err = s.Repo.TouchLastUsed(ctx, token.ID, timeNow)
+		if err != nil {
+			return AuthenticatePersonalTokenResponse{}, err
+		}
+		token.LastUsedAt = mo.Some(timeNow)
+	}
+
+	return AuthenticatePersonalTokenResponse{Token: token}, nil
+}
@@ -0,0 +1,183 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type fakePersonalTokenRepo struct {
+	tokens  map[string]entities.PersonalAccessToken
+	touches int
+}
+
+func newFakePersonalTokenRepo() *fakePersonalTokenRepo {
+	return &fakePersonalTokenRepo{tokens: make(map[string]entities.PersonalAccessToken)}
+}
+
+func (r *fakePersonalTokenRepo) Create(ctx context.Context, attrs entities.PersonalAccessTokenAttrs, ent *entities.PersonalAccessToken) error {
+	*ent = entities.PersonalAccessToken{
+		ID:        attrs.ID,
+		UserID:    attrs.UserID,
+		Name:      attrs.Name,
+		TokenHash: attrs.TokenHash,
+		Scopes:    attrs.Scopes,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: attrs.ExpiresAt,
+	}
+	r.tokens[attrs.ID] = *ent
+	return nil
+}
+
+func (r *fakePersonalTokenRepo) matches(token entities.PersonalAccessToken, filter entities.PersonalAccessTokenFilterAttrs) bool {
+	if v, ok := filter.ID.Get(); ok && token.ID != v {
+		return false
+	}
+	if v, ok := filter.UserID.Get(); ok && token.UserID != v {
+		return false
+	}
+	if v, ok := filter.TokenHash.Get(); ok && token.TokenHash != v {
+		return false
+	}
+	return true
+}
+
+func (r *fakePersonalTokenRepo) Find(ctx context.Context, filter entities.PersonalAccessTokenFilterAttrs) ([]entities.PersonalAccessToken, error) {
+	tokens := []entities.PersonalAccessToken{}
+	for _, token := range r.tokens {
+		if r.matches(token, filter) {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *fakePersonalTokenRepo) FindOne(ctx context.Context, filter entities.PersonalAccessTokenFilterAttrs, ent *entities.PersonalAccessToken) error {
+	for _, token := range r.tokens {
+		if r.matches(token, filter) {
+			*ent = token
+			return nil
+		}
+	}
+	return ErrPersonalTokenNotFound
+}
+
+func (r *fakePersonalTokenRepo) Delete(ctx context.Context, filter entities.PersonalAccessTokenFilterAttrs) error {
+	for id, token := range r.tokens {
+		if r.matches(token, filter) {
+			delete(r.tokens, id)
+			return nil
+		}
+	}
+	return ErrPersonalTokenNotFound
+}
+
+func (r *fakePersonalTokenRepo) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	token := r.tokens[id]
+	token.LastUsedAt = mo.Some(usedAt)
+	r.tokens[id] = token
+	r.touches++
+	return nil
+}
+
+func TestPersonalTokenService_CreateAndAuthenticate(t *testing.T) {
+	repo := newFakePersonalTokenRepo()
+	service := NewPersonalTokenService(repo, &idGenStub{})
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, CreatePersonalTokenRequest{
+		UserID: "1",
+		Name:   "ci",
+		Scopes: []string{ScopeSessionsRead, ScopeSessionsRead},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(created.Secret, PersonalTokenPrefix) {
+		t.Fatalf("secret must carry the %q prefix: %q", PersonalTokenPrefix, created.Secret)
+	}
+	if stored := repo.tokens[created.Token.ID]; stored.TokenHash == created.Secret || stored.TokenHash != hashOpaqueToken(created.Secret) {
+		t.Fatalf("only the token hash must be stored")
+	}
+	if len(created.Token.Scopes) != 1 {
+		t.Fatalf("duplicate scopes must be collapsed, got %v", created.Token.Scopes)
+	}
+
+	authenticated, err := service.Authenticate(ctx, AuthenticatePersonalTokenRequest{Secret: created.Secret})
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if authenticated.Token.UserID != "1" || authenticated.Token.LastUsedAt.IsAbsent() {
+		t.Fatalf("unexpected token: %+v", authenticated.Token)
+	}
+
+	_, err = service.Authenticate(ctx, AuthenticatePersonalTokenRequest{Secret: created.Secret})
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if repo.touches != 1 {
+		t.Fatalf("last-used time should be written once per interval, got %d writes", repo.touches)
+	}
+
+	_, err = service.Authenticate(ctx, AuthenticatePersonalTokenRequest{Secret: created.Secret + "x"})
+	if !errors.Is(err, ErrPersonalTokenInvalid) {
+		t.Fatalf("expected ErrPersonalTokenInvalid, got: %v", err)
+	}
+}
+
+func TestPersonalTokenService_Validation(t *testing.T) {
+	service := NewPersonalTokenService(newFakePersonalTokenRepo(), &idGenStub{})
+	ctx := context.Background()
+
+	_, err := service.Create(ctx, CreatePersonalTokenRequest{UserID: "1", Name: "  "})
+	if !errors.Is(err, ErrTokenNameRequired) {
+		t.Fatalf("expected ErrTokenNameRequired, got: %v", err)
+	}
+
+	_, err = service.Create(ctx, CreatePersonalTokenRequest{UserID: "1", Name: "ci", Scopes: []string{"admin"}})
+	if !errors.Is(err, ErrUnknownScope) {
+		t.Fatalf("expected ErrUnknownScope, got: %v", err)
+	}
+
+	_, err = service.Create(ctx, CreatePersonalTokenRequest{UserID: "1", Name: "ci", ExpiresAt: mo.Some(time.Now().Add(-time.Hour))})
+	if !errors.Is(err, ErrTokenExpiryInPast) {
+		t.Fatalf("expected ErrTokenExpiryInPast, got: %v", err)
+	}
+}
+
+func TestPersonalTokenService_ExpiredAndRevoked(t *testing.T) {
+	repo := newFakePersonalTokenRepo()
+	service := NewPersonalTokenService(repo, &idGenStub{})
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, CreatePersonalTokenRequest{UserID: "1", Name: "ci", ExpiresAt: mo.Some(time.Now().Add(time.Hour))})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	stored := repo.tokens[created.Token.ID]
+	stored.ExpiresAt = mo.Some(time.Now().Add(-time.Minute))
+	repo.tokens[created.Token.ID] = stored
+	_, err = service.Authenticate(ctx, AuthenticatePersonalTokenRequest{Secret: created.Secret})
+	if !errors.Is(err, ErrPersonalTokenInvalid) {
+		t.Fatalf("expected ErrPersonalTokenInvalid for expired token, got: %v", err)
+	}
+
+	_, err = service.Revoke(ctx, RevokePersonalTokenRequest{UserID: "2", TokenID: created.Token.ID})
+	if !errors.Is(err, ErrPersonalTokenNotFound) {
+		t.Fatalf("tokens of other users must not be revocable, got: %v", err)
+	}
+	_, err = service.Revoke(ctx, RevokePersonalTokenRequest{UserID: "1", TokenID: created.Token.ID})
+	if err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	listed, _ := service.List(ctx, ListPersonalTokensRequest{UserID: "1"})
+	if len(listed.Tokens) != 0 {
+		t.Fatalf("expected no tokens after revoke, got %d", len(listed.Tokens))
+	}
+}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/samber/mo"
@@ -16,7 +17,13 @@ type RegisterRequest struct {
 }
 
 type RegisterResponse struct {
+	// User is the new account. It is empty when ConcealExistingEmail hid a
+	// taken email.
 	User entities.User
+	// Concealed is set in every response of a service with
+	// ConcealExistingEmail. Callers have to answer the same way whether or
+	// not an account was created.
+	Concealed bool
 }
 
 type RegisterRepository interface {
@@ -28,6 +35,16 @@ type RegisterService struct {
 	Repo   RegisterRepository
 	Hasher PasswordHasher
 	IdGen  IDGen
+	// PasswordPolicy checks new passwords, DefaultPasswordPolicy when nil.
+	PasswordPolicy *PasswordPolicy
+	// Verification, when set, mails new users a link to confirm their email.
+	Verification *EmailVerificationService
+	// ConcealExistingEmail makes a registration with a taken email look
+	// like a successful one and mails the owner of the address instead, so
+	// that registration does not tell who has an account. Taken usernames
+	// are still reported. Notifier is required with it.
+	ConcealExistingEmail bool
+	Notifier             Notifier
 }
 
 func NewRegisterService(repo RegisterRepository, hasher PasswordHasher, idGen IDGen) *RegisterService {
@@ -59,26 +76,32 @@ func (s *RegisterService) Register(ctx context.Context, req RegisterRequest) (Re
 		return RegisterResponse{}, ErrEmailIncorrect
 	}
 
-	if !ValidatePassword(password) {
-		return RegisterResponse{}, ErrPasswordIncorrect
+	if err := passwordPolicyOrDefault(s.PasswordPolicy).Check(ctx, password, username, email); err != nil {
+		return RegisterResponse{}, err
 	}
 
+	// The username is checked first: reporting a taken username only for
+	// new emails would give the concealed ones away.
 	err := s.Repo.FindOne(ctx, entities.UserFilterAttrs{
-		Email: mo.Some(email),
+		Username: mo.Some(username),
 	}, &entities.User{})
 	if err != ErrUserNotFound {
 		if err == nil {
-			return RegisterResponse{}, ErrEmailTaken
+			return RegisterResponse{}, ErrUsernameTaken
 		}
 		return RegisterResponse{}, err
 	}
 
+	var existing entities.User
 	err = s.Repo.FindOne(ctx, entities.UserFilterAttrs{
-		Username: mo.Some(username),
-	}, &entities.User{})
+		Email: mo.Some(email),
+	}, &existing)
 	if err != ErrUserNotFound {
 		if err == nil {
-			return RegisterResponse{}, ErrUsernameTaken
+			if s.ConcealExistingEmail {
+				return s.concealTakenEmail(ctx, existing, password)
+			}
+			return RegisterResponse{}, ErrEmailTaken
 		}
 		return RegisterResponse{}, err
 	}
@@ -101,9 +124,44 @@ func (s *RegisterService) Register(ctx context.Context, req RegisterRequest) (Re
 	}
 	var user entities.User
 	err = s.Repo.Create(ctx, attrs, &user)
+	if errors.Is(err, ErrEmailTaken) && s.ConcealExistingEmail {
+		// Lost a race with another registration of the same email.
+		s.notifyTakenEmail(ctx, email)
+		return RegisterResponse{Concealed: true}, nil
+	}
 	if err != nil {
 		return RegisterResponse{}, err
 	}
 
-	return RegisterResponse{User: user}, nil
+	if s.Verification != nil {
+		// The account exists at this point; a lost email can be resent.
+		_ = s.Verification.Send(ctx, user)
+	}
+
+	return RegisterResponse{User: user, Concealed: s.ConcealExistingEmail}, nil
+}
+
+// concealTakenEmail does the work of a registration, hashing included, so
+// that a taken email takes as long as a new one, and tells the owner about
+// the attempt.
+func (s *RegisterService) concealTakenEmail(ctx context.Context, existing entities.User, password string) (RegisterResponse, error) {
+	if _, err := s.IdGen.NewID(); err != nil {
+		return RegisterResponse{}, err
+	}
+	if _, err := s.Hasher.Hash(ctx, password); err != nil {
+		return RegisterResponse{}, err
+	}
+	s.notifyTakenEmail(ctx, existing.Email)
+	return RegisterResponse{Concealed: true}, nil
+}
+
+// notifyTakenEmail ignores failures, like the verification mail of a new
+// account, so that they do not change the response.
+func (s *RegisterService) notifyTakenEmail(ctx context.Context, email string) {
+	_ = s.Notifier.Notify(ctx, Message{
+		To:      email,
+		Subject: "Registration attempt",
+		Body: "Someone tried to create an account with this email address, which already has one.\n\n" +
+			"If it was you, log in or reset your password instead. Otherwise ignore this message.",
+	})
 }
@@ -0,0 +1,73 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"testing"
+)
+
+func newConcealingRegisterService(users ...entities.User) (*RegisterService, *hasherStub, *notifierStub) {
+	hasher := &hasherStub{}
+	notifier := &notifierStub{}
+	service := NewRegisterService(newFakeUserRepo(users...), hasher, &idGenStub{})
+	service.ConcealExistingEmail = true
+	service.Notifier = notifier
+	return service, hasher, notifier
+}
+
+func TestRegister_ConcealsTakenEmail(t *testing.T) {
+	ctx := context.Background()
+	existing := entitiesUser()
+
+	newService, newHasher, newNotifier := newConcealingRegisterService(existing)
+	created, err := newService.Register(ctx, RegisterRequest{Username: "demo", Email: "demo@example.com", Password: "password1"})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	takenService, takenHasher, takenNotifier := newConcealingRegisterService(existing)
+	taken, err := takenService.Register(ctx, RegisterRequest{Username: "demo", Email: existing.Email, Password: "password1"})
+	if err != nil {
+		t.Fatalf("taken email must not be reported, got: %v", err)
+	}
+
+	if !created.Concealed || !taken.Concealed {
+		t.Fatalf("both responses must be concealed: %+v, %+v", created, taken)
+	}
+	if taken.User.ID != "" {
+		t.Fatalf("no user expected for a taken email, got %+v", taken.User)
+	}
+	if newHasher.hashes != 1 || takenHasher.hashes != 1 {
+		t.Fatalf("both paths must hash once, got %d and %d", newHasher.hashes, takenHasher.hashes)
+	}
+	if len(newNotifier.messages) != 0 {
+		t.Fatalf("new accounts must not get the notice, got %+v", newNotifier.messages)
+	}
+	if len(takenNotifier.messages) != 1 || takenNotifier.messages[0].To != existing.Email {
+		t.Fatalf("expected a notice to %s, got %+v", existing.Email, takenNotifier.messages)
+	}
+}
+
+func TestRegister_UsernameCheckedBeforeEmail(t *testing.T) {
+	service, _, notifier := newConcealingRegisterService(entitiesUser())
+
+	for _, email := range []string{"islam@gmail.com", "other@example.com"} {
+		_, err := service.Register(context.Background(), RegisterRequest{Username: "islam", Email: email, Password: "password1"})
+		if !errors.Is(err, ErrUsernameTaken) {
+			t.Fatalf("%s: expected ErrUsernameTaken, got: %v", email, err)
+		}
+	}
+	if len(notifier.messages) != 0 {
+		t.Fatalf("no notice expected, got %+v", notifier.messages)
+	}
+}
+
+func TestRegister_ReportsTakenEmailByDefault(t *testing.T) {
+	service := NewRegisterService(newFakeUserRepo(entitiesUser()), &hasherStub{}, &idGenStub{})
+
+	resp, err := service.Register(context.Background(), RegisterRequest{Username: "demo", Email: "islam@gmail.com", Password: "password1"})
+	if !errors.Is(err, ErrEmailTaken) || resp.Concealed {
+		t.Fatalf("expected ErrEmailTaken, got: %v, %+v", err, resp)
+	}
+}
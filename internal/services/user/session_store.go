@@ -6,14 +6,54 @@ import (
 )
 
 type Session struct {
-	ID        string
-	UserID    string
-	ExpiresAt time.Time
+	ID         string
+	UserID     string
+	IP         string
+	UserAgent  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	Persistent bool
+}
+
+type SessionAttrs struct {
+	UserID     string
+	IP         string
+	UserAgent  string
+	Persistent bool
+}
+
+// SessionLifetime describes how long a session stays valid. Every authenticated
+// request pushes the expiry IdleTimeout into the future, but never past
+// MaxLifetime after creation. A zero MaxLifetime disables the absolute cap.
+// Persistent ("remember me") sessions instead live for RememberMeTTL from
+// creation regardless of activity.
+type SessionLifetime struct {
+	IdleTimeout   time.Duration
+	MaxLifetime   time.Duration
+	RememberMeTTL time.Duration
+}
+
+func (l SessionLifetime) ExpiresAt(session Session, seenAt time.Time) time.Time {
+	if session.Persistent && l.RememberMeTTL > 0 {
+		return session.CreatedAt.Add(l.RememberMeTTL)
+	}
+
+	expiresAt := seenAt.Add(l.IdleTimeout)
+	if l.MaxLifetime > 0 {
+		deadline := session.CreatedAt.Add(l.MaxLifetime)
+		if expiresAt.After(deadline) {
+			expiresAt = deadline
+		}
+	}
+	return expiresAt
 }
 
 type SessionStore interface {
-	Create(ctx context.Context, userID string) (Session, error)
+	Create(ctx context.Context, attrs SessionAttrs) (Session, error)
 	Get(ctx context.Context, sessionID string) (Session, error)
+	Touch(ctx context.Context, sessionID string, seenAt time.Time) (Session, error)
 	Delete(ctx context.Context, sessionID string) error
+	ListByUser(ctx context.Context, userID string) ([]Session, error)
+	DeleteByUser(ctx context.Context, userID string) error
 }
-
@@ -0,0 +1,129 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+)
+
+type ListSessionsRequest struct {
+	UserID string
+}
+
+type ListSessionsResponse struct {
+	Sessions []Session
+}
+
+type RevokeSessionRequest struct {
+	UserID string
+	// Handle is the SessionHandle of the session, never its ID.
+	Handle string
+}
+
+type RevokeSessionResponse struct {
+	Success bool
+}
+
+type RevokeAllSessionsRequest struct {
+	UserID string
+}
+
+type RevokeAllSessionsResponse struct {
+	Success bool
+}
+
+type SessionService struct {
+	SessionStore SessionStore
+	// RefreshTokens, when set, has every refresh token of the user revoked
+	// by RevokeAll.
+	RefreshTokens RefreshTokenStore
+}
+
+func NewSessionService(sessionStore SessionStore) *SessionService {
+	return &SessionService{
+		SessionStore: sessionStore,
+	}
+}
+
+func (s *SessionService) List(ctx context.Context, req ListSessionsRequest) (ListSessionsResponse, error) {
+	sessions, err := s.SessionStore.ListByUser(ctx, req.UserID)
+	if err != nil {
+		return ListSessionsResponse{}, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return ListSessionsResponse{Sessions: sessions}, nil
+}
+
+// SessionHandle identifies a session in listings without revealing its ID,
+// which is a credential: it works as the session cookie and bearer token.
+func SessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+// Revoke deletes a single session of the user by its handle. Only the user's
+// own sessions are searched, so handles of other users' sessions are
+// reported as not found.
+func (s *SessionService) Revoke(ctx context.Context, req RevokeSessionRequest) (RevokeSessionResponse, error) {
+	sessions, err := s.SessionStore.ListByUser(ctx, req.UserID)
+	if err != nil {
+		return RevokeSessionResponse{Success: false}, err
+	}
+
+	for _, session := range sessions {
+		if SessionHandle(session.ID) != req.Handle {
+			continue
+		}
+		err = s.SessionStore.Delete(ctx, session.ID)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return RevokeSessionResponse{Success: false}, err
+		}
+		return RevokeSessionResponse{Success: true}, nil
+	}
+	return RevokeSessionResponse{Success: false}, ErrSessionNotFound
+}
+
+// RevokeAll signs the user out everywhere: the sessions go, and so do the
+// refresh tokens that would mint new access tokens.
+func (s *SessionService) RevokeAll(ctx context.Context, req RevokeAllSessionsRequest) (RevokeAllSessionsResponse, error) {
+	err := s.SessionStore.DeleteByUser(ctx, req.UserID)
+	if err != nil {
+		return RevokeAllSessionsResponse{Success: false}, err
+	}
+	if s.RefreshTokens != nil {
+		err = s.RefreshTokens.DeleteByUser(ctx, req.UserID)
+		if err != nil {
+			return RevokeAllSessionsResponse{Success: false}, err
+		}
+	}
+	return RevokeAllSessionsResponse{Success: true}, nil
+}
+
+// revokeOtherSessions deletes every session of the user except keepSessionID.
+// An empty keepSessionID revokes all of them.
+func revokeOtherSessions(ctx context.Context, store SessionStore, userID string, keepSessionID string) error {
+	if keepSessionID == "" {
+		return store.DeleteByUser(ctx, userID)
+	}
+
+	sessions, err := store.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == keepSessionID {
+			continue
+		}
+		err = store.Delete(ctx, session.ID)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+	return nil
+}
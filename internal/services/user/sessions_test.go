@@ -0,0 +1,198 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeSessionStore struct {
+	sessions map[string]Session
+}
+
+func newFakeSessionStore(sessions ...Session) *fakeSessionStore {
+	store := &fakeSessionStore{sessions: make(map[string]Session)}
+	for _, session := range sessions {
+		store.sessions[session.ID] = session
+	}
+	return store
+}
+
+func (s *fakeSessionStore) Create(ctx context.Context, attrs SessionAttrs) (Session, error) {
+	now := time.Now()
+	session := Session{
+		ID:         "session-" + attrs.UserID,
+		UserID:     attrs.UserID,
+		IP:         attrs.IP,
+		UserAgent:  attrs.UserAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	s.sessions[session.ID] = session
+	return session, nil
+}
+
+func (s *fakeSessionStore) Get(ctx context.Context, sessionID string) (Session, error) {
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (s *fakeSessionStore) Touch(ctx context.Context, sessionID string, seenAt time.Time) (Session, error) {
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	session.LastSeenAt = seenAt
+	s.sessions[sessionID] = session
+	return session, nil
+}
+
+func (s *fakeSessionStore) Delete(ctx context.Context, sessionID string) error {
+	if _, ok := s.sessions[sessionID]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func (s *fakeSessionStore) ListByUser(ctx context.Context, userID string) ([]Session, error) {
+	var sessions []Session
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *fakeSessionStore) DeleteByUser(ctx context.Context, userID string) error {
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func TestSessionService_List(t *testing.T) {
+	now := time.Now()
+	store := newFakeSessionStore(
+		Session{ID: "a", UserID: "1", LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		Session{ID: "b", UserID: "1", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+		Session{ID: "c", UserID: "2", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+	)
+	service := NewSessionService(store)
+
+	resp, err := service.List(context.Background(), ListSessionsRequest{UserID: "1"})
+	if err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if len(resp.Sessions) != 2 || resp.Sessions[0].ID != "b" || resp.Sessions[1].ID != "a" {
+		t.Fatalf("unexpected sessions: %+v", resp.Sessions)
+	}
+}
+
+func TestSessionService_RevokeForeignSession(t *testing.T) {
+	store := newFakeSessionStore(Session{ID: "a", UserID: "2", ExpiresAt: time.Now().Add(time.Hour)})
+	service := NewSessionService(store)
+
+	_, err := service.Revoke(context.Background(), RevokeSessionRequest{UserID: "1", Handle: SessionHandle("a")})
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got: %v", err)
+	}
+	if _, ok := store.sessions["a"]; !ok {
+		t.Fatalf("foreign session must not be deleted")
+	}
+}
+
+func TestSessionService_RevokeByHandle(t *testing.T) {
+	store := newFakeSessionStore(
+		Session{ID: "a", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+		Session{ID: "b", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+	)
+	service := NewSessionService(store)
+
+	_, err := service.Revoke(context.Background(), RevokeSessionRequest{UserID: "1", Handle: "a"})
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("the session ID itself must not be accepted, got: %v", err)
+	}
+
+	_, err = service.Revoke(context.Background(), RevokeSessionRequest{UserID: "1", Handle: SessionHandle("a")})
+	if err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if _, ok := store.sessions["a"]; ok || len(store.sessions) != 1 {
+		t.Fatalf("expected only session a to be deleted, got: %+v", store.sessions)
+	}
+}
+
+func TestSessionService_RevokeAll(t *testing.T) {
+	store := newFakeSessionStore(
+		Session{ID: "a", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+		Session{ID: "b", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+		Session{ID: "c", UserID: "2", ExpiresAt: time.Now().Add(time.Hour)},
+	)
+	refreshTokens := newFakeRefreshTokenStore()
+	refreshTokens.tokens["r1"] = RefreshToken{Hash: "r1", UserID: "1"}
+	refreshTokens.tokens["r2"] = RefreshToken{Hash: "r2", UserID: "2"}
+	service := NewSessionService(store)
+	service.RefreshTokens = refreshTokens
+
+	_, err := service.RevokeAll(context.Background(), RevokeAllSessionsRequest{UserID: "1"})
+	if err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if len(store.sessions) != 1 {
+		t.Fatalf("expected only the other user's session to remain, got: %+v", store.sessions)
+	}
+	if _, ok := refreshTokens.tokens["r1"]; ok || len(refreshTokens.tokens) != 1 {
+		t.Fatalf("expected only the other user's refresh token to remain, got: %+v", refreshTokens.tokens)
+	}
+}
+
+func TestSessionLifetime_ExpiresAt(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	session := Session{CreatedAt: createdAt}
+	lifetime := SessionLifetime{IdleTimeout: 30 * time.Minute, MaxLifetime: 12 * time.Hour}
+
+	if got := lifetime.ExpiresAt(session, createdAt); !got.Equal(createdAt.Add(30 * time.Minute)) {
+		t.Fatalf("unexpected expiry for new session: %v", got)
+	}
+
+	seenAt := createdAt.Add(2 * time.Hour)
+	if got := lifetime.ExpiresAt(session, seenAt); !got.Equal(seenAt.Add(30 * time.Minute)) {
+		t.Fatalf("expiry was not extended: %v", got)
+	}
+
+	seenAt = createdAt.Add(11*time.Hour + 50*time.Minute)
+	if got := lifetime.ExpiresAt(session, seenAt); !got.Equal(createdAt.Add(12 * time.Hour)) {
+		t.Fatalf("expiry was not capped: %v", got)
+	}
+
+	uncapped := SessionLifetime{IdleTimeout: 30 * time.Minute}
+	seenAt = createdAt.Add(48 * time.Hour)
+	if got := uncapped.ExpiresAt(session, seenAt); !got.Equal(seenAt.Add(30 * time.Minute)) {
+		t.Fatalf("unexpected uncapped expiry: %v", got)
+	}
+}
+
+func TestSessionLifetime_RememberMe(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	session := Session{CreatedAt: createdAt, Persistent: true}
+	lifetime := SessionLifetime{IdleTimeout: 30 * time.Minute, MaxLifetime: 12 * time.Hour, RememberMeTTL: 720 * time.Hour}
+
+	seenAt := createdAt.Add(24 * time.Hour)
+	if got := lifetime.ExpiresAt(session, seenAt); !got.Equal(createdAt.Add(720 * time.Hour)) {
+		t.Fatalf("unexpected persistent expiry: %v", got)
+	}
+
+	lifetime.RememberMeTTL = 0
+	if got := lifetime.ExpiresAt(session, createdAt); !got.Equal(createdAt.Add(30 * time.Minute)) {
+		t.Fatalf("expected regular expiry without remember me ttl: %v", got)
+	}
+}
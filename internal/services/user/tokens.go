@@ -0,0 +1,177 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type AccessToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+type AccessClaims struct {
+	UserID    string
+	ExpiresAt time.Time
+}
+
+type AccessTokenIssuer interface {
+	Issue(ctx context.Context, userID string) (AccessToken, error)
+	Verify(ctx context.Context, token string) (AccessClaims, error)
+}
+
+// RefreshToken is the server-side record of an opaque refresh token. Tokens
+// obtained from one another by rotation share a FamilyID.
+type RefreshToken struct {
+	Hash      string
+	FamilyID  string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type RefreshTokenStore interface {
+	Create(ctx context.Context, token RefreshToken) error
+	// Consume marks the token as used and returns it. A token that has been
+	// consumed before is returned together with ErrRefreshTokenReused.
+	Consume(ctx context.Context, hash string) (RefreshToken, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+type TokenPair struct {
+	AccessToken      AccessToken
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+type IssueTokensRequest struct {
+	UserID string
+}
+
+type IssueTokensResponse struct {
+	Tokens TokenPair
+}
+
+type RefreshTokensRequest struct {
+	RefreshToken string
+}
+
+type RefreshTokensResponse struct {
+	Tokens TokenPair
+}
+
+type RevokeTokensRequest struct {
+	RefreshToken string
+}
+
+type RevokeTokensResponse struct {
+	Success bool
+}
+
+type TokenService struct {
+	AccessTokens  AccessTokenIssuer
+	RefreshTokens RefreshTokenStore
+	RefreshTTL    time.Duration
+	IdGen         IDGen
+}
+
+func NewTokenService(accessTokens AccessTokenIssuer, refreshTokens RefreshTokenStore, refreshTTL time.Duration, idGen IDGen) *TokenService {
+	return &TokenService{
+		AccessTokens:  accessTokens,
+		RefreshTokens: refreshTokens,
+		RefreshTTL:    refreshTTL,
+		IdGen:         idGen,
+	}
+}
+
+func (s *TokenService) Issue(ctx context.Context, req IssueTokensRequest) (IssueTokensResponse, error) {
+	familyID, err := s.IdGen.NewID()
+	if err != nil {
+		return IssueTokensResponse{}, err
+	}
+
+	tokens, err := s.issue(ctx, req.UserID, familyID)
+	if err != nil {
+		return IssueTokensResponse{}, err
+	}
+	return IssueTokensResponse{Tokens: tokens}, nil
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already
+// rotated means it has leaked, so the whole family is revoked and both the
+// attacker and the legitimate client have to log in again.
+func (s *TokenService) Refresh(ctx context.Context, req RefreshTokensRequest) (RefreshTokensResponse, error) {
+	if req.RefreshToken == "" {
+		return RefreshTokensResponse{}, ErrRefreshTokenInvalid
+	}
+
+	token, err := s.RefreshTokens.Consume(ctx, hashOpaqueToken(req.RefreshToken))
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := s.RefreshTokens.RevokeFamily(ctx, token.FamilyID); revokeErr != nil {
+			return RefreshTokensResponse{}, revokeErr
+		}
+		return RefreshTokensResponse{}, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return RefreshTokensResponse{}, err
+	}
+
+	if time.Now().UTC().After(token.ExpiresAt) {
+		return RefreshTokensResponse{}, ErrRefreshTokenInvalid
+	}
+
+	tokens, err := s.issue(ctx, token.UserID, token.FamilyID)
+	if err != nil {
+		return RefreshTokensResponse{}, err
+	}
+	return RefreshTokensResponse{Tokens: tokens}, nil
+}
+
+func (s *TokenService) Revoke(ctx context.Context, req RevokeTokensRequest) (RevokeTokensResponse, error) {
+	token, err := s.RefreshTokens.Consume(ctx, hashOpaqueToken(req.RefreshToken))
+	if errors.Is(err, ErrRefreshTokenInvalid) {
+		return RevokeTokensResponse{Success: true}, nil
+	}
+	if err != nil && !errors.Is(err, ErrRefreshTokenReused) {
+		return RevokeTokensResponse{Success: false}, err
+	}
+
+	err = s.RefreshTokens.RevokeFamily(ctx, token.FamilyID)
+	if err != nil {
+		return RevokeTokensResponse{Success: false}, err
+	}
+	return RevokeTokensResponse{Success: true}, nil
+}
+
+func (s *TokenService) issue(ctx context.Context, userID string, familyID string) (TokenPair, error) {
+	accessToken, err := s.AccessTokens.Issue(ctx, userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refreshToken, hash, err := newOpaqueToken("")
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	timeNow := time.Now().UTC()
+	record := RefreshToken{
+		Hash:      hash,
+		FamilyID:  familyID,
+		UserID:    userID,
+		CreatedAt: timeNow,
+		ExpiresAt: timeNow.Add(s.RefreshTTL),
+	}
+	err = s.RefreshTokens.Create(ctx, record)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: record.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type accessTokensStub struct{}
+
+func (a *accessTokensStub) Issue(ctx context.Context, userID string) (AccessToken, error) {
+	return AccessToken{Token: "access-" + userID, ExpiresAt: time.Now().Add(time.Minute)}, nil
+}
+
+func (a *accessTokensStub) Verify(ctx context.Context, token string) (AccessClaims, error) {
+	return AccessClaims{}, ErrAccessTokenInvalid
+}
+
+type fakeRefreshTokenStore struct {
+	tokens map[string]RefreshToken
+	used   map[string]bool
+}
+
+func newFakeRefreshTokenStore() *fakeRefreshTokenStore {
+	return &fakeRefreshTokenStore{tokens: make(map[string]RefreshToken), used: make(map[string]bool)}
+}
+
+func (s *fakeRefreshTokenStore) Create(ctx context.Context, token RefreshToken) error {
+	s.tokens[token.Hash] = token
+	return nil
+}
+
+func (s *fakeRefreshTokenStore) Consume(ctx context.Context, hash string) (RefreshToken, error) {
+	token, ok := s.tokens[hash]
+	if !ok {
+		return RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	if s.used[hash] {
+		return token, ErrRefreshTokenReused
+	}
+	s.used[hash] = true
+	return token, nil
+}
+
+func (s *fakeRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	for hash, token := range s.tokens {
+		if token.FamilyID == familyID {
+			delete(s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (s *fakeRefreshTokenStore) DeleteByUser(ctx context.Context, userID string) error {
+	for hash, token := range s.tokens {
+		if token.UserID == userID {
+			delete(s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+type idGenStub struct {
+	n int
+}
+
+func (g *idGenStub) NewID() (string, error) {
+	g.n++
+	return "id-" + strconv.Itoa(g.n), nil
+}
+
+func TestTokenService_RefreshRotates(t *testing.T) {
+	store := newFakeRefreshTokenStore()
+	service := NewTokenService(&accessTokensStub{}, store, time.Hour, &idGenStub{})
+	ctx := context.Background()
+
+	issued, err := service.Issue(ctx, IssueTokensRequest{UserID: "1"})
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	if issued.Tokens.AccessToken.Token != "access-1" || issued.Tokens.RefreshToken == "" {
+		t.Fatalf("unexpected tokens: %+v", issued.Tokens)
+	}
+	for hash := range store.tokens {
+		if hash == issued.Tokens.RefreshToken {
+			t.Fatalf("refresh token must be stored hashed")
+		}
+	}
+
+	refreshed, err := service.Refresh(ctx, RefreshTokensRequest{RefreshToken: issued.Tokens.RefreshToken})
+	if err != nil {
+		t.Fatalf("failed to refresh tokens: %v", err)
+	}
+	if refreshed.Tokens.RefreshToken == issued.Tokens.RefreshToken {
+		t.Fatalf("refresh token was not rotated")
+	}
+
+	if _, err := service.Refresh(ctx, RefreshTokensRequest{RefreshToken: refreshed.Tokens.RefreshToken}); err != nil {
+		t.Fatalf("rotated token should be usable: %v", err)
+	}
+}
+
+func TestTokenService_ReuseRevokesFamily(t *testing.T) {
+	store := newFakeRefreshTokenStore()
+	service := NewTokenService(&accessTokensStub{}, store, time.Hour, &idGenStub{})
+	ctx := context.Background()
+
+	issued, _ := service.Issue(ctx, IssueTokensRequest{UserID: "1"})
+	other, _ := service.Issue(ctx, IssueTokensRequest{UserID: "1"})
+	refreshed, err := service.Refresh(ctx, RefreshTokensRequest{RefreshToken: issued.Tokens.RefreshToken})
+	if err != nil {
+		t.Fatalf("failed to refresh tokens: %v", err)
+	}
+
+	_, err = service.Refresh(ctx, RefreshTokensRequest{RefreshToken: issued.Tokens.RefreshToken})
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got: %v", err)
+	}
+
+	_, err = service.Refresh(ctx, RefreshTokensRequest{RefreshToken: refreshed.Tokens.RefreshToken})
+	if !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("expected the whole family to be revoked, got: %v", err)
+	}
+
+	if _, err := service.Refresh(ctx, RefreshTokensRequest{RefreshToken: other.Tokens.RefreshToken}); err != nil {
+		t.Fatalf("unrelated family should survive: %v", err)
+	}
+}
+
+func TestLogin_Stateless(t *testing.T) {
+	repo := &loginRepoStub{user: entitiesUser()}
+	loginService := NewLoginService(repo, &hasherStub{}, &sessionStoreStub{})
+
+	request := LoginRequest{Email: "islam@gmail.com", Password: "secret", Stateless: true}
+	if _, err := loginService.Login(context.Background(), request); !errors.Is(err, ErrStatelessDisabled) {
+		t.Fatalf("expected ErrStatelessDisabled, got: %v", err)
+	}
+
+	loginService.Tokens = NewTokenService(&accessTokensStub{}, newFakeRefreshTokenStore(), time.Hour, &idGenStub{})
+	response, err := loginService.Login(context.Background(), request)
+	if err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if response.Session.ID != "" || response.Tokens.AccessToken.Token == "" {
+		t.Fatalf("expected tokens instead of a session, got: %+v", response)
+	}
+}
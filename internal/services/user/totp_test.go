@@ -0,0 +1,63 @@
+package user
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 test vectors,
+// "12345678901234567890" in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// The RFC lists eight digit values; six digit codes are their suffix.
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		code, err := totpCode(rfc6238Secret, totpStep(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("totpCode failed: %v", err)
+		}
+		if code != tc.code {
+			t.Fatalf("at %d: expected %s, got %s", tc.unix, tc.code, code)
+		}
+	}
+}
+
+func TestValidateTOTP_SkewAndReplay(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := totpStep(now)
+	previous, _ := totpCode(rfc6238Secret, step-1)
+
+	got, ok := validateTOTP(rfc6238Secret, previous, now, 0)
+	if !ok || got != step-1 {
+		t.Fatalf("code of the previous step should be accepted")
+	}
+	if _, ok := validateTOTP(rfc6238Secret, previous, now, step-1); ok {
+		t.Fatalf("code of an already used step must be rejected")
+	}
+
+	stale, _ := totpCode(rfc6238Secret, step-2)
+	if _, ok := validateTOTP(rfc6238Secret, stale, now, 0); ok {
+		t.Fatalf("code outside the skew window must be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("crud-service", "demo@example.com", rfc6238Secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/crud-service:demo@example.com?") {
+		t.Fatalf("unexpected label: %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+rfc6238Secret) || !strings.Contains(uri, "issuer=crud-service") {
+		t.Fatalf("missing parameters: %s", uri)
+	}
+}
@@ -0,0 +1,318 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+const (
+	defaultTwoFactorChallengeTTL = 5 * time.Minute
+	// maxTwoFactorAttempts bounds the codes that can be tried against one
+	// challenge before the password has to be entered again.
+	maxTwoFactorAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+type TwoFactorRepository interface {
+	FindOne(ctx context.Context, userID string, ent *entities.TwoFactor) error
+	// SavePending stores a new unconfirmed secret. It fails with
+	// ErrTwoFactorAlreadyEnabled once the secret has been confirmed.
+	SavePending(ctx context.Context, userID string, secret string) error
+	Confirm(ctx context.Context, userID string, step int64, confirmedAt time.Time, recoveryCodes []string) error
+	// UseStep records an accepted TOTP step and fails with
+	// ErrTwoFactorCodeInvalid if it is not newer than the last one.
+	UseStep(ctx context.Context, userID string, step int64) error
+	// UseRecoveryCode removes a recovery code hash and fails with
+	// ErrTwoFactorCodeInvalid if it is not there.
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) error
+	Delete(ctx context.Context, userID string) error
+}
+
+// TwoFactorChallenge is a login whose password has been verified but which
+// still waits for a second factor.
+type TwoFactorChallenge struct {
+	Hash       string
+	UserID     string
+	RememberMe bool
+	ExpiresAt  time.Time
+}
+
+type TwoFactorChallengeStore interface {
+	Create(ctx context.Context, challenge TwoFactorChallenge) error
+	// Get returns ErrTwoFactorChallengeInvalid for unknown or expired
+	// challenges.
+	Get(ctx context.Context, hash string) (TwoFactorChallenge, error)
+	// Fail counts a wrong code and returns the number of failed attempts.
+	Fail(ctx context.Context, hash string) (int, error)
+	// Delete returns ErrTwoFactorChallengeInvalid if the challenge is already
+	// gone, so that only one caller can complete it.
+	Delete(ctx context.Context, hash string) error
+}
+
+type EnrollTwoFactorRequest struct {
+	UserID string
+}
+
+type EnrollTwoFactorResponse struct {
+	Secret string
+	// URI is the otpauth:// URI to be shown as a QR code.
+	URI string
+}
+
+type ConfirmTwoFactorRequest struct {
+	UserID string
+	Code   string
+}
+
+type ConfirmTwoFactorResponse struct {
+	// RecoveryCodes are shown once; only their hashes are stored.
+	RecoveryCodes []string
+}
+
+type DisableTwoFactorRequest struct {
+	UserID string
+	Code   string
+}
+
+type DisableTwoFactorResponse struct {
+	Success bool
+}
+
+type TwoFactorService struct {
+	Repo         TwoFactorRepository
+	Users        LoginRepository
+	Challenges   TwoFactorChallengeStore
+	Issuer       string
+	ChallengeTTL time.Duration
+	// Throttle, when set, counts wrong codes given to Disable against the
+	// account like failed logins and refuses Disable while the account is
+	// held back. Disable is only limited by the rate limits when it is nil.
+	Throttle *LoginThrottle
+}
+
+func NewTwoFactorService(repo TwoFactorRepository, users LoginRepository, challenges TwoFactorChallengeStore, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		Repo:         repo,
+		Users:        users,
+		Challenges:   challenges,
+		Issuer:       issuer,
+		ChallengeTTL: defaultTwoFactorChallengeTTL,
+	}
+}
+
+func (s *TwoFactorService) Enroll(ctx context.Context, req EnrollTwoFactorRequest) (EnrollTwoFactorResponse, error) {
+	var user entities.User
+	err := s.Users.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(req.UserID)}, &user)
+	if err != nil {
+		return EnrollTwoFactorResponse{}, err
+	}
+
+	var tf entities.TwoFactor
+	err = s.Repo.FindOne(ctx, req.UserID, &tf)
+	if err == nil && tf.ConfirmedAt.IsPresent() {
+		return EnrollTwoFactorResponse{}, ErrTwoFactorAlreadyEnabled
+	}
+	if err != nil && !errors.Is(err, ErrTwoFactorNotEnrolled) {
+		return EnrollTwoFactorResponse{}, err
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return EnrollTwoFactorResponse{}, err
+	}
+	err = s.Repo.SavePending(ctx, req.UserID, secret)
+	if err != nil {
+		return EnrollTwoFactorResponse{}, err
+	}
+
+	return EnrollTwoFactorResponse{
+		Secret: secret,
+		URI:    totpURI(s.Issuer, user.Email, secret),
+	}, nil
+}
+
+func (s *TwoFactorService) Confirm(ctx context.Context, req ConfirmTwoFactorRequest) (ConfirmTwoFactorResponse, error) {
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		return ConfirmTwoFactorResponse{}, ErrTwoFactorCodeRequired
+	}
+
+	var tf entities.TwoFactor
+	err := s.Repo.FindOne(ctx, req.UserID, &tf)
+	if err != nil {
+		return ConfirmTwoFactorResponse{}, err
+	}
+	if tf.ConfirmedAt.IsPresent() {
+		return ConfirmTwoFactorResponse{}, ErrTwoFactorAlreadyEnabled
+	}
+
+	timeNow := time.Now().UTC()
+	step, ok := validateTOTP(tf.Secret, code, timeNow, 0)
+	if !ok {
+		return ConfirmTwoFactorResponse{}, ErrTwoFactorCodeInvalid
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return ConfirmTwoFactorResponse{}, err
+	}
+	err = s.Repo.Confirm(ctx, req.UserID, step, timeNow, hashes)
+	if err != nil {
+		return ConfirmTwoFactorResponse{}, err
+	}
+
+	return ConfirmTwoFactorResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable removes the second factor after checking a current code, so that
+// a stolen session alone cannot turn it off.
+func (s *TwoFactorService) Disable(ctx context.Context, req DisableTwoFactorRequest) (DisableTwoFactorResponse, error) {
+	var email string
+	if s.Throttle != nil {
+		var user entities.User
+		err := s.Users.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(req.UserID)}, &user)
+		if err != nil {
+			return DisableTwoFactorResponse{Success: false}, err
+		}
+		email = user.Email
+		if err := s.Throttle.Check(ctx, email, ""); err != nil {
+			return DisableTwoFactorResponse{Success: false}, err
+		}
+	}
+
+	err := s.verify(ctx, req.UserID, req.Code)
+	if errors.Is(err, ErrTwoFactorCodeInvalid) && s.Throttle != nil {
+		if failErr := s.Throttle.Fail(ctx, email, ""); failErr != nil {
+			return DisableTwoFactorResponse{Success: false}, failErr
+		}
+	}
+	if err != nil {
+		return DisableTwoFactorResponse{Success: false}, err
+	}
+
+	err = s.Repo.Delete(ctx, req.UserID)
+	if err != nil {
+		return DisableTwoFactorResponse{Success: false}, err
+	}
+	return DisableTwoFactorResponse{Success: true}, nil
+}
+
+// enabled reports whether the user has a confirmed second factor.
+func (s *TwoFactorService) enabled(ctx context.Context, userID string) (bool, error) {
+	var tf entities.TwoFactor
+	err := s.Repo.FindOne(ctx, userID, &tf)
+	if errors.Is(err, ErrTwoFactorNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return tf.ConfirmedAt.IsPresent(), nil
+}
+
+// verify accepts either a current TOTP code or an unused recovery code. Both
+// are single use.
+func (s *TwoFactorService) verify(ctx context.Context, userID, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrTwoFactorCodeRequired
+	}
+
+	var tf entities.TwoFactor
+	err := s.Repo.FindOne(ctx, userID, &tf)
+	if err != nil {
+		return err
+	}
+	if tf.ConfirmedAt.IsAbsent() {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if step, ok := validateTOTP(tf.Secret, code, time.Now(), tf.LastUsedStep); ok {
+		return s.Repo.UseStep(ctx, userID, step)
+	}
+	if len(code) == totpDigits {
+		return ErrTwoFactorCodeInvalid
+	}
+	return s.Repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+}
+
+func (s *TwoFactorService) startChallenge(ctx context.Context, userID string, rememberMe bool) (PendingLogin, error) {
+	token, hash, err := newOpaqueToken("")
+	if err != nil {
+		return PendingLogin{}, err
+	}
+
+	expiresAt := time.Now().UTC().Add(s.ChallengeTTL)
+	err = s.Challenges.Create(ctx, TwoFactorChallenge{
+		Hash:       hash,
+		UserID:     userID,
+		RememberMe: rememberMe,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return PendingLogin{}, err
+	}
+	return PendingLogin{Challenge: token, ExpiresAt: expiresAt}, nil
+}
+
+// completeChallenge checks code against the challenge and consumes it. A
+// wrong code gives ErrTwoFactorCodeInvalid together with the challenge, so
+// that the caller can count the failure against the user.
+func (s *TwoFactorService) completeChallenge(ctx context.Context, token, code string) (TwoFactorChallenge, error) {
+	hash := hashOpaqueToken(token)
+	challenge, err := s.Challenges.Get(ctx, hash)
+	if err != nil {
+		return TwoFactorChallenge{}, err
+	}
+
+	err = s.verify(ctx, challenge.UserID, code)
+	if errors.Is(err, ErrTwoFactorCodeInvalid) {
+		attempts, failErr := s.Challenges.Fail(ctx, hash)
+		if failErr != nil {
+			return TwoFactorChallenge{}, failErr
+		}
+		if attempts >= maxTwoFactorAttempts {
+			_ = s.Challenges.Delete(ctx, hash)
+		}
+		return challenge, err
+	}
+	if err != nil {
+		return TwoFactorChallenge{}, err
+	}
+
+	err = s.Challenges.Delete(ctx, hash)
+	if err != nil {
+		return TwoFactorChallenge{}, err
+	}
+	return challenge, nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	buf := make([]byte, 5)
+	for range recoveryCodeCount {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(buf)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case and separators so that codes can be typed
+// as printed or without the dash.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashOpaqueToken(code)
+}
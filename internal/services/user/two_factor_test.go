@@ -0,0 +1,269 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type fakeTwoFactorRepo struct {
+	records map[string]entities.TwoFactor
+}
+
+func newFakeTwoFactorRepo() *fakeTwoFactorRepo {
+	return &fakeTwoFactorRepo{records: make(map[string]entities.TwoFactor)}
+}
+
+func (r *fakeTwoFactorRepo) FindOne(ctx context.Context, userID string, ent *entities.TwoFactor) error {
+	tf, ok := r.records[userID]
+	if !ok {
+		return ErrTwoFactorNotEnrolled
+	}
+	*ent = tf
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) SavePending(ctx context.Context, userID string, secret string) error {
+	if r.records[userID].ConfirmedAt.IsPresent() {
+		return ErrTwoFactorAlreadyEnabled
+	}
+	r.records[userID] = entities.TwoFactor{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) Confirm(ctx context.Context, userID string, step int64, confirmedAt time.Time, recoveryCodes []string) error {
+	tf := r.records[userID]
+	tf.ConfirmedAt = mo.Some(confirmedAt)
+	tf.LastUsedStep = step
+	tf.RecoveryCodes = recoveryCodes
+	r.records[userID] = tf
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) UseStep(ctx context.Context, userID string, step int64) error {
+	tf := r.records[userID]
+	if step <= tf.LastUsedStep {
+		return ErrTwoFactorCodeInvalid
+	}
+	tf.LastUsedStep = step
+	r.records[userID] = tf
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) UseRecoveryCode(ctx context.Context, userID string, codeHash string) error {
+	tf := r.records[userID]
+	i := slices.Index(tf.RecoveryCodes, codeHash)
+	if i < 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+	tf.RecoveryCodes = slices.Delete(tf.RecoveryCodes, i, i+1)
+	r.records[userID] = tf
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) Delete(ctx context.Context, userID string) error {
+	if _, ok := r.records[userID]; !ok {
+		return ErrTwoFactorNotEnrolled
+	}
+	delete(r.records, userID)
+	return nil
+}
+
+type fakeChallengeStore struct {
+	challenges map[string]TwoFactorChallenge
+	attempts   map[string]int
+}
+
+func newFakeChallengeStore() *fakeChallengeStore {
+	return &fakeChallengeStore{challenges: make(map[string]TwoFactorChallenge), attempts: make(map[string]int)}
+}
+
+func (s *fakeChallengeStore) Create(ctx context.Context, challenge TwoFactorChallenge) error {
+	s.challenges[challenge.Hash] = challenge
+	return nil
+}
+
+func (s *fakeChallengeStore) Get(ctx context.Context, hash string) (TwoFactorChallenge, error) {
+	challenge, ok := s.challenges[hash]
+	if !ok {
+		return TwoFactorChallenge{}, ErrTwoFactorChallengeInvalid
+	}
+	return challenge, nil
+}
+
+func (s *fakeChallengeStore) Fail(ctx context.Context, hash string) (int, error) {
+	s.attempts[hash]++
+	return s.attempts[hash], nil
+}
+
+func (s *fakeChallengeStore) Delete(ctx context.Context, hash string) error {
+	if _, ok := s.challenges[hash]; !ok {
+		return ErrTwoFactorChallengeInvalid
+	}
+	delete(s.challenges, hash)
+	return nil
+}
+
+// enableTwoFactor enrolls the user and confirms with the code of the
+// previous step, so that the current one is still unused.
+func enableTwoFactor(t *testing.T, service *TwoFactorService, userID string) (string, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	enrolled, err := service.Enroll(ctx, EnrollTwoFactorRequest{UserID: userID})
+	if err != nil {
+		t.Fatalf("Enroll failed: %v", err)
+	}
+	code, _ := totpCode(enrolled.Secret, totpStep(time.Now())-1)
+	confirmed, err := service.Confirm(ctx, ConfirmTwoFactorRequest{UserID: userID, Code: code})
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if len(confirmed.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(confirmed.RecoveryCodes))
+	}
+	return enrolled.Secret, confirmed.RecoveryCodes
+}
+
+func newTwoFactorLogin() (*LoginService, *TwoFactorService, *fakeChallengeStore) {
+	users := &loginRepoStub{user: entitiesUser()}
+	challenges := newFakeChallengeStore()
+	twoFactor := NewTwoFactorService(newFakeTwoFactorRepo(), users, challenges, "crud-service")
+	login := NewLoginService(users, &hasherStub{}, &sessionStoreStub{})
+	login.TwoFactor = twoFactor
+	return login, twoFactor, challenges
+}
+
+func TestTwoFactor_LoginRequiresCode(t *testing.T) {
+	login, twoFactor, _ := newTwoFactorLogin()
+	ctx := context.Background()
+	user := entitiesUser()
+	secret, _ := enableTwoFactor(t, twoFactor, user.ID)
+
+	resp, err := login.Login(ctx, LoginRequest{Email: user.Email, Password: "secret", RememberMe: true})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	pending, ok := resp.Pending.Get()
+	if !ok || resp.Session.ID != "" {
+		t.Fatalf("login must stop before a session is issued: %+v", resp)
+	}
+
+	code, _ := totpCode(secret, totpStep(time.Now()))
+	completed, err := login.CompleteTwoFactor(ctx, CompleteTwoFactorRequest{Challenge: pending.Challenge, Code: code})
+	if err != nil {
+		t.Fatalf("CompleteTwoFactor failed: %v", err)
+	}
+	if completed.Session.UserID != user.ID || !completed.Session.Persistent {
+		t.Fatalf("unexpected session: %+v", completed.Session)
+	}
+
+	_, err = login.CompleteTwoFactor(ctx, CompleteTwoFactorRequest{Challenge: pending.Challenge, Code: code})
+	if !errors.Is(err, ErrTwoFactorChallengeInvalid) {
+		t.Fatalf("challenge must be single use, got: %v", err)
+	}
+
+	resp, _ = login.Login(ctx, LoginRequest{Email: user.Email, Password: "secret"})
+	pending, _ = resp.Pending.Get()
+	_, err = login.CompleteTwoFactor(ctx, CompleteTwoFactorRequest{Challenge: pending.Challenge, Code: code})
+	if !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Fatalf("TOTP code must not be accepted twice, got: %v", err)
+	}
+}
+
+func TestTwoFactor_RecoveryCode(t *testing.T) {
+	login, twoFactor, _ := newTwoFactorLogin()
+	ctx := context.Background()
+	user := entitiesUser()
+	_, recoveryCodes := enableTwoFactor(t, twoFactor, user.ID)
+
+	for i, want := range []error{nil, ErrTwoFactorCodeInvalid} {
+		resp, _ := login.Login(ctx, LoginRequest{Email: user.Email, Password: "secret"})
+		pending, _ := resp.Pending.Get()
+		_, err := login.CompleteTwoFactor(ctx, CompleteTwoFactorRequest{Challenge: pending.Challenge, Code: recoveryCodes[0]})
+		if !errors.Is(err, want) {
+			t.Fatalf("attempt %d: expected %v, got: %v", i, want, err)
+		}
+	}
+}
+
+func TestTwoFactor_AttemptLimit(t *testing.T) {
+	login, twoFactor, challenges := newTwoFactorLogin()
+	ctx := context.Background()
+	user := entitiesUser()
+	enableTwoFactor(t, twoFactor, user.ID)
+
+	resp, _ := login.Login(ctx, LoginRequest{Email: user.Email, Password: "secret"})
+	pending, _ := resp.Pending.Get()
+	for range maxTwoFactorAttempts {
+		_, err := login.CompleteTwoFactor(ctx, CompleteTwoFactorRequest{Challenge: pending.Challenge, Code: "000000"})
+		if !errors.Is(err, ErrTwoFactorCodeInvalid) {
+			t.Fatalf("expected ErrTwoFactorCodeInvalid, got: %v", err)
+		}
+	}
+	if len(challenges.challenges) != 0 {
+		t.Fatalf("challenge must be dropped after %d failed attempts", maxTwoFactorAttempts)
+	}
+}
+
+func TestTwoFactor_DisableThrottled(t *testing.T) {
+	_, twoFactor, _ := newTwoFactorLogin()
+	store := newFakeLoginAttemptStore()
+	twoFactor.Throttle = NewLoginThrottle(store)
+	twoFactor.Throttle.Account = ThrottlePolicy{LockoutThreshold: 3, LockoutDuration: time.Hour}
+	ctx := context.Background()
+	user := entitiesUser()
+	secret, _ := enableTwoFactor(t, twoFactor, user.ID)
+
+	for range 3 {
+		_, err := twoFactor.Disable(ctx, DisableTwoFactorRequest{UserID: user.ID, Code: "000000"})
+		if !errors.Is(err, ErrTwoFactorCodeInvalid) {
+			t.Fatalf("expected ErrTwoFactorCodeInvalid, got: %v", err)
+		}
+	}
+	if store.failures["account:islam@gmail.com"].Count != 3 {
+		t.Fatalf("expected every wrong code to be counted: %+v", store.failures)
+	}
+
+	code, _ := totpCode(secret, totpStep(time.Now()))
+	_, err := twoFactor.Disable(ctx, DisableTwoFactorRequest{UserID: user.ID, Code: code})
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("expected the account to be locked out, got: %v", err)
+	}
+	if enabled, _ := twoFactor.enabled(ctx, user.ID); !enabled {
+		t.Fatalf("a locked out account must keep its second factor")
+	}
+}
+
+func TestTwoFactor_EnrollAndDisable(t *testing.T) {
+	_, twoFactor, _ := newTwoFactorLogin()
+	ctx := context.Background()
+	user := entitiesUser()
+	secret, _ := enableTwoFactor(t, twoFactor, user.ID)
+
+	_, err := twoFactor.Enroll(ctx, EnrollTwoFactorRequest{UserID: user.ID})
+	if !errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+		t.Fatalf("expected ErrTwoFactorAlreadyEnabled, got: %v", err)
+	}
+
+	_, err = twoFactor.Disable(ctx, DisableTwoFactorRequest{UserID: user.ID, Code: "123456"})
+	if !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Fatalf("expected ErrTwoFactorCodeInvalid, got: %v", err)
+	}
+
+	code, _ := totpCode(secret, totpStep(time.Now()))
+	_, err = twoFactor.Disable(ctx, DisableTwoFactorRequest{UserID: user.ID, Code: code})
+	if err != nil {
+		t.Fatalf("Disable failed: %v", err)
+	}
+	enabled, _ := twoFactor.enabled(ctx, user.ID)
+	if enabled {
+		t.Fatalf("two-factor must be disabled")
+	}
+}
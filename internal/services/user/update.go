@@ -8,10 +8,13 @@ import (
 )
 
 type UpdateRequest struct {
-	ID       string
-	Username mo.Option[string]
-	Email    mo.Option[string]
-	Password mo.Option[string]
+	ID string
+	// SessionID is the session the request was made with. It survives a
+	// password change while every other session of the user is revoked.
+	SessionID string
+	Username  mo.Option[string]
+	Email     mo.Option[string]
+	Password  mo.Option[string]
 }
 
 type UpdateResponse struct {
@@ -19,17 +22,27 @@ type UpdateResponse struct {
 }
 
 type UpdateRepository interface {
+	FindOne(context.Context, entities.UserFilterAttrs, *entities.User) error
 	Update(context.Context, entities.UserUpdateAttrs, entities.UserFilterAttrs, *entities.User) error
 }
 type UpdateService struct {
-	Repo   UpdateRepository
-	Hasher PasswordHasher
+	Repo         UpdateRepository
+	Hasher       PasswordHasher
+	SessionStore SessionStore
+	// PasswordPolicy checks new passwords, DefaultPasswordPolicy when nil.
+	PasswordPolicy *PasswordPolicy
+	// RefreshTokens, when set, has every refresh token of the user revoked
+	// on password change.
+	RefreshTokens RefreshTokenStore
+	// Verification, when set, mails a confirmation link to a changed email.
+	Verification *EmailVerificationService
 }
 
-func NewUpdateService(repo UpdateRepository, hasher PasswordHasher) *UpdateService {
+func NewUpdateService(repo UpdateRepository, hasher PasswordHasher, sessionStore SessionStore) *UpdateService {
 	return &UpdateService{
-		Repo:   repo,
-		Hasher: hasher,
+		Repo:         repo,
+		Hasher:       hasher,
+		SessionStore: sessionStore,
 	}
 }
 
@@ -41,8 +54,15 @@ func (s *UpdateService) Update(ctx context.Context, req UpdateRequest) (UpdateRe
 
 	password, ok := req.Password.Get()
 	if ok {
-		if !ValidatePassword(password) {
-			return UpdateResponse{}, ErrPasswordIncorrect
+		var current entities.User
+		err := s.Repo.FindOne(ctx, entities.UserFilterAttrs{ID: mo.Some(id)}, &current)
+		if err != nil {
+			return UpdateResponse{}, err
+		}
+		err = passwordPolicyOrDefault(s.PasswordPolicy).Check(ctx, password,
+			current.Username, current.Email, username.OrEmpty(), email.OrEmpty())
+		if err != nil {
+			return UpdateResponse{}, err
 		}
 		hash, err := s.Hasher.Hash(ctx, password)
 		if err != nil {
@@ -64,6 +84,24 @@ func (s *UpdateService) Update(ctx context.Context, req UpdateRequest) (UpdateRe
 		return UpdateResponse{}, err
 	}
 
+	if hashedPassword.IsPresent() {
+		err = revokeOtherSessions(ctx, s.SessionStore, updatedUser.ID, req.SessionID)
+		if err != nil {
+			return UpdateResponse{}, err
+		}
+		if s.RefreshTokens != nil {
+			err = s.RefreshTokens.DeleteByUser(ctx, updatedUser.ID)
+			if err != nil {
+				return UpdateResponse{}, err
+			}
+		}
+	}
+
+	if email.IsPresent() && updatedUser.EmailVerifiedAt.IsAbsent() && s.Verification != nil {
+		// The change is stored at this point; a lost email can be resent.
+		_ = s.Verification.Send(ctx, updatedUser)
+	}
+
 	return UpdateResponse{
 		User: updatedUser,
 	}, nil
@@ -0,0 +1,76 @@
+package user
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"testing"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type updateRepoStub struct {
+	user entities.User
+}
+
+func (r *updateRepoStub) FindOne(ctx context.Context, filterAttrs entities.UserFilterAttrs, ent *entities.User) error {
+	*ent = r.user
+	return nil
+}
+
+func (r *updateRepoStub) Update(ctx context.Context, attrs entities.UserUpdateAttrs, filterAttrs entities.UserFilterAttrs, ent *entities.User) error {
+	if v, ok := attrs.HashedPassword.Get(); ok {
+		r.user.HashedPassword = v
+	}
+	if v, ok := attrs.Username.Get(); ok {
+		r.user.Username = v
+	}
+	*ent = r.user
+	return nil
+}
+
+func TestUpdate_PasswordChangeRevokesOtherSessions(t *testing.T) {
+	repo := &updateRepoStub{user: entities.User{ID: "1", Username: "islam"}}
+	store := newFakeSessionStore(
+		Session{ID: "current", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+		Session{ID: "other", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+		Session{ID: "foreign", UserID: "2", ExpiresAt: time.Now().Add(time.Hour)},
+	)
+	service := NewUpdateService(repo, &hasherStub{}, store)
+
+	_, err := service.Update(context.Background(), UpdateRequest{
+		ID:        "1",
+		SessionID: "current",
+		Password:  mo.Some("new-secret"),
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+
+	for id, want := range map[string]bool{"current": true, "other": false, "foreign": true} {
+		if _, ok := store.sessions[id]; ok != want {
+			t.Fatalf("session %q present = %v, want %v", id, ok, want)
+		}
+	}
+}
+
+func TestUpdate_KeepsSessionsWithoutPasswordChange(t *testing.T) {
+	repo := &updateRepoStub{user: entities.User{ID: "1", Username: "islam"}}
+	store := newFakeSessionStore(
+		Session{ID: "current", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+		Session{ID: "other", UserID: "1", ExpiresAt: time.Now().Add(time.Hour)},
+	)
+	service := NewUpdateService(repo, &hasherStub{}, store)
+
+	_, err := service.Update(context.Background(), UpdateRequest{
+		ID:        "1",
+		SessionID: "current",
+		Username:  mo.Some("renamed"),
+	})
+	if err != nil {
+		t.Fatalf("expected nil, got: %v", err)
+	}
+	if len(store.sessions) != 2 {
+		t.Fatalf("expected sessions to be kept, got: %+v", store.sessions)
+	}
+}
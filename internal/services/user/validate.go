@@ -9,10 +9,3 @@ func ValidateEmail(email string) bool {
 	}
 	return true
 }
-
-func ValidatePassword(password string) bool {
-	if len(password) < 8 {
-		return false
-	}
-	return true
-}
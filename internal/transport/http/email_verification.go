@@ -0,0 +1,68 @@
+package http
+
+import (
+	"crud/internal/services/user"
+	helpers "crud/internal/transport/http/helpers"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type EmailVerificationHandler struct {
+	emailVerificationService *user.EmailVerificationService
+	logger                   *log.Logger
+}
+
+func NewEmailVerificationHandler(emailVerificationService *user.EmailVerificationService, logger *log.Logger) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		emailVerificationService: emailVerificationService,
+		logger:                   logger,
+	}
+}
+
+func (h *EmailVerificationHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var verifyReq VerifyEmailRequest
+	err := helpers.DecodeJSON(r, &verifyReq)
+	if err != nil {
+		h.logger.Printf("verify email: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	serviceResp, err := h.emailVerificationService.Verify(r.Context(), user.VerifyEmailRequest{Token: verifyReq.Token})
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrVerificationTokenInvalid):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			h.logger.Printf("verify email: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	err = helpers.WriteJSON(w, http.StatusOK, VerifyEmailResponse{User: newUserDTO(serviceResp.User)})
+	if err != nil {
+		h.logger.Printf("verify email: write response failed: %v", err)
+	}
+}
+
+// Resend answers 202 whatever happens past decoding, so that the response
+// does not reveal whether the email is registered or already verified.
+func (h *EmailVerificationHandler) Resend(w http.ResponseWriter, r *http.Request) {
+	var resendReq ResendVerificationRequest
+	err := helpers.DecodeJSON(r, &resendReq)
+	if err != nil {
+		h.logger.Printf("resend verification: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	_, err = h.emailVerificationService.Resend(r.Context(), user.ResendVerificationRequest{Email: resendReq.Email})
+	if err != nil && !errors.Is(err, user.ErrEmailRequired) && !errors.Is(err, user.ErrEmailIncorrect) {
+		h.logger.Printf("resend verification: internal error: %v", err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"crud/internal/services/user"
+	helpers "crud/internal/transport/http/helpers"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// externalLoginStateCookie binds an external login to the browser that
+// started it, so that a callback URL cannot be replayed in another browser
+// to sign its user into the attacker's account.
+const externalLoginStateCookie = "external_login_state"
+
+const externalLoginCookiePath = "/users/login/external"
+
+func (h *UserHandler) ExternalLogin(w http.ResponseWriter, r *http.Request) {
+	serviceResponse, err := h.externalLoginService.Start(r.Context(), user.StartExternalLoginRequest{
+		Provider: chi.URLParam(r, "provider"),
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrUnknownIdentityProvider) {
+			helpers.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.logger.Printf("external login: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     externalLoginStateCookie,
+		Value:    serviceResponse.State,
+		Path:     externalLoginCookiePath,
+		Expires:  serviceResponse.ExpiresAt,
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, serviceResponse.RedirectURL, http.StatusFound)
+}
+
+func (h *UserHandler) ExternalLoginCallback(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     externalLoginStateCookie,
+		Value:    "",
+		Path:     externalLoginCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		helpers.WriteError(w, http.StatusUnauthorized, "external login failed: "+providerErr)
+		return
+	}
+	state := query.Get("state")
+	cookie, err := r.Cookie(externalLoginStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		helpers.WriteError(w, http.StatusBadRequest, user.ErrExternalLoginStateInvalid.Error())
+		return
+	}
+
+	serviceResponse, err := h.externalLoginService.Callback(r.Context(), user.ExternalLoginCallbackRequest{
+		Provider:  chi.URLParam(r, "provider"),
+		State:     state,
+		Code:      query.Get("code"),
+		Stateless: tokenDelivery(r) == tokenDeliveryJWT,
+		IP:        h.clientIP.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		if writeOverloadedError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, user.ErrUnknownIdentityProvider):
+			helpers.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, user.ErrExternalLoginStateInvalid):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, user.ErrExternalLoginFailed):
+			h.logger.Printf("external login callback: %v", err)
+			helpers.WriteError(w, http.StatusUnauthorized, "external login failed")
+			return
+		case errors.Is(err, user.ErrExternalEmailNotVerified) || errors.Is(err, user.ErrEmailNotVerified):
+			helpers.WriteError(w, http.StatusForbidden, err.Error())
+			return
+		case errors.Is(err, user.ErrExternalAccountConflict):
+			helpers.WriteError(w, http.StatusConflict, err.Error())
+			return
+		default:
+			h.logger.Printf("external login callback: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	h.writeLogin(w, r, serviceResponse)
+}
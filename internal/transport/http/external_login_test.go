@@ -0,0 +1,138 @@
+package http
+
+import (
+	"context"
+	stateMemory "crud/internal/adapters/external_login_state/memory"
+	"crud/internal/adapters/jwt"
+	refreshMemory "crud/internal/adapters/refresh_token/memory"
+	sessionMemory "crud/internal/adapters/session/memory"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"crud/internal/transport/http/helpers"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+type fakeIdentityProvider struct {
+	identity user.ExternalIdentity
+}
+
+func (p *fakeIdentityProvider) AuthURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	return "https://idp.example.com/authorize?state=" + url.QueryEscape(state), nil
+}
+
+func (p *fakeIdentityProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (user.ExternalIdentity, error) {
+	return p.identity, nil
+}
+
+type fakeLinkedIdentityRepo struct {
+	identities []entities.LinkedIdentity
+}
+
+func (r *fakeLinkedIdentityRepo) Create(ctx context.Context, identity entities.LinkedIdentity) error {
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeLinkedIdentityRepo) FindOne(ctx context.Context, provider string, subject string, ent *entities.LinkedIdentity) error {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			*ent = identity
+			return nil
+		}
+	}
+	return user.ErrLinkedIdentityNotFound
+}
+
+type externalLoginUserRepo struct {
+	fakeUserRepo
+}
+
+func (r *externalLoginUserRepo) Update(ctx context.Context, attrs entities.UserUpdateAttrs, filter entities.UserFilterAttrs, ent *entities.User) error {
+	return r.FindOne(ctx, filter, ent)
+}
+
+func TestExternalLoginCallback_JWTDelivery(t *testing.T) {
+	key, err := jwt.GenerateKey("k1")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keys, _ := jwt.NewKeySet([]jwt.Key{key}, "")
+	sessions, _ := sessionMemory.NewMemoryStore(user.SessionLifetime{IdleTimeout: time.Hour}, nil)
+	users := &externalLoginUserRepo{fakeUserRepo{users: []entities.User{{
+		ID:       "user-1",
+		Username: "demo",
+		Email:    "demo@example.com",
+	}}}}
+	identities := &fakeLinkedIdentityRepo{identities: []entities.LinkedIdentity{{
+		Provider: "idp",
+		Subject:  "subject-1",
+		UserID:   "user-1",
+	}}}
+
+	logins := user.NewLoginService(users, nil, sessions)
+	accessTokens := jwt.NewAccessTokens(keys, "https://auth.example.com", time.Minute)
+	logins.Tokens = user.NewTokenService(accessTokens, refreshMemory.NewMemoryStore(), time.Hour, &sequenceIDGen{})
+	externalLogins := user.NewExternalLoginService(
+		map[string]user.IdentityProvider{"idp": &fakeIdentityProvider{identity: user.ExternalIdentity{Subject: "subject-1"}}},
+		identities, stateMemory.NewMemoryStore(), users, nil, logins)
+	clientIP, _ := helpers.NewClientIPResolver(nil)
+	handler := NewUserHandler(nil, logins, nil, nil, nil, nil, externalLogins, clientIP, log.New(io.Discard, "", 0))
+
+	router := chi.NewRouter()
+	router.Get("/users/login/external/{provider}", handler.ExternalLogin)
+	router.Get("/users/login/external/{provider}/callback", handler.ExternalLoginCallback)
+
+	start := httptest.NewRecorder()
+	router.ServeHTTP(start, httptest.NewRequest(http.MethodGet, "/users/login/external/idp", nil))
+	if start.Code != http.StatusFound {
+		t.Fatalf("start status = %d, want %d", start.Code, http.StatusFound)
+	}
+	location, err := url.Parse(start.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("failed to parse redirect: %v", err)
+	}
+	state := location.Query().Get("state")
+
+	req := httptest.NewRequest(http.MethodGet, "/users/login/external/idp/callback?"+url.Values{
+		"state": {state},
+		"code":  {"code-1"},
+		"token": {tokenDeliveryJWT},
+	}.Encode(), nil)
+	for _, cookie := range start.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	callback := httptest.NewRecorder()
+	router.ServeHTTP(callback, req)
+
+	if callback.Code != http.StatusOK {
+		t.Fatalf("callback status = %d, want %d: %s", callback.Code, http.StatusOK, callback.Body.String())
+	}
+	var resp LoginResponse
+	if err := json.NewDecoder(callback.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Token == nil || resp.Token.AccessToken == "" || resp.Token.RefreshToken == "" {
+		t.Fatalf("token = %+v, want an access and a refresh token", resp.Token)
+	}
+	claims, err := accessTokens.Verify(context.Background(), resp.Token.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to verify access token: %v", err)
+	}
+	if claims.UserID != "user-1" {
+		t.Fatalf("access token subject = %q, want user-1", claims.UserID)
+	}
+	for _, cookie := range callback.Result().Cookies() {
+		if cookie.Name == helpers.SessionCookieName {
+			t.Fatalf("stateless login set a session cookie")
+		}
+	}
+}
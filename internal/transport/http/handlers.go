@@ -1,6 +1,7 @@
 package http
 
 import (
+	"crud/internal/domain/entities"
 	"crud/internal/services/user"
 	helpers "crud/internal/transport/http/helpers"
 	"crud/internal/transport/http/middleware"
@@ -8,15 +9,30 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/go-chi/chi"
 	"github.com/samber/mo"
 )
 
+const (
+	// Clients that cannot keep cookies ask for the session token in the login
+	// response body with ?token=body or the X-Token-Delivery header, or for a
+	// JWT access token plus refresh token with ?token=jwt.
+	tokenDeliveryParam  = "token"
+	tokenDeliveryHeader = "X-Token-Delivery"
+	tokenDeliveryBody   = "body"
+	tokenDeliveryJWT    = "jwt"
+)
+
 type UserHandler struct {
-	registerService *user.RegisterService
-	loginService    *user.LoginService
-	updateService   *user.UpdateService
-	deleteService   *user.DeleteService
-	logger          *log.Logger
+	registerService      *user.RegisterService
+	loginService         *user.LoginService
+	updateService        *user.UpdateService
+	deleteService        *user.DeleteService
+	sessionService       *user.SessionService
+	magicLinkService     *user.MagicLinkService
+	externalLoginService *user.ExternalLoginService
+	clientIP             *helpers.ClientIPResolver
+	logger               *log.Logger
 }
 
 func NewUserHandler(
@@ -24,40 +40,30 @@ func NewUserHandler(
 	loginService *user.LoginService,
 	updateService *user.UpdateService,
 	deleteService *user.DeleteService,
+	sessionService *user.SessionService,
+	magicLinkService *user.MagicLinkService,
+	externalLoginService *user.ExternalLoginService,
+	clientIP *helpers.ClientIPResolver,
 	logger *log.Logger) *UserHandler {
 	return &UserHandler{
-		registerService: registerService,
-		loginService:    loginService,
-		updateService:   updateService,
-		deleteService:   deleteService,
-		logger:          logger,
+		registerService:      registerService,
+		loginService:         loginService,
+		updateService:        updateService,
+		deleteService:        deleteService,
+		sessionService:       sessionService,
+		magicLinkService:     magicLinkService,
+		externalLoginService: externalLoginService,
+		clientIP:             clientIP,
+		logger:               logger,
 	}
 }
 
 func (h *UserHandler) setSessionCookie(w http.ResponseWriter, session user.Session) {
-	cookie := &http.Cookie{
-		Name:     "session_id",
-		Value:    session.ID,
-		Path:     "/",
-		Expires:  session.ExpiresAt,
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteLaxMode,
-	}
-	http.SetCookie(w, cookie)
+	helpers.SetSessionCookie(w, session.ID, session.ExpiresAt, session.Persistent)
 }
 
 func (h *UserHandler) clearSessionCookie(w http.ResponseWriter) {
-	cookie := &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteLaxMode,
-	}
-	http.SetCookie(w, cookie)
+	helpers.ClearSessionCookie(w)
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +85,9 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	serviceResponse, err := h.registerService.Register(ctx, serviceRequest)
 	if err != nil {
+		if writePasswordPolicyError(w, err) || writeOverloadedError(w, err) {
+			return
+		}
 		switch {
 		case errors.Is(err, user.ErrEmailRequired) || errors.Is(err, user.ErrPasswordRequired) || errors.Is(err, user.ErrUsernameRequired) ||
 			errors.Is(err, user.ErrEmailIncorrect) || errors.Is(err, user.ErrPasswordIncorrect):
@@ -94,13 +103,16 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if serviceResponse.Concealed {
+		// Whether the account was created or its email was taken, the
+		// next step is in the mailbox.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
 	user := serviceResponse.User
 	registerResp := RegisterResponse{
-		User: UserDTO{
-			ID:       user.ID,
-			UserName: user.Username,
-			Email:    user.Email,
-		},
+		User: newUserDTO(user),
 	}
 
 	err = helpers.WriteJSON(w, http.StatusCreated, registerResp)
@@ -121,20 +133,35 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	serviceRequest := user.LoginRequest{
-		Email:    loginReq.Email,
-		Password: loginReq.Password,
+		Email:      loginReq.Email,
+		Password:   loginReq.Password,
+		RememberMe: loginReq.RememberMe,
+		Stateless:  tokenDelivery(r) == tokenDeliveryJWT,
+		IP:         h.clientIP.ClientIP(r),
+		UserAgent:  r.UserAgent(),
 	}
 
 	serviceResponse, err := h.loginService.Login(ctx, serviceRequest)
 	if err != nil {
+		if writeOverloadedError(w, err) {
+			return
+		}
+		var throttled *user.ThrottledError
 		switch {
+		case errors.As(err, &throttled):
+			helpers.SetRetryAfter(w, throttled.RetryAfter)
+			helpers.WriteError(w, http.StatusTooManyRequests, "too many login attempts")
+			return
 		case errors.Is(err, user.ErrEmailRequired) || errors.Is(err, user.ErrPasswordRequired) ||
-			errors.Is(err, user.ErrEmailIncorrect):
+			errors.Is(err, user.ErrEmailIncorrect) || errors.Is(err, user.ErrStatelessDisabled):
 			helpers.WriteError(w, http.StatusBadRequest, err.Error())
 			return
 		case errors.Is(err, user.ErrPasswordIncorrect) || errors.Is(err, user.ErrUserNotFound):
 			helpers.WriteError(w, http.StatusUnauthorized, "invalid credentials")
 			return
+		case errors.Is(err, user.ErrEmailNotVerified):
+			helpers.WriteError(w, http.StatusForbidden, err.Error())
+			return
 		default:
 			h.logger.Printf("login: internal error: %v", err)
 			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
@@ -142,39 +169,139 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.writeLogin(w, r, serviceResponse)
+}
+
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var twoFactorReq LoginTwoFactorRequest
+	err := helpers.DecodeJSON(r, &twoFactorReq)
+	if err != nil {
+		h.logger.Printf("login 2fa: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	serviceResponse, err := h.loginService.CompleteTwoFactor(r.Context(), user.CompleteTwoFactorRequest{
+		Challenge: twoFactorReq.Challenge,
+		Code:      twoFactorReq.Code,
+		Stateless: tokenDelivery(r) == tokenDeliveryJWT,
+		IP:        h.clientIP.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrTwoFactorCodeRequired) || errors.Is(err, user.ErrStatelessDisabled):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, user.ErrTwoFactorCodeInvalid) || errors.Is(err, user.ErrTwoFactorChallengeInvalid) ||
+			errors.Is(err, user.ErrTwoFactorNotEnrolled) || errors.Is(err, user.ErrUserNotFound):
+			helpers.WriteError(w, http.StatusUnauthorized, "invalid code")
+			return
+		default:
+			h.logger.Printf("login 2fa: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	h.writeLogin(w, r, serviceResponse)
+}
+
+// SendMagicLink answers 202 whatever happens past decoding, so that the
+// response does not reveal whether the email is registered.
+func (h *UserHandler) SendMagicLink(w http.ResponseWriter, r *http.Request) {
+	var magicReq MagicLinkRequest
+	err := helpers.DecodeJSON(r, &magicReq)
+	if err != nil {
+		h.logger.Printf("magic link: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	_, err = h.magicLinkService.Send(r.Context(), user.SendMagicLinkRequest{Email: magicReq.Email})
+	if err != nil && !errors.Is(err, user.ErrEmailRequired) && !errors.Is(err, user.ErrEmailIncorrect) {
+		h.logger.Printf("magic link: internal error: %v", err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *UserHandler) MagicLinkLogin(w http.ResponseWriter, r *http.Request) {
+	serviceResponse, err := h.magicLinkService.Login(r.Context(), user.MagicLinkLoginRequest{
+		Token:     chi.URLParam(r, "token"),
+		Stateless: tokenDelivery(r) == tokenDeliveryJWT,
+		IP:        h.clientIP.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrStatelessDisabled):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, user.ErrOneTimeTokenInvalid):
+			helpers.WriteError(w, http.StatusUnauthorized, "invalid or expired link")
+			return
+		default:
+			h.logger.Printf("magic link login: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	h.writeLogin(w, r, serviceResponse)
+}
+
+// writeLogin delivers the credentials of a completed login the way the
+// client asked for them, or the challenge of a login that waits for a second
+// factor.
+func (h *UserHandler) writeLogin(w http.ResponseWriter, r *http.Request, serviceResponse user.LoginResponse) {
+	if pending, ok := serviceResponse.Pending.Get(); ok {
+		err := helpers.WriteJSON(w, http.StatusAccepted, TwoFactorRequiredResponse{
+			TwoFactorRequired: true,
+			Challenge:         pending.Challenge,
+			ExpiresAt:         pending.ExpiresAt,
+		})
+		if err != nil {
+			h.logger.Printf("login: write response failed: %v", err)
+		}
+		return
+	}
+
 	session := serviceResponse.Session
 	user := serviceResponse.User
 
 	loginResp := LoginResponse{
-		User: UserDTO{
-			ID:       user.ID,
-			UserName: user.Username,
-			Email:    user.Email,
-		},
+		User: newUserDTO(user),
 	}
 
-	h.setSessionCookie(w, session)
+	switch tokenDelivery(r) {
+	case tokenDeliveryJWT:
+		loginResp.Token = newTokenPairDTO(serviceResponse.Tokens)
+	case tokenDeliveryBody:
+		loginResp.Token = &TokenDTO{
+			AccessToken: session.ID,
+			TokenType:   "Bearer",
+			ExpiresAt:   session.ExpiresAt,
+		}
+	default:
+		h.setSessionCookie(w, session)
+	}
 
-	err = helpers.WriteJSON(w, http.StatusOK, loginResp)
+	err := helpers.WriteJSON(w, http.StatusOK, loginResp)
 	if err != nil {
 		h.logger.Printf("login: write response failed: %v", err)
 	}
 }
 
 func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session_id")
-	if err != nil {
-		if errors.Is(err, http.ErrNoCookie) {
-			h.clearSessionCookie(w)
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		h.logger.Printf("logout: read cookie failed: %v", err)
-		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+	sessionID, ok := middleware.SessionIDFromContext(r.Context())
+	if !ok {
+		h.clearSessionCookie(w)
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	if err := h.loginService.SessionStore.Delete(r.Context(), cookie.Value); err != nil {
+	if err := h.loginService.SessionStore.Delete(r.Context(), sessionID); err != nil {
 		if !errors.Is(err, user.ErrSessionNotFound) && !errors.Is(err, user.ErrSessionExpired) {
 			h.logger.Printf("logout: delete session failed: %v", err)
 			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
@@ -207,22 +334,27 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 	username := updateReq.UserName
 	password := updateReq.Password
 
+	sessionID, _ := middleware.SessionIDFromContext(ctx)
+
 	serviceRequest := user.UpdateRequest{
-		ID: userID,
+		ID:        userID,
+		SessionID: sessionID,
 	}
-	switch {
-	case email != nil:
+	if email != nil {
 		serviceRequest.Email = mo.Some(*email)
-		fallthrough
-	case username != nil:
+	}
+	if username != nil {
 		serviceRequest.Username = mo.Some(*username)
-		fallthrough
-	case password != nil:
+	}
+	if password != nil {
 		serviceRequest.Password = mo.Some(*password)
 	}
 
 	serviceResp, err := h.updateService.Update(ctx, serviceRequest)
 	if err != nil {
+		if writePasswordPolicyError(w, err) || writeOverloadedError(w, err) {
+			return
+		}
 		if errors.Is(err, user.ErrUserNotFound) {
 			helpers.WriteError(w, http.StatusUnauthorized, "invalid credentials")
 			return
@@ -233,11 +365,7 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	user := serviceResp.User
 	updateReps := UpdateResponse{
-		User: UserDTO{
-			ID:       user.ID,
-			UserName: user.Username,
-			Email:    user.Email,
-		},
+		User: newUserDTO(user),
 	}
 	
 	err = helpers.WriteJSON(w, http.StatusOK, updateReps)
@@ -274,3 +402,111 @@ func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("list sessions: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+	currentSessionID, _ := middleware.SessionIDFromContext(ctx)
+
+	serviceResp, err := h.sessionService.List(ctx, user.ListSessionsRequest{UserID: userID})
+	if err != nil {
+		h.logger.Printf("list sessions: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	listResp := ListSessionsResponse{
+		Sessions: make([]SessionDTO, 0, len(serviceResp.Sessions)),
+	}
+	for _, session := range serviceResp.Sessions {
+		listResp.Sessions = append(listResp.Sessions, SessionDTO{
+			ID:         user.SessionHandle(session.ID),
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Persistent: session.Persistent,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	err = helpers.WriteJSON(w, http.StatusOK, listResp)
+	if err != nil {
+		h.logger.Printf("list sessions: write response failed: %v", err)
+	}
+}
+
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("revoke session: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+	currentSessionID, _ := middleware.SessionIDFromContext(ctx)
+
+	handle := chi.URLParam(r, "id")
+	_, err := h.sessionService.Revoke(ctx, user.RevokeSessionRequest{
+		UserID: userID,
+		Handle: handle,
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrSessionNotFound) {
+			helpers.WriteError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		h.logger.Printf("revoke session: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	if currentSessionID != "" && handle == user.SessionHandle(currentSessionID) {
+		h.clearSessionCookie(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("revoke all sessions: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	_, err := h.sessionService.RevokeAll(ctx, user.RevokeAllSessionsRequest{UserID: userID})
+	if err != nil {
+		h.logger.Printf("revoke all sessions: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	h.clearSessionCookie(w)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newUserDTO(user entities.User) UserDTO {
+	return UserDTO{
+		ID:            user.ID,
+		UserName:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.IsPresent(),
+	}
+}
+
+func tokenDelivery(r *http.Request) string {
+	if v := r.URL.Query().Get(tokenDeliveryParam); v != "" {
+		return v
+	}
+	return r.Header.Get(tokenDeliveryHeader)
+}
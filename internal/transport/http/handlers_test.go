@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"crud/internal/adapters/password"
+	sessionMemory "crud/internal/adapters/session/memory"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"crud/internal/transport/http/helpers"
+	"crud/internal/transport/http/middleware"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type updateUserRepo struct {
+	fakeUserRepo
+	updates []entities.UserUpdateAttrs
+}
+
+func (r *updateUserRepo) Update(ctx context.Context, attrs entities.UserUpdateAttrs, filter entities.UserFilterAttrs, ent *entities.User) error {
+	r.updates = append(r.updates, attrs)
+	return r.FindOne(ctx, filter, ent)
+}
+
+func TestUpdate_PartialBodies(t *testing.T) {
+	cases := map[string]struct {
+		body  string
+		check func(attrs entities.UserUpdateAttrs) bool
+	}{
+		"email only": {
+			body: `{"email":"new@example.com"}`,
+			check: func(attrs entities.UserUpdateAttrs) bool {
+				return attrs.Email.OrEmpty() == "new@example.com" && attrs.Username.IsAbsent() && attrs.HashedPassword.IsAbsent()
+			},
+		},
+		"password only": {
+			body: `{"password":"long enough passphrase"}`,
+			check: func(attrs entities.UserUpdateAttrs) bool {
+				return attrs.HashedPassword.IsPresent() && attrs.Email.IsAbsent() && attrs.Username.IsAbsent()
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			sessions, _ := sessionMemory.NewMemoryStore(user.SessionLifetime{IdleTimeout: time.Hour}, nil)
+			session, _ := sessions.Create(context.Background(), user.SessionAttrs{UserID: "user-1"})
+			users := &updateUserRepo{fakeUserRepo: fakeUserRepo{users: []entities.User{{
+				ID:       "user-1",
+				Username: "demo",
+				Email:    "demo@example.com",
+			}}}}
+			updates := user.NewUpdateService(users, password.NewBcryptHasher(4), sessions)
+			handler := NewUserHandler(nil, nil, updates, nil, nil, nil, nil, nil, log.New(io.Discard, "", 0))
+			auth, _ := middleware.NewAuthMiddleware(sessions, nil, nil, middleware.AuthConfig{})
+
+			req := httptest.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(c.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: helpers.SessionCookieName, Value: session.ID})
+			rec := httptest.NewRecorder()
+			auth.RequireAuth(http.HandlerFunc(handler.Update)).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			if len(users.updates) != 1 || !c.check(users.updates[0]) {
+				t.Fatalf("unexpected update: %+v", users.updates)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("failed to create resolver: %v", err)
+	}
+
+	cases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expectedIP   string
+	}{
+		{"direct client", "203.0.113.7:5555", nil, "203.0.113.7"},
+		{"untrusted peer is not believed", "203.0.113.7:5555", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:5555", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"proxy chain", "192.0.2.1:5555", []string{"198.51.100.1, 10.0.0.5"}, "198.51.100.1"},
+		{"spoofed leftmost entry", "10.1.2.3:5555", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"multiple headers", "10.1.2.3:5555", []string{"198.51.100.1", "10.0.0.5"}, "198.51.100.1"},
+		{"garbage stops the walk", "10.1.2.3:5555", []string{"198.51.100.1, junk"}, "10.1.2.3"},
+		{"only proxies", "10.1.2.3:5555", []string{"10.0.0.5"}, "10.0.0.5"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if ip := resolver.ClientIP(req); ip != tc.expectedIP {
+				t.Fatalf("expected %s, got %s", tc.expectedIP, ip)
+			}
+		})
+	}
+}
+
+func TestClientIPResolver_InvalidProxy(t *testing.T) {
+	if _, err := NewClientIPResolver([]string{"not-an-ip"}); err == nil {
+		t.Fatalf("expected error for invalid proxy")
+	}
+}
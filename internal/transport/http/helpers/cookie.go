@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"net/http"
+	"time"
+)
+
+const SessionCookieName = "session_id"
+
+// SetSessionCookie writes the session cookie. Only persistent sessions get an
+// Expires attribute; the rest are browser-session cookies that are dropped
+// when the browser is closed.
+func SetSessionCookie(w http.ResponseWriter, sessionID string, expiresAt time.Time, persistent bool) {
+	cookie := &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if persistent {
+		cookie.Expires = expiresAt
+	}
+	http.SetCookie(w, cookie)
+}
+
+func ClearSessionCookie(w http.ResponseWriter) {
+	cookie := &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, cookie)
+}
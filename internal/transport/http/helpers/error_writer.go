@@ -2,7 +2,10 @@ package helpers
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func WriteError(w http.ResponseWriter, status int, msg string) {
@@ -10,3 +13,10 @@ func WriteError(w http.ResponseWriter, status int, msg string) {
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
+
+// SetRetryAfter sets Retry-After in whole seconds, rounded up so that clients
+// honouring it do not come back too early.
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := max(int64(math.Ceil(d.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
@@ -5,49 +5,232 @@ import (
 	"crud/internal/services/user"
 	httpapi "crud/internal/transport/http/helpers"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey     contextKey = "userID"
+	sessionIDKey  contextKey = "sessionID"
+	authSourceKey contextKey = "authSource"
+	scopesKey     contextKey = "scopes"
+)
+
+const (
+	SourceCookie = "cookie"
+	SourceBearer = "bearer"
+)
+
+type AuthConfig struct {
+	// Sources lists where the session token is looked up, in order of
+	// preference. The first source that carries a credential wins.
+	Sources []string
+	// TouchInterval bounds how often the last-seen time of a session is
+	// written back to the store.
+	TouchInterval time.Duration
+}
+
+type AccessTokenVerifier interface {
+	Verify(ctx context.Context, token string) (user.AccessClaims, error)
+}
+
+type PersonalTokenAuthenticator interface {
+	Authenticate(ctx context.Context, req user.AuthenticatePersonalTokenRequest) (user.AuthenticatePersonalTokenResponse, error)
+}
 
 type AuthMiddleware struct {
-	sessionStore user.SessionStore
+	sessionStore   user.SessionStore
+	accessTokens   AccessTokenVerifier
+	personalTokens PersonalTokenAuthenticator
+	sources        []string
+	touchInterval  time.Duration
 }
 
-func NewAuthMiddleware(sessionStore user.SessionStore) *AuthMiddleware {
-	return &AuthMiddleware{
-		sessionStore: sessionStore,
+// NewAuthMiddleware creates the middleware. The last-seen time of a session is
+// written at most once per touch interval to keep the store write load bounded;
+// each write also slides the session expiry and refreshes the cookie.
+// Bearer JWTs are verified with accessTokens and bearer personal access tokens
+// with personalTokens; pass nil for either to reject that kind of token.
+func NewAuthMiddleware(sessionStore user.SessionStore, accessTokens AccessTokenVerifier, personalTokens PersonalTokenAuthenticator, cfg AuthConfig) (*AuthMiddleware, error) {
+	sources := cfg.Sources
+	if sources == nil {
+		sources = []string{SourceCookie, SourceBearer}
+	}
+	if len(sources) == 0 {
+		return nil, ErrNoAuthSources
+	}
+	for _, source := range sources {
+		if source != SourceCookie && source != SourceBearer {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownAuthSource, source)
+		}
 	}
+
+	return &AuthMiddleware{
+		sessionStore:   sessionStore,
+		accessTokens:   accessTokens,
+		personalTokens: personalTokens,
+		sources:        sources,
+		touchInterval:  cfg.TouchInterval,
+	}, nil
 }
 
 func (s *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_id")
+		source, sessionID, err := s.credentials(r)
 		if err != nil {
-			if errors.Is(err, http.ErrNoCookie) {
-				httpapi.WriteError(w, http.StatusUnauthorized, "missing session")
+			httpapi.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if sessionID == "" {
+			httpapi.WriteError(w, http.StatusUnauthorized, "missing session")
+			return
+		}
+
+		ctx := r.Context()
+		if source == SourceBearer && s.accessTokens != nil && isJWT(sessionID) {
+			claims, err := s.accessTokens.Verify(ctx, sessionID)
+			if err != nil {
+				httpapi.WriteError(w, 401, "Not authorized")
+				return
+			}
+			ctx = context.WithValue(ctx, userIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, authSourceKey, source)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		if source == SourceBearer && strings.HasPrefix(sessionID, user.PersonalTokenPrefix) {
+			if s.personalTokens == nil {
+				httpapi.WriteError(w, 401, "Not authorized")
+				return
+			}
+			resp, err := s.personalTokens.Authenticate(ctx, user.AuthenticatePersonalTokenRequest{Secret: sessionID})
+			if err != nil {
+				httpapi.WriteError(w, 401, "Not authorized")
 				return
 			}
-			httpapi.WriteError(w, http.StatusBadRequest, "invalid cookie")
+			ctx = context.WithValue(ctx, userIDKey, resp.Token.UserID)
+			ctx = context.WithValue(ctx, authSourceKey, source)
+			ctx = context.WithValue(ctx, scopesKey, resp.Token.Scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		ctx := r.Context()
-		sessionID := cookie.Value
 		session, err := s.sessionStore.Get(ctx, sessionID)
 		if err != nil {
 			httpapi.WriteError(w, 401, "Not authorized")
 			return
 		}
+
+		timeNow := time.Now().UTC()
+		if timeNow.Sub(session.LastSeenAt) >= s.touchInterval {
+			touched, err := s.sessionStore.Touch(ctx, sessionID, timeNow)
+			switch {
+			case errors.Is(err, user.ErrSessionNotFound) || errors.Is(err, user.ErrSessionExpired):
+				httpapi.WriteError(w, 401, "Not authorized")
+				return
+			case err == nil:
+				session = touched
+				if source == SourceCookie {
+					httpapi.SetSessionCookie(w, session.ID, session.ExpiresAt, session.Persistent)
+				}
+			}
+		}
+
 		userID := session.UserID
 		ctx = context.WithValue(ctx, userIDKey, userID)
+		ctx = context.WithValue(ctx, sessionIDKey, session.ID)
+		ctx = context.WithValue(ctx, authSourceKey, source)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireScope rejects requests authenticated with a personal access token
+// that was not granted scope. Sessions and access tokens carry no scopes and
+// are always let through.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := ScopesFromContext(r.Context())
+			if ok && !slices.Contains(scopes, scope) {
+				httpapi.WriteError(w, http.StatusForbidden, "insufficient scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RejectPersonalTokens keeps personal access tokens away from routes that
+// manage them, so a leaked token cannot mint new ones.
+func RejectPersonalTokens(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ScopesFromContext(r.Context()); ok {
+			httpapi.WriteError(w, http.StatusForbidden, "personal access tokens are not allowed here")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// credentials returns the first session token found in the configured
+// sources. An empty token means the request carries no credentials.
+func (s *AuthMiddleware) credentials(r *http.Request) (string, string, error) {
+	for _, source := range s.sources {
+		switch source {
+		case SourceCookie:
+			cookie, err := r.Cookie(httpapi.SessionCookieName)
+			if errors.Is(err, http.ErrNoCookie) {
+				continue
+			}
+			if err != nil || cookie.Value == "" {
+				return "", "", errors.New("invalid cookie")
+			}
+			return source, cookie.Value, nil
+		case SourceBearer:
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				continue
+			}
+			scheme, token, ok := strings.Cut(header, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+				return "", "", errors.New("invalid authorization header")
+			}
+			return source, strings.TrimSpace(token), nil
+		}
+	}
+	return "", "", nil
+}
+
+// isJWT tells signed access tokens apart from opaque session IDs.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
 func UserIDFromContext(ctx context.Context) (string, bool) {
 	id, ok := ctx.Value(userIDKey).(string)
 	return id, ok
 }
+
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionIDKey).(string)
+	return id, ok
+}
+
+// AuthSourceFromContext reports where the credentials of the request came
+// from, one of SourceCookie or SourceBearer.
+func AuthSourceFromContext(ctx context.Context) (string, bool) {
+	source, ok := ctx.Value(authSourceKey).(string)
+	return source, ok
+}
+
+// ScopesFromContext returns the scopes of the personal access token that
+// authenticated the request. ok is false for any other kind of credentials.
+func ScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopesKey).([]string)
+	return scopes, ok
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"context"
+	"crud/internal/adapters/jwt"
+	"crud/internal/adapters/session/memory"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"crud/internal/transport/http/helpers"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestAuth(t *testing.T, sources []string) (*AuthMiddleware, *memory.MemoryStore) {
+	t.Helper()
+
+	store, err := memory.NewMemoryStore(user.SessionLifetime{IdleTimeout: time.Hour}, nil)
+	if err != nil {
+		t.Fatalf("failed to create MemoryStore: %v", err)
+	}
+	auth, err := NewAuthMiddleware(store, nil, nil, AuthConfig{Sources: sources, TouchInterval: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create AuthMiddleware: %v", err)
+	}
+	return auth, store
+}
+
+func serve(auth *AuthMiddleware, req *http.Request) (*httptest.ResponseRecorder, string, string) {
+	var userID, source string
+	handler := auth.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ = UserIDFromContext(r.Context())
+		source, _ = AuthSourceFromContext(r.Context())
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, userID, source
+}
+
+func TestRequireAuth_Sources(t *testing.T) {
+	auth, store := newTestAuth(t, nil)
+	ctx := context.Background()
+	cookieSession, _ := store.Create(ctx, user.SessionAttrs{UserID: "cookie-user"})
+	bearerSession, _ := store.Create(ctx, user.SessionAttrs{UserID: "bearer-user"})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+bearerSession.ID)
+	rec, userID, source := serve(auth, req)
+	if rec.Code != http.StatusOK || userID != "bearer-user" || source != SourceBearer {
+		t.Fatalf("bearer auth failed: %d %q %q", rec.Code, userID, source)
+	}
+
+	req.AddCookie(&http.Cookie{Name: helpers.SessionCookieName, Value: cookieSession.ID})
+	rec, userID, source = serve(auth, req)
+	if rec.Code != http.StatusOK || userID != "cookie-user" || source != SourceCookie {
+		t.Fatalf("cookie should take precedence: %d %q %q", rec.Code, userID, source)
+	}
+
+	bearerFirst, _ := NewAuthMiddleware(store, nil, nil, AuthConfig{Sources: []string{SourceBearer, SourceCookie}})
+	rec, userID, source = serve(bearerFirst, req)
+	if rec.Code != http.StatusOK || userID != "bearer-user" || source != SourceBearer {
+		t.Fatalf("bearer should take precedence: %d %q %q", rec.Code, userID, source)
+	}
+}
+
+func TestRequireAuth_Rejects(t *testing.T) {
+	auth, _ := newTestAuth(t, []string{SourceCookie})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer whatever")
+	if rec, _, _ := serve(auth, req); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bearer must be ignored when not configured, got %d", rec.Code)
+	}
+
+	auth, _ = newTestAuth(t, nil)
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	if rec, _, _ := serve(auth, req); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for non-bearer scheme, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer unknown")
+	if rec, _, _ := serve(auth, req); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unknown token, got %d", rec.Code)
+	}
+}
+
+func TestNewAuthMiddleware_UnknownSource(t *testing.T) {
+	_, err := NewAuthMiddleware(nil, nil, nil, AuthConfig{Sources: []string{"header"}})
+	if !errors.Is(err, ErrUnknownAuthSource) {
+		t.Fatalf("expected ErrUnknownAuthSource, got: %v", err)
+	}
+}
+
+func TestRequireAuth_AccessToken(t *testing.T) {
+	store, _ := memory.NewMemoryStore(user.SessionLifetime{IdleTimeout: time.Hour}, nil)
+	key, err := jwt.GenerateKey("k1")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keys, _ := jwt.NewKeySet([]jwt.Key{key}, "")
+	accessTokens := jwt.NewAccessTokens(keys, "crud", time.Minute)
+	auth, _ := NewAuthMiddleware(store, accessTokens, nil, AuthConfig{})
+
+	issued, err := accessTokens.Issue(context.Background(), "jwt-user")
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.Token)
+	rec, userID, source := serve(auth, req)
+	if rec.Code != http.StatusOK || userID != "jwt-user" || source != SourceBearer {
+		t.Fatalf("jwt auth failed: %d %q %q", rec.Code, userID, source)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+issued.Token+"x")
+	if rec, _, _ := serve(auth, req); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for tampered token, got %d", rec.Code)
+	}
+}
+
+type personalTokensStub struct {
+	tokens map[string]entities.PersonalAccessToken
+}
+
+func (s *personalTokensStub) Authenticate(ctx context.Context, req user.AuthenticatePersonalTokenRequest) (user.AuthenticatePersonalTokenResponse, error) {
+	token, ok := s.tokens[req.Secret]
+	if !ok {
+		return user.AuthenticatePersonalTokenResponse{}, user.ErrPersonalTokenInvalid
+	}
+	return user.AuthenticatePersonalTokenResponse{Token: token}, nil
+}
+
+func TestRequireAuth_PersonalToken(t *testing.T) {
+	store, _ := memory.NewMemoryStore(user.SessionLifetime{IdleTimeout: time.Hour}, nil)
+	personalTokens := &personalTokensStub{tokens: map[string]entities.PersonalAccessToken{
+		"pat_reader": {UserID: "pat-user", Scopes: []string{user.ScopeSessionsRead}},
+	}}
+	auth, _ := NewAuthMiddleware(store, nil, personalTokens, AuthConfig{})
+
+	var scopes []string
+	handler := auth.RequireAuth(RequireScope(user.ScopeSessionsRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scopes, _ = ScopesFromContext(r.Context())
+	})))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer pat_reader")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || len(scopes) != 1 {
+		t.Fatalf("personal token auth failed: %d %v", rec.Code, scopes)
+	}
+
+	handler = auth.RequireAuth(RequireScope(user.ScopeProfileWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for missing scope, got %d", rec.Code)
+	}
+
+	handler = auth.RequireAuth(RejectPersonalTokens(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 on routes closed to personal tokens, got %d", rec.Code)
+	}
+
+	req.Header.Set("Authorization", "Bearer pat_unknown")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unknown token, got %d", rec.Code)
+	}
+
+	session, _ := store.Create(context.Background(), user.SessionAttrs{UserID: "session-user"})
+	handler = auth.RequireAuth(RequireScope(user.ScopeProfileWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	req.Header.Set("Authorization", "Bearer "+session.ID)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sessions must not be restricted by scopes, got %d", rec.Code)
+	}
+}
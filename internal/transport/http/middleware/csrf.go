@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	httpapi "crud/internal/transport/http/helpers"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// csrfTokenLength is the length of an encoded token, 32 random bytes.
+var csrfTokenLength = base64.RawURLEncoding.EncodedLen(32)
+
+type CSRFConfig struct {
+	// TrustedOrigins may send cross-site requests besides the service's own
+	// origin, e.g. "https://app.example.com".
+	TrustedOrigins []string
+	// DoubleSubmit also requires the X-CSRF-Token header to repeat the
+	// csrf_token cookie handed out by the token endpoint.
+	DoubleSubmit bool
+}
+
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+// CSRFProtection rejects state-changing requests that a browser sent from a
+// page of another site with the session cookie attached. Requests
+// authenticated with a bearer token are left alone, since browsers never
+// add those on their own.
+type CSRFProtection struct {
+	origins      map[string]struct{}
+	doubleSubmit bool
+}
+
+func NewCSRFProtection(cfg CSRFConfig) (*CSRFProtection, error) {
+	p := &CSRFProtection{origins: make(map[string]struct{}, len(cfg.TrustedOrigins)), doubleSubmit: cfg.DoubleSubmit}
+	for _, origin := range cfg.TrustedOrigins {
+		normalized, ok := normalizeOrigin(origin)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidOrigin, origin)
+		}
+		p.origins[normalized] = struct{}{}
+	}
+	return p, nil
+}
+
+// Handler has to run after RequireAuth, which tells it how the request was
+// authenticated. A nil CSRFProtection lets every request through.
+func (p *CSRFProtection) Handler(next http.Handler) http.Handler {
+	if p == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source, _ := AuthSourceFromContext(r.Context())
+		if source != SourceCookie || isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !p.sameSite(r) {
+			httpapi.WriteError(w, http.StatusForbidden, "cross-site request rejected")
+			return
+		}
+		if p.doubleSubmit && !validCSRFToken(r) {
+			httpapi.WriteError(w, http.StatusForbidden, "csrf token missing or invalid")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Token answers with the CSRF token of the browser, issuing one in the
+// csrf_token cookie if it has none yet. The token stays the same across
+// calls so that several open tabs can share it.
+func (p *CSRFProtection) Token(w http.ResponseWriter, r *http.Request) {
+	token := ""
+	if cookie, err := r.Cookie(CSRFCookieName); err == nil && len(cookie.Value) == csrfTokenLength {
+		token = cookie.Value
+	} else {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			httpapi.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		token = base64.RawURLEncoding.EncodeToString(buf)
+		http.SetCookie(w, &http.Cookie{
+			Name:     CSRFCookieName,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   false,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	_ = httpapi.WriteJSON(w, http.StatusOK, tokenResponse{Token: token})
+}
+
+// sameSite trusts Sec-Fetch-Site where the browser sends it and the Origin
+// header otherwise. Requests with neither come from clients that are no
+// browsers, or too old to be a concern, and pass.
+func (p *CSRFProtection) sameSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+		if r.Header.Get("Origin") == "" {
+			return true
+		}
+	}
+
+	origin, ok := normalizeOrigin(r.Header.Get("Origin"))
+	if !ok {
+		return false
+	}
+	if _, trusted := p.origins[origin]; trusted {
+		return true
+	}
+	// Behind a proxy the scheme of the service is unknown, so its own origin
+	// is matched by host alone.
+	_, host, _ := strings.Cut(origin, "://")
+	return strings.EqualFold(host, r.Host)
+}
+
+func validCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(CSRFHeaderName)
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) == 1
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// normalizeOrigin lowercases scheme://host[:port] and rejects anything else,
+// "null" included.
+func normalizeOrigin(origin string) (string, bool) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", false
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), true
+}
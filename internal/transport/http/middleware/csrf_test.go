@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveCSRF(p *CSRFProtection, req *http.Request, source string) int {
+	if source != "" {
+		req = req.WithContext(context.WithValue(req.Context(), authSourceKey, source))
+	}
+	handler := p.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestCSRF_Origin(t *testing.T) {
+	p, err := NewCSRFProtection(CSRFConfig{TrustedOrigins: []string{"https://App.example.com/"}})
+	if err != nil {
+		t.Fatalf("NewCSRFProtection failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		source  string
+		headers map[string]string
+		want    int
+	}{
+		{"safe method", "GET", SourceCookie, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusOK},
+		{"bearer token", "DELETE", SourceBearer, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusOK},
+		{"same origin", "PATCH", SourceCookie, map[string]string{"Sec-Fetch-Site": "same-origin"}, http.StatusOK},
+		{"cross site", "PATCH", SourceCookie, map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, http.StatusForbidden},
+		{"same site subdomain", "PATCH", SourceCookie, map[string]string{"Sec-Fetch-Site": "same-site", "Origin": "https://blog.example.com"}, http.StatusForbidden},
+		{"trusted origin", "PATCH", SourceCookie, map[string]string{"Sec-Fetch-Site": "same-site", "Origin": "https://app.example.com"}, http.StatusOK},
+		{"own host by origin", "DELETE", SourceCookie, map[string]string{"Origin": "http://example.com"}, http.StatusOK},
+		{"foreign origin", "DELETE", SourceCookie, map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"null origin", "DELETE", SourceCookie, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"no browser headers", "DELETE", SourceCookie, nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/users/me", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := serveCSRF(p, req, tt.source); got != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCSRF_DoubleSubmit(t *testing.T) {
+	p, _ := NewCSRFProtection(CSRFConfig{DoubleSubmit: true})
+
+	rec := httptest.NewRecorder()
+	p.Token(rec, httptest.NewRequest("GET", "/users/csrf-token", nil))
+	var body tokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Token == "" {
+		t.Fatalf("token endpoint failed: %d %v", rec.Code, err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != CSRFCookieName || cookies[0].Value != body.Token {
+		t.Fatalf("token must be set in the %s cookie, got %+v", CSRFCookieName, cookies)
+	}
+
+	again := httptest.NewRequest("GET", "/users/csrf-token", nil)
+	again.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	p.Token(rec, again)
+	var second tokenResponse
+	_ = json.NewDecoder(rec.Body).Decode(&second)
+	if second.Token != body.Token || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("existing token must be reused")
+	}
+
+	req := httptest.NewRequest("PATCH", "/users/me", nil)
+	req.AddCookie(cookies[0])
+	if got := serveCSRF(p, req, SourceCookie); got != http.StatusForbidden {
+		t.Fatalf("missing header: expected 403, got %d", got)
+	}
+	req.Header.Set(CSRFHeaderName, "forged")
+	if got := serveCSRF(p, req, SourceCookie); got != http.StatusForbidden {
+		t.Fatalf("wrong header: expected 403, got %d", got)
+	}
+	req.Header.Set(CSRFHeaderName, body.Token)
+	if got := serveCSRF(p, req, SourceCookie); got != http.StatusOK {
+		t.Fatalf("matching header: expected 200, got %d", got)
+	}
+
+	bearer := httptest.NewRequest("PATCH", "/users/me", nil)
+	if got := serveCSRF(p, bearer, SourceBearer); got != http.StatusOK {
+		t.Fatalf("bearer requests need no token, got %d", got)
+	}
+}
+
+func TestNewCSRFProtection_InvalidOrigin(t *testing.T) {
+	for _, origin := range []string{"app.example.com", "https://app.example.com/path", "null"} {
+		if _, err := NewCSRFProtection(CSRFConfig{TrustedOrigins: []string{origin}}); !errors.Is(err, ErrInvalidOrigin) {
+			t.Errorf("%s: expected ErrInvalidOrigin, got: %v", origin, err)
+		}
+	}
+}
+
+func TestCSRF_Nil(t *testing.T) {
+	var p *CSRFProtection
+	req := httptest.NewRequest("DELETE", "/users/me", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	if got := serveCSRF(p, req, SourceCookie); got != http.StatusOK {
+		t.Fatalf("nil protection must let requests through, got %d", got)
+	}
+}
@@ -0,0 +1,9 @@
+package middleware
+
+import "errors"
+
+var (
+	ErrUnknownAuthSource = errors.New("unknown auth source")
+	ErrNoAuthSources     = errors.New("no auth sources configured")
+	ErrInvalidOrigin     = errors.New("invalid origin")
+)
@@ -0,0 +1,9 @@
+package ratelimit
+
+import "errors"
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown rate limit algorithm")
+	ErrUnknownKey       = errors.New("unknown rate limit key")
+	ErrInvalidRule      = errors.New("rate limit needs a positive limit and period")
+)
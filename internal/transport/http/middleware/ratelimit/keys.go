@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"crud/internal/transport/http/middleware"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+const (
+	KeyIP    = "ip"
+	KeyUser  = "user"
+	KeyRoute = "route"
+)
+
+// KeyFunc picks the quota a request counts against. Requests it returns an
+// empty key for are not limited.
+type KeyFunc func(r *http.Request) string
+
+type ClientIPResolver interface {
+	ClientIP(r *http.Request) string
+}
+
+func ByIP(clientIP ClientIPResolver) KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + clientIP.ClientIP(r)
+	}
+}
+
+// ByUser keys by the authenticated user and so has to run after RequireAuth.
+func ByUser(r *http.Request) string {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	return "user:" + userID
+}
+
+// ByRoute shares one quota between all clients of a route. The pattern is the
+// one matched when the limiter runs, so a limiter used on a whole route group
+// shares the quota across the group.
+func ByRoute(r *http.Request) string {
+	pattern := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		pattern = rctx.RoutePattern()
+	}
+	return "route:" + r.Method + " " + pattern
+}
+
+func keyFunc(name string, clientIP ClientIPResolver) (KeyFunc, error) {
+	switch name {
+	case KeyIP:
+		return ByIP(clientIP), nil
+	case KeyUser:
+		return ByUser, nil
+	case KeyRoute:
+		return ByRoute, nil
+	default:
+		return nil, ErrUnknownKey
+	}
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"crud/internal/transport/http/helpers"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Store interface {
+	// Allow counts a request against the quota of key and reports whether it
+	// is within rule.
+	Allow(ctx context.Context, key string, rule Rule, now time.Time) (Result, error)
+}
+
+type Config struct {
+	// Name separates the quotas of limiters sharing a store.
+	Name      string
+	Algorithm string
+	Limit     int
+	Period    time.Duration
+	Burst     int
+	// Key is one of KeyIP, KeyUser or KeyRoute.
+	Key string
+}
+
+// Limiter is a middleware that answers 429 once a client has used up its
+// quota and reports the quota in the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers.
+type Limiter struct {
+	Name  string
+	Rule  Rule
+	Key   KeyFunc
+	Store Store
+	// Logger gets store failures, which let the request through.
+	Logger *log.Logger
+}
+
+func New(store Store, cfg Config, clientIP ClientIPResolver) (*Limiter, error) {
+	rule := Rule{Algorithm: cfg.Algorithm, Limit: cfg.Limit, Period: cfg.Period, Burst: cfg.Burst}
+	if err := rule.validate(); err != nil {
+		return nil, fmt.Errorf("rate limit %q: %w", cfg.Name, err)
+	}
+	key, err := keyFunc(cfg.Key, clientIP)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit %q: %w: %s", cfg.Name, err, cfg.Key)
+	}
+	return &Limiter{Name: cfg.Name, Rule: rule, Key: key, Store: store}, nil
+}
+
+// Handler limits next. A nil Limiter lets every request through, so that
+// routes can be wired the same way whether or not a limit is configured.
+func (l *Limiter) Handler(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.Key(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		res, err := l.Store.Allow(r.Context(), l.Name+":"+key, l.Rule, time.Now())
+		if err != nil {
+			if l.Logger != nil {
+				l.Logger.Printf("rate limit %s: %v", l.Name, err)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		l.writeHeaders(w, res)
+		if !res.Allowed {
+			helpers.SetRetryAfter(w, res.RetryAfter)
+			helpers.WriteError(w, http.StatusTooManyRequests, "too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *Limiter) writeHeaders(w http.ResponseWriter, res Result) {
+	policy := fmt.Sprintf("%d;w=%d", l.Rule.Limit, int64(l.Rule.Period.Seconds()))
+	if l.Rule.Algorithm == TokenBucket {
+		policy += fmt.Sprintf(";burst=%d", l.Rule.Capacity())
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(res.Reset.Seconds())), 10))
+	w.Header().Set("RateLimit-Policy", policy)
+}
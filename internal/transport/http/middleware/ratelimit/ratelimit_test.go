@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// windowStore keeps sliding windows in a map, like the memory adapter.
+type windowStore struct {
+	hits map[string][]time.Time
+	err  error
+}
+
+func (s *windowStore) Allow(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	if s.err != nil {
+		return Result{}, s.err
+	}
+	var res Result
+	s.hits[key], res = rule.TakeSlot(s.hits[key], now)
+	return res, nil
+}
+
+type remoteAddrIP struct{}
+
+func (remoteAddrIP) ClientIP(r *http.Request) string {
+	return r.RemoteAddr
+}
+
+func newTestLimiter(t *testing.T, store Store) http.Handler {
+	t.Helper()
+	limiter, err := New(store, Config{Name: "test", Algorithm: SlidingWindow, Limit: 2, Period: time.Minute, Key: KeyIP}, remoteAddrIP{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return limiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+}
+
+func request(handler http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users/register", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLimiter_Handler(t *testing.T) {
+	handler := newTestLimiter(t, &windowStore{hits: map[string][]time.Time{}})
+
+	rec := request(handler, "192.0.2.1")
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "2" ||
+		rec.Header().Get("RateLimit-Remaining") != "1" || rec.Header().Get("RateLimit-Reset") != "60" ||
+		rec.Header().Get("RateLimit-Policy") != "2;w=60" {
+		t.Fatalf("unexpected response: %d %v", rec.Code, rec.Header())
+	}
+	request(handler, "192.0.2.1")
+
+	rec = request(handler, "192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+
+	if rec := request(handler, "192.0.2.2"); rec.Code != http.StatusOK {
+		t.Fatalf("other clients must have their own quota, got %d", rec.Code)
+	}
+}
+
+func TestLimiter_StoreFailureLetsThrough(t *testing.T) {
+	handler := newTestLimiter(t, &windowStore{err: errors.New("redis down")})
+
+	if rec := request(handler, "192.0.2.1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the request to pass, got %d", rec.Code)
+	}
+}
+
+func TestNew_Validates(t *testing.T) {
+	cases := []struct {
+		cfg  Config
+		want error
+	}{
+		{Config{Algorithm: "leaky_bucket", Limit: 1, Period: time.Second, Key: KeyIP}, ErrUnknownAlgorithm},
+		{Config{Algorithm: TokenBucket, Period: time.Second, Key: KeyIP}, ErrInvalidRule},
+		{Config{Algorithm: TokenBucket, Limit: 1, Period: time.Second, Key: "session"}, ErrUnknownKey},
+	}
+	for _, c := range cases {
+		if _, err := New(&windowStore{}, c.cfg, remoteAddrIP{}); !errors.Is(err, c.want) {
+			t.Errorf("%+v: expected %v, got %v", c.cfg, c.want, err)
+		}
+	}
+
+	var limiter *Limiter
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if rec := request(limiter.Handler(next), "192.0.2.1"); rec.Code != http.StatusOK {
+		t.Fatalf("nil limiter must let requests through, got %d", rec.Code)
+	}
+}
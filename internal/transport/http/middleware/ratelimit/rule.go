@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// TokenBucket refills Limit tokens per Period up to Burst and lets short
+	// bursts through.
+	TokenBucket = "token_bucket"
+	// SlidingWindow allows Limit requests in any Period, counted exactly from
+	// the times of the previous requests.
+	SlidingWindow = "sliding_window"
+)
+
+type Rule struct {
+	Algorithm string
+	Limit     int
+	Period    time.Duration
+	// Burst is the bucket size of TokenBucket, Limit when zero.
+	Burst int
+}
+
+type Result struct {
+	Allowed bool
+	// Limit is the quota the client can use at once.
+	Limit     int
+	Remaining int
+	// Reset is when the quota is whole again.
+	Reset time.Duration
+	// RetryAfter is when the next request is allowed, zero if it is now.
+	RetryAfter time.Duration
+}
+
+func (r Rule) Capacity() int {
+	if r.Algorithm == TokenBucket && r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+// TokensPerMilli is the refill rate of TokenBucket.
+func (r Rule) TokensPerMilli() float64 {
+	return float64(r.Limit) / float64(r.Period.Milliseconds())
+}
+
+// BucketState is a token bucket as of UpdatedAt.
+type BucketState struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// TakeToken refills the bucket up to now and takes a token if there is one. A
+// zero state is a full bucket.
+func (r Rule) TakeToken(state BucketState, now time.Time) (BucketState, Result) {
+	capacity := float64(r.Capacity())
+	tokens := capacity
+	if !state.UpdatedAt.IsZero() {
+		elapsed := max(now.Sub(state.UpdatedAt).Milliseconds(), 0)
+		tokens = min(capacity, state.Tokens+float64(elapsed)*r.TokensPerMilli())
+	}
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	return BucketState{Tokens: tokens, UpdatedAt: now}, r.BucketResult(tokens, allowed)
+}
+
+// BucketResult describes a bucket left with tokens after a request.
+func (r Rule) BucketResult(tokens float64, allowed bool) Result {
+	rate := r.TokensPerMilli()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     r.Capacity(),
+		Remaining: int(math.Floor(tokens)),
+		Reset:     millis((float64(r.Capacity()) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = millis((1 - tokens) / rate)
+	}
+	return res
+}
+
+// TakeSlot drops requests older than Period from hits, which are in order,
+// and records now if fewer than Limit remain.
+func (r Rule) TakeSlot(hits []time.Time, now time.Time) ([]time.Time, Result) {
+	start := 0
+	for start < len(hits) && !hits[start].After(now.Add(-r.Period)) {
+		start++
+	}
+	hits = hits[start:]
+	allowed := len(hits) < r.Limit
+	if allowed {
+		hits = append(hits, now)
+	}
+	return hits, r.WindowResult(len(hits), hits[0], allowed, now)
+}
+
+// WindowResult describes a window holding count requests, the oldest at
+// oldest, after a request.
+func (r Rule) WindowResult(count int, oldest time.Time, allowed bool, now time.Time) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     r.Limit,
+		Remaining: max(r.Limit-count, 0),
+		Reset:     max(oldest.Add(r.Period).Sub(now), 0),
+	}
+	if !allowed {
+		res.RetryAfter = res.Reset
+	}
+	return res
+}
+
+func (r Rule) validate() error {
+	if r.Algorithm != TokenBucket && r.Algorithm != SlidingWindow {
+		return ErrUnknownAlgorithm
+	}
+	if r.Limit <= 0 || r.Period < time.Millisecond || r.Burst < 0 {
+		return ErrInvalidRule
+	}
+	return nil
+}
+
+func millis(ms float64) time.Duration {
+	return time.Duration(math.Ceil(ms)) * time.Millisecond
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRule_TakeToken(t *testing.T) {
+	rule := Rule{Algorithm: TokenBucket, Limit: 1, Period: time.Second, Burst: 3}
+	now := time.Now()
+
+	var state BucketState
+	var res Result
+	for i := 2; i >= 0; i-- {
+		state, res = rule.TakeToken(state, now)
+		if !res.Allowed || res.Remaining != i || res.Limit != 3 {
+			t.Fatalf("burst request must pass: %+v", res)
+		}
+	}
+	state, res = rule.TakeToken(state, now)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Fatalf("empty bucket must refuse: %+v", res)
+	}
+
+	_, res = rule.TakeToken(state, now.Add(time.Second))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("refilled token must pass: %+v", res)
+	}
+}
+
+func TestRule_TakeSlot(t *testing.T) {
+	rule := Rule{Algorithm: SlidingWindow, Limit: 2, Period: time.Minute}
+	now := time.Now()
+
+	hits, res := rule.TakeSlot(nil, now)
+	if !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("first request must pass: %+v", res)
+	}
+	hits, res = rule.TakeSlot(hits, now.Add(30*time.Second))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("second request must pass: %+v", res)
+	}
+	hits, res = rule.TakeSlot(hits, now.Add(40*time.Second))
+	if res.Allowed || res.RetryAfter != 20*time.Second {
+		t.Fatalf("third request must wait for the first to leave the window: %+v", res)
+	}
+
+	_, res = rule.TakeSlot(hits, now.Add(time.Minute))
+	if !res.Allowed || res.Remaining != 0 || res.Reset != 30*time.Second {
+		t.Fatalf("window must slide: %+v", res)
+	}
+}
@@ -0,0 +1,368 @@
+package http
+
+import (
+	"crud/internal/domain/entities"
+	"crud/internal/services/oidc"
+	helpers "crud/internal/transport/http/helpers"
+	"crud/internal/transport/http/middleware"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+type OIDCHandler struct {
+	provider      *oidc.Provider
+	clientService *oidc.ClientService
+	issuer        string
+	logger        *log.Logger
+}
+
+func NewOIDCHandler(provider *oidc.Provider, clientService *oidc.ClientService, issuer string, logger *log.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		provider:      provider,
+		clientService: clientService,
+		issuer:        strings.TrimSuffix(issuer, "/"),
+		logger:        logger,
+	}
+}
+
+func (h *OIDCHandler) Discovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	err := helpers.WriteJSON(w, http.StatusOK, OpenIDConfiguration{
+		Issuer:                            h.issuer,
+		AuthorizationEndpoint:             h.issuer + "/oauth/authorize",
+		TokenEndpoint:                     h.issuer + "/oauth/token",
+		UserInfoEndpoint:                  h.issuer + "/oauth/userinfo",
+		JWKSURI:                           h.issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   oidc.SupportedScopes,
+		ResponseTypesSupported:            []string{oidc.ResponseTypeCode},
+		GrantTypesSupported:               []string{oidc.GrantTypeAuthorization},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{oidc.CodeChallengeS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "email", "email_verified", "preferred_username"},
+	})
+	if err != nil {
+		h.logger.Printf("discovery: write response failed: %v", err)
+	}
+}
+
+// Authorize redirects back to the client right away when the user already
+// consented to the requested scopes. Otherwise it describes the request so
+// that the frontend can ask for consent and post the answer to Consent.
+func (h *OIDCHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("authorize: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	query := r.URL.Query()
+	serviceResp, err := h.provider.Authorize(ctx, oidc.AuthorizeRequest{
+		UserID:              userID,
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		ResponseType:        query.Get("response_type"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		Nonce:               query.Get("nonce"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	})
+	if err != nil {
+		h.writeAuthorizeError(w, "authorize", err)
+		return
+	}
+
+	if serviceResp.ConsentRequired {
+		err = helpers.WriteJSON(w, http.StatusOK, ConsentRequiredResponse{
+			ConsentRequired: true,
+			ClientID:        serviceResp.Client.ID,
+			ClientName:      serviceResp.Client.Name,
+			Scopes:          serviceResp.Scopes,
+		})
+		if err != nil {
+			h.logger.Printf("authorize: write response failed: %v", err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, serviceResp.RedirectTo, http.StatusFound)
+}
+
+func (h *OIDCHandler) Consent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("consent: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	var consentReq OAuthConsentRequest
+	err := helpers.DecodeJSON(r, &consentReq)
+	if err != nil {
+		h.logger.Printf("consent: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	serviceResp, err := h.provider.Consent(ctx, oidc.ConsentRequest{
+		AuthorizeRequest: oidc.AuthorizeRequest{
+			UserID:              userID,
+			ClientID:            consentReq.ClientID,
+			RedirectURI:         consentReq.RedirectURI,
+			ResponseType:        consentReq.ResponseType,
+			Scope:               consentReq.Scope,
+			State:               consentReq.State,
+			Nonce:               consentReq.Nonce,
+			CodeChallenge:       consentReq.CodeChallenge,
+			CodeChallengeMethod: consentReq.CodeChallengeMethod,
+		},
+		Approve: consentReq.Approve,
+	})
+	if err != nil {
+		h.writeAuthorizeError(w, "consent", err)
+		return
+	}
+
+	err = helpers.WriteJSON(w, http.StatusOK, OAuthRedirectResponse{RedirectTo: serviceResp.RedirectTo})
+	if err != nil {
+		h.logger.Printf("consent: write response failed: %v", err)
+	}
+}
+
+// Token exchanges an authorization code. Clients authenticate with HTTP
+// Basic, with client_secret in the form, or not at all when they are public.
+func (h *OIDCHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, oidc.ErrInvalidRequest)
+		return
+	}
+
+	clientID, clientSecret := r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	basicID, basicSecret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 form-encodes both values before Basic.
+		id, idErr := url.QueryUnescape(basicID)
+		secret, secretErr := url.QueryUnescape(basicSecret)
+		if idErr != nil || secretErr != nil || clientSecret != "" || (clientID != "" && clientID != id) {
+			writeOAuthError(w, http.StatusBadRequest, oidc.ErrInvalidRequest)
+			return
+		}
+		clientID, clientSecret = id, secret
+	}
+
+	serviceResp, err := h.provider.Exchange(r.Context(), oidc.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	})
+	if err != nil {
+		switch oidc.ErrorCode(err) {
+		case "invalid_client":
+			if basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			writeOAuthError(w, http.StatusUnauthorized, err)
+		case "server_error":
+			h.logger.Printf("token: internal error: %v", err)
+			writeOAuthError(w, http.StatusInternalServerError, err)
+		default:
+			writeOAuthError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	err = helpers.WriteJSON(w, http.StatusOK, OAuthTokenResponse{
+		AccessToken: serviceResp.AccessToken.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(serviceResp.AccessToken.ExpiresAt).Seconds()),
+		IDToken:     serviceResp.IDToken,
+		Scope:       strings.Join(serviceResp.Scopes, " "),
+	})
+	if err != nil {
+		h.logger.Printf("token: write response failed: %v", err)
+	}
+}
+
+func (h *OIDCHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing access token")
+		return
+	}
+
+	serviceResp, err := h.provider.UserInfo(r.Context(), oidc.UserInfoRequest{AccessToken: strings.TrimSpace(token)})
+	if err != nil {
+		if errors.Is(err, oidc.ErrAccessTokenInvalid) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeOAuthError(w, http.StatusUnauthorized, err)
+			return
+		}
+		h.logger.Printf("userinfo: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	claims := serviceResp.Claims
+	err = helpers.WriteJSON(w, http.StatusOK, UserInfoResponse{
+		Sub:               claims.Subject,
+		Email:             claims.Email.ToPointer(),
+		EmailVerified:     claims.EmailVerified.ToPointer(),
+		PreferredUsername: claims.PreferredUsername.ToPointer(),
+	})
+	if err != nil {
+		h.logger.Printf("userinfo: write response failed: %v", err)
+	}
+}
+
+func (h *OIDCHandler) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("register client: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	var registerReq RegisterOAuthClientRequest
+	err := helpers.DecodeJSON(r, &registerReq)
+	if err != nil {
+		h.logger.Printf("register client: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	serviceResp, err := h.clientService.Register(ctx, oidc.RegisterClientRequest{
+		OwnerID:      userID,
+		Name:         registerReq.Name,
+		RedirectURIs: registerReq.RedirectURIs,
+		Public:       registerReq.Public,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrClientNameRequired),
+			errors.Is(err, oidc.ErrRedirectURIRequired),
+			errors.Is(err, oidc.ErrRedirectURIInvalid):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			h.logger.Printf("register client: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	err = helpers.WriteJSON(w, http.StatusCreated, RegisterOAuthClientResponse{
+		OAuthClientDTO: newOAuthClientDTO(serviceResp.Client),
+		ClientSecret:   serviceResp.Secret.ToPointer(),
+	})
+	if err != nil {
+		h.logger.Printf("register client: write response failed: %v", err)
+	}
+}
+
+func (h *OIDCHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("list clients: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	serviceResp, err := h.clientService.List(ctx, oidc.ListClientsRequest{OwnerID: userID})
+	if err != nil {
+		h.logger.Printf("list clients: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	listResp := ListOAuthClientsResponse{
+		Clients: make([]OAuthClientDTO, 0, len(serviceResp.Clients)),
+	}
+	for _, client := range serviceResp.Clients {
+		listResp.Clients = append(listResp.Clients, newOAuthClientDTO(client))
+	}
+
+	err = helpers.WriteJSON(w, http.StatusOK, listResp)
+	if err != nil {
+		h.logger.Printf("list clients: write response failed: %v", err)
+	}
+}
+
+func (h *OIDCHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("delete client: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	_, err := h.clientService.Delete(ctx, oidc.DeleteClientRequest{
+		OwnerID:  userID,
+		ClientID: chi.URLParam(r, "id"),
+	})
+	if err != nil {
+		if errors.Is(err, oidc.ErrClientNotFound) {
+			helpers.WriteError(w, http.StatusNotFound, "client not found")
+			return
+		}
+		h.logger.Printf("delete client: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAuthorizeError reports errors that cannot be sent to the redirect URI
+// because the client or the URI itself is not trusted.
+func (h *OIDCHandler) writeAuthorizeError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, oidc.ErrInvalidClient),
+		errors.Is(err, oidc.ErrRedirectURIInvalid):
+		writeOAuthError(w, http.StatusBadRequest, err)
+	default:
+		h.logger.Printf("%s: internal error: %v", op, err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+	}
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Cache-Control", "no-store")
+	description := err.Error()
+	if status == http.StatusInternalServerError {
+		description = ""
+	}
+	_ = helpers.WriteJSON(w, status, OAuthErrorResponse{
+		Error:            oidc.ErrorCode(err),
+		ErrorDescription: description,
+	})
+}
+
+func newOAuthClientDTO(client entities.OAuthClient) OAuthClientDTO {
+	return OAuthClientDTO{
+		ClientID:     client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Public:       client.SecretHash.IsAbsent(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
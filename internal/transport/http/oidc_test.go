@@ -0,0 +1,437 @@
+package http
+
+import (
+	"context"
+	"crud/internal/adapters/authorization_code/memory"
+	"crud/internal/adapters/jwt"
+	sessionMemory "crud/internal/adapters/session/memory"
+	"crud/internal/domain/entities"
+	"crud/internal/services/oidc"
+	"crud/internal/services/user"
+	"crud/internal/transport/http/helpers"
+	"crud/internal/transport/http/middleware"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/samber/mo"
+)
+
+type fakeClientRepo struct {
+	mu      sync.Mutex
+	clients map[string]entities.OAuthClient
+}
+
+func (r *fakeClientRepo) Create(ctx context.Context, client entities.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[client.ID] = client
+	return nil
+}
+
+func (r *fakeClientRepo) FindOne(ctx context.Context, id string, ent *entities.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client, ok := r.clients[id]
+	if !ok {
+		return oidc.ErrClientNotFound
+	}
+	*ent = client
+	return nil
+}
+
+func (r *fakeClientRepo) FindByOwner(ctx context.Context, ownerID string) ([]entities.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clients := []entities.OAuthClient{}
+	for _, client := range r.clients {
+		if client.OwnerID == ownerID {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+func (r *fakeClientRepo) Delete(ctx context.Context, id string, ownerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if client, ok := r.clients[id]; !ok || client.OwnerID != ownerID {
+		return oidc.ErrClientNotFound
+	}
+	delete(r.clients, id)
+	return nil
+}
+
+type fakeConsentRepo struct {
+	mu       sync.Mutex
+	consents map[string]entities.OAuthConsent
+}
+
+func (r *fakeConsentRepo) FindOne(ctx context.Context, userID string, clientID string, ent *entities.OAuthConsent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	consent, ok := r.consents[userID+"/"+clientID]
+	if !ok {
+		return oidc.ErrConsentNotFound
+	}
+	*ent = consent
+	return nil
+}
+
+func (r *fakeConsentRepo) Save(ctx context.Context, consent entities.OAuthConsent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consents[consent.UserID+"/"+consent.ClientID] = consent
+	return nil
+}
+
+type fakeUserRepo struct {
+	users []entities.User
+}
+
+func (r *fakeUserRepo) FindOne(ctx context.Context, filter entities.UserFilterAttrs, ent *entities.User) error {
+	for _, u := range r.users {
+		if id, ok := filter.ID.Get(); ok && u.ID == id {
+			*ent = u
+			return nil
+		}
+	}
+	return user.ErrUserNotFound
+}
+
+type sequenceIDGen struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (g *sequenceIDGen) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	return fmt.Sprintf("client-%d", g.next), nil
+}
+
+// oidcTestServer runs the provider in process. The returned cookie belongs to
+// a signed-in user with ID user-1.
+func oidcTestServer(t *testing.T) (*httptest.Server, *http.Cookie) {
+	t.Helper()
+
+	key, err := jwt.GenerateKey("k1")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keys, _ := jwt.NewKeySet([]jwt.Key{key}, "")
+	sessions, _ := sessionMemory.NewMemoryStore(user.SessionLifetime{IdleTimeout: time.Hour}, nil)
+	session, _ := sessions.Create(context.Background(), user.SessionAttrs{UserID: "user-1"})
+	users := &fakeUserRepo{users: []entities.User{{
+		ID:              "user-1",
+		Username:        "demo",
+		Email:           "demo@example.com",
+		EmailVerifiedAt: mo.Some(time.Now()),
+	}}}
+
+	server := httptest.NewServer(nil)
+	t.Cleanup(server.Close)
+
+	logger := log.New(io.Discard, "", 0)
+	clients := &fakeClientRepo{clients: map[string]entities.OAuthClient{}}
+	consents := &fakeConsentRepo{consents: map[string]entities.OAuthConsent{}}
+	provider := oidc.NewProvider(clients, consents, memory.NewMemoryStore(), users,
+		jwt.NewOIDCTokens(keys, server.URL, time.Minute, time.Minute))
+	auth, _ := middleware.NewAuthMiddleware(sessions, jwt.NewAccessTokens(keys, server.URL, time.Minute), nil, middleware.AuthConfig{})
+	server.Config.Handler = NewRouter(nil, NewTokenHandler(nil, keys, logger), nil, nil, nil, nil,
+		NewOIDCHandler(provider, oidc.NewClientService(clients, &sequenceIDGen{}), server.URL, logger), auth, RateLimits{}, nil)
+
+	return server, &http.Cookie{Name: helpers.SessionCookieName, Value: session.ID}
+}
+
+// testRP is a minimal relying party that drives the provider the way a real
+// OpenID Connect client library would.
+type testRP struct {
+	t        *testing.T
+	http     *http.Client
+	cookie   *http.Cookie
+	config   OpenIDConfiguration
+	clientID string
+	secret   string
+	redirect string
+	verifier string
+	nonce    string
+}
+
+func newTestRP(t *testing.T, server *httptest.Server, cookie *http.Cookie) *testRP {
+	rp := &testRP{
+		t:      t,
+		cookie: cookie,
+		http: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+		redirect: "http://127.0.0.1:9999/callback",
+		verifier: strings.Repeat("v", 50),
+		nonce:    "n-0S6_WzA2Mj",
+	}
+
+	resp := rp.do("GET", server.URL+"/.well-known/openid-configuration", nil, "", nil)
+	rp.decode(resp, http.StatusOK, &rp.config)
+	if rp.config.Issuer != server.URL {
+		t.Fatalf("unexpected issuer %q", rp.config.Issuer)
+	}
+
+	resp = rp.do("POST", server.URL+"/oauth/clients", cookie, "application/json",
+		strings.NewReader(`{"name":"test app","redirect_uris":["`+rp.redirect+`"]}`))
+	var registered RegisterOAuthClientResponse
+	rp.decode(resp, http.StatusCreated, &registered)
+	if registered.ClientSecret == nil {
+		t.Fatalf("confidential client must get a secret")
+	}
+	rp.clientID, rp.secret = registered.ClientID, *registered.ClientSecret
+	return rp
+}
+
+func (rp *testRP) do(method string, target string, cookie *http.Cookie, contentType string, body io.Reader) *http.Response {
+	rp.t.Helper()
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		rp.t.Fatalf("failed to build request: %v", err)
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := rp.http.Do(req)
+	if err != nil {
+		rp.t.Fatalf("%s %s failed: %v", method, target, err)
+	}
+	rp.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func (rp *testRP) decode(resp *http.Response, status int, dst any) {
+	rp.t.Helper()
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		rp.t.Fatalf("%s %s: expected %d, got %d: %s", resp.Request.Method, resp.Request.URL.Path, status, resp.StatusCode, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		rp.t.Fatalf("failed to decode response: %v", err)
+	}
+}
+
+func (rp *testRP) authorizeParams(scope string) url.Values {
+	sum := sha256.Sum256([]byte(rp.verifier))
+	return url.Values{
+		"client_id":             {rp.clientID},
+		"redirect_uri":          {rp.redirect},
+		"response_type":         {"code"},
+		"scope":                 {scope},
+		"state":                 {"xyz"},
+		"nonce":                 {rp.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func (rp *testRP) authorize(params url.Values) *http.Response {
+	return rp.do("GET", rp.config.AuthorizationEndpoint+"?"+params.Encode(), rp.cookie, "", nil)
+}
+
+// callback checks that the user agent was sent back to the client and returns
+// the query it was sent back with.
+func (rp *testRP) callback(location string) url.Values {
+	rp.t.Helper()
+	u, err := url.Parse(location)
+	if err != nil || !strings.HasPrefix(location, rp.redirect+"?") {
+		rp.t.Fatalf("unexpected redirect %q", location)
+	}
+	if u.Query().Get("state") != "xyz" {
+		rp.t.Fatalf("state was not returned: %q", location)
+	}
+	return u.Query()
+}
+
+func (rp *testRP) exchange(code string, verifier string) *http.Response {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {rp.redirect},
+		"code_verifier": {verifier},
+	}
+	req, _ := http.NewRequest("POST", rp.config.TokenEndpoint, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(rp.clientID), url.QueryEscape(rp.secret))
+	resp, err := rp.http.Do(req)
+	if err != nil {
+		rp.t.Fatalf("token request failed: %v", err)
+	}
+	rp.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// verifyIDToken checks the ID token against the published JWKS the way a
+// relying party that knows nothing but the discovery document would.
+func (rp *testRP) verifyIDToken(token string) jwtlib.MapClaims {
+	rp.t.Helper()
+	var jwks jwt.JWKS
+	rp.decode(rp.do("GET", rp.config.JWKSURI, nil, "", nil), http.StatusOK, &jwks)
+
+	claims := jwtlib.MapClaims{}
+	_, err := jwtlib.ParseWithClaims(token, claims, func(token *jwtlib.Token) (any, error) {
+		for _, key := range jwks.Keys {
+			if key.Kid == token.Header["kid"] {
+				return key.RSAPublicKey()
+			}
+		}
+		return nil, fmt.Errorf("unknown kid %v", token.Header["kid"])
+	},
+		jwtlib.WithValidMethods([]string{"RS256"}),
+		jwtlib.WithIssuer(rp.config.Issuer),
+		jwtlib.WithAudience(rp.clientID),
+		jwtlib.WithExpirationRequired(),
+	)
+	if err != nil {
+		rp.t.Fatalf("id token does not verify: %v", err)
+	}
+	if claims["nonce"] != rp.nonce {
+		rp.t.Fatalf("nonce mismatch: %v", claims["nonce"])
+	}
+	return claims
+}
+
+func TestOIDC_AuthorizationCodeFlow(t *testing.T) {
+	server, cookie := oidcTestServer(t)
+	rp := newTestRP(t, server, cookie)
+
+	resp := rp.authorize(rp.authorizeParams("openid email"))
+	var consent ConsentRequiredResponse
+	rp.decode(resp, http.StatusOK, &consent)
+	if !consent.ConsentRequired || consent.ClientName != "test app" || !slices.Equal(consent.Scopes, []string{"openid", "email"}) {
+		t.Fatalf("unexpected consent prompt: %+v", consent)
+	}
+
+	consentReq := map[string]any{"approve": true}
+	for key, values := range rp.authorizeParams("openid email") {
+		consentReq[key] = values[0]
+	}
+	body, _ := json.Marshal(consentReq)
+	var redirect OAuthRedirectResponse
+	rp.decode(rp.do("POST", server.URL+"/oauth/authorize/consent", cookie, "application/json", strings.NewReader(string(body))), http.StatusOK, &redirect)
+	code := rp.callback(redirect.RedirectTo).Get("code")
+	if code == "" {
+		t.Fatalf("no code in %q", redirect.RedirectTo)
+	}
+
+	resp = rp.exchange(code, rp.verifier)
+	if resp.Header.Get("Cache-Control") != "no-store" {
+		t.Fatalf("token response must not be cached")
+	}
+	var tokens OAuthTokenResponse
+	rp.decode(resp, http.StatusOK, &tokens)
+	if tokens.TokenType != "Bearer" || tokens.ExpiresIn <= 0 || tokens.Scope != "openid email" {
+		t.Fatalf("unexpected token response: %+v", tokens)
+	}
+
+	claims := rp.verifyIDToken(tokens.IDToken)
+	if claims["sub"] != "user-1" || claims["email"] != "demo@example.com" || claims["email_verified"] != true {
+		t.Fatalf("unexpected id token claims: %v", claims)
+	}
+	if _, ok := claims["preferred_username"]; ok {
+		t.Fatalf("profile claims released without the profile scope: %v", claims)
+	}
+
+	req, _ := http.NewRequest("GET", rp.config.UserInfoEndpoint, nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	userInfoResp, err := rp.http.Do(req)
+	if err != nil {
+		t.Fatalf("userinfo request failed: %v", err)
+	}
+	defer userInfoResp.Body.Close()
+	var info UserInfoResponse
+	rp.decode(userInfoResp, http.StatusOK, &info)
+	if info.Sub != "user-1" || info.Email == nil || *info.Email != "demo@example.com" {
+		t.Fatalf("unexpected userinfo: %+v", info)
+	}
+
+	var oauthErr OAuthErrorResponse
+	rp.decode(rp.exchange(code, rp.verifier), http.StatusBadRequest, &oauthErr)
+	if oauthErr.Error != "invalid_grant" {
+		t.Fatalf("code must be single use, got %+v", oauthErr)
+	}
+
+	req, _ = http.NewRequest("GET", server.URL+"/oauth/clients", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	apiResp, err := rp.http.Do(req)
+	if err != nil {
+		t.Fatalf("api request failed: %v", err)
+	}
+	defer apiResp.Body.Close()
+	if apiResp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("client access token must not open the API, got %d", apiResp.StatusCode)
+	}
+
+	// Consent is remembered, so the next login goes straight back.
+	resp = rp.authorize(rp.authorizeParams("openid"))
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect with stored consent, got %d", resp.StatusCode)
+	}
+	code = rp.callback(resp.Header.Get("Location")).Get("code")
+	rp.decode(rp.exchange(code, strings.Repeat("w", 50)), http.StatusBadRequest, &oauthErr)
+	if oauthErr.Error != "invalid_grant" {
+		t.Fatalf("wrong code_verifier must be rejected, got %+v", oauthErr)
+	}
+}
+
+func TestOIDC_AuthorizeErrors(t *testing.T) {
+	server, cookie := oidcTestServer(t)
+	rp := newTestRP(t, server, cookie)
+
+	params := rp.authorizeParams("openid")
+	params.Set("redirect_uri", "https://attacker.example/callback")
+	var oauthErr OAuthErrorResponse
+	rp.decode(rp.authorize(params), http.StatusBadRequest, &oauthErr)
+	if oauthErr.Error != "invalid_request" {
+		t.Fatalf("unregistered redirect uri must not be redirected to, got %+v", oauthErr)
+	}
+
+	params = rp.authorizeParams("openid")
+	params.Del("code_challenge")
+	resp := rp.authorize(params)
+	if resp.StatusCode != http.StatusFound || rp.callback(resp.Header.Get("Location")).Get("error") != "invalid_request" {
+		t.Fatalf("missing PKCE must be reported to the client, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	params = rp.authorizeParams("email")
+	resp = rp.authorize(params)
+	if resp.StatusCode != http.StatusFound || rp.callback(resp.Header.Get("Location")).Get("error") != "invalid_scope" {
+		t.Fatalf("requests without openid must fail, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	consentReq := map[string]any{"approve": false}
+	for key, values := range rp.authorizeParams("openid") {
+		consentReq[key] = values[0]
+	}
+	body, _ := json.Marshal(consentReq)
+	var redirect OAuthRedirectResponse
+	rp.decode(rp.do("POST", server.URL+"/oauth/authorize/consent", cookie, "application/json", strings.NewReader(string(body))), http.StatusOK, &redirect)
+	if rp.callback(redirect.RedirectTo).Get("error") != "access_denied" {
+		t.Fatalf("declined consent must give access_denied, got %q", redirect.RedirectTo)
+	}
+
+	resp = rp.do("GET", rp.config.AuthorizationEndpoint+"?"+rp.authorizeParams("openid").Encode(), nil, "", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("authorize requires a signed-in user, got %d", resp.StatusCode)
+	}
+}
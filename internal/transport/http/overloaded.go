@@ -0,0 +1,24 @@
+package http
+
+import (
+	"crud/internal/services/user"
+	helpers "crud/internal/transport/http/helpers"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// overloadedRetryAfter is suggested to clients turned away because password
+// hashing is at capacity.
+const overloadedRetryAfter = time.Second
+
+// writeOverloadedError answers 503 when password hashing had no capacity
+// left and reports whether it did.
+func writeOverloadedError(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, user.ErrHashingOverloaded) {
+		return false
+	}
+	helpers.SetRetryAfter(w, overloadedRetryAfter)
+	helpers.WriteError(w, http.StatusServiceUnavailable, "service is overloaded, retry later")
+	return true
+}
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"crud/internal/services/user"
+	helpers "crud/internal/transport/http/helpers"
+	"errors"
+	"net/http"
+)
+
+// writePasswordPolicyError answers 400 with every rule the password broke
+// and reports whether err was a policy failure.
+func writePasswordPolicyError(w http.ResponseWriter, err error) bool {
+	var policyErr *user.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	resp := PasswordPolicyErrorResponse{
+		Error:   user.ErrPasswordPolicy.Error(),
+		Reasons: make([]PasswordViolationDTO, len(policyErr.Violations)),
+	}
+	for i, v := range policyErr.Violations {
+		resp.Reasons[i] = PasswordViolationDTO{Code: v.Code, Message: v.Message}
+	}
+	_ = helpers.WriteJSON(w, http.StatusBadRequest, resp)
+	return true
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"crud/internal/services/user"
+	helpers "crud/internal/transport/http/helpers"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// forgotPasswordTimeout bounds the work of a reset request, which outlives
+// the request itself.
+const forgotPasswordTimeout = time.Minute
+
+type PasswordResetHandler struct {
+	passwordResetService *user.PasswordResetService
+	logger               *log.Logger
+}
+
+func NewPasswordResetHandler(passwordResetService *user.PasswordResetService, logger *log.Logger) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		passwordResetService: passwordResetService,
+		logger:               logger,
+	}
+}
+
+// Forgot answers 202 whatever happens past decoding, and before the reset is
+// done, so that neither the status, a failure to send nor the response time
+// reveals whether the email is registered.
+func (h *PasswordResetHandler) Forgot(w http.ResponseWriter, r *http.Request) {
+	var forgotReq ForgotPasswordRequest
+	err := helpers.DecodeJSON(r, &forgotReq)
+	if err != nil {
+		h.logger.Printf("forgot password: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	// The lookup, the token and the mail take longer for registered emails,
+	// so they run after the response with a context of their own.
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, forgotPasswordTimeout)
+		defer cancel()
+		_, err := h.passwordResetService.Forgot(ctx, user.ForgotPasswordRequest{Email: forgotReq.Email})
+		if err != nil && !errors.Is(err, user.ErrEmailRequired) && !errors.Is(err, user.ErrEmailIncorrect) {
+			h.logger.Printf("forgot password: internal error: %v", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *PasswordResetHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	var resetReq ResetPasswordRequest
+	err := helpers.DecodeJSON(r, &resetReq)
+	if err != nil {
+		h.logger.Printf("reset password: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	_, err = h.passwordResetService.Reset(r.Context(), user.ResetPasswordRequest{
+		Token:    resetReq.Token,
+		Password: resetReq.Password,
+	})
+	if err != nil {
+		if writePasswordPolicyError(w, err) || writeOverloadedError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, user.ErrPasswordRequired) || errors.Is(err, user.ErrPasswordIncorrect) ||
+			errors.Is(err, user.ErrOneTimeTokenInvalid):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			h.logger.Printf("reset password: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
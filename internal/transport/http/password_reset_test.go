@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/mo"
+)
+
+type emailUserRepo struct {
+	fakeUserRepo
+}
+
+func (r *emailUserRepo) Update(ctx context.Context, attrs entities.UserUpdateAttrs, filter entities.UserFilterAttrs, ent *entities.User) error {
+	return r.FindOne(ctx, filter, ent)
+}
+
+func (r *emailUserRepo) FindOne(ctx context.Context, filter entities.UserFilterAttrs, ent *entities.User) error {
+	if email, ok := filter.Email.Get(); ok {
+		for _, u := range r.users {
+			if u.Email == email {
+				*ent = u
+				return nil
+			}
+		}
+		return user.ErrUserNotFound
+	}
+	return r.fakeUserRepo.FindOne(ctx, filter, ent)
+}
+
+type fakeOneTimeTokenRepo struct {
+	created chan entities.OneTimeToken
+}
+
+func (r *fakeOneTimeTokenRepo) Create(ctx context.Context, token entities.OneTimeToken) error {
+	r.created <- token
+	return nil
+}
+
+func (r *fakeOneTimeTokenRepo) Consume(ctx context.Context, hash string, purpose string, ent *entities.OneTimeToken) error {
+	return user.ErrOneTimeTokenInvalid
+}
+
+func (r *fakeOneTimeTokenRepo) DeleteByUser(ctx context.Context, userID string, purpose string) error {
+	return nil
+}
+
+type blockingNotifier struct {
+	release chan struct{}
+	sent    chan user.Message
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, msg user.Message) error {
+	<-n.release
+	n.sent <- msg
+	return nil
+}
+
+func TestForgot_AnswersBeforeSending(t *testing.T) {
+	users := &emailUserRepo{fakeUserRepo{users: []entities.User{{
+		ID:              "user-1",
+		Email:           "demo@example.com",
+		EmailVerifiedAt: mo.Some(time.Now()),
+	}}}}
+	tokens := &fakeOneTimeTokenRepo{created: make(chan entities.OneTimeToken, 1)}
+	notifier := &blockingNotifier{release: make(chan struct{}), sent: make(chan user.Message, 1)}
+	service := user.NewPasswordResetService(users, tokens, nil, nil, notifier, "https://app.example.com/reset")
+	handler := NewPasswordResetHandler(service, log.New(io.Discard, "", 0))
+
+	req := httptest.NewRequest(http.MethodPost, "/users/password/forgot", strings.NewReader(`{"email":"demo@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.Forgot(rec, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the response waited for the reset link to be sent")
+	}
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	close(notifier.release)
+	select {
+	case msg := <-notifier.sent:
+		if msg.To != "demo@example.com" {
+			t.Fatalf("reset link sent to %q", msg.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reset link was not sent after the response")
+	}
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"crud/internal/domain/entities"
+	"crud/internal/services/user"
+	helpers "crud/internal/transport/http/helpers"
+	"crud/internal/transport/http/middleware"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/samber/mo"
+)
+
+type PersonalTokenHandler struct {
+	personalTokenService *user.PersonalTokenService
+	logger               *log.Logger
+}
+
+func NewPersonalTokenHandler(personalTokenService *user.PersonalTokenService, logger *log.Logger) *PersonalTokenHandler {
+	return &PersonalTokenHandler{
+		personalTokenService: personalTokenService,
+		logger:               logger,
+	}
+}
+
+func (h *PersonalTokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("create token: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	var createReq CreatePersonalTokenRequest
+	err := helpers.DecodeJSON(r, &createReq)
+	if err != nil {
+		h.logger.Printf("create token: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	serviceResp, err := h.personalTokenService.Create(ctx, user.CreatePersonalTokenRequest{
+		UserID:    userID,
+		Name:      createReq.Name,
+		Scopes:    createReq.Scopes,
+		ExpiresAt: mo.PointerToOption(createReq.ExpiresAt),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrTokenNameRequired),
+			errors.Is(err, user.ErrUnknownScope),
+			errors.Is(err, user.ErrTokenExpiryInPast):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		default:
+			h.logger.Printf("create token: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	err = helpers.WriteJSON(w, http.StatusCreated, CreatePersonalTokenResponse{
+		PersonalTokenDTO: newPersonalTokenDTO(serviceResp.Token),
+		Token:            serviceResp.Secret,
+	})
+	if err != nil {
+		h.logger.Printf("create token: write response failed: %v", err)
+	}
+}
+
+func (h *PersonalTokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("list tokens: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	serviceResp, err := h.personalTokenService.List(ctx, user.ListPersonalTokensRequest{UserID: userID})
+	if err != nil {
+		h.logger.Printf("list tokens: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	listResp := ListPersonalTokensResponse{
+		Tokens: make([]PersonalTokenDTO, 0, len(serviceResp.Tokens)),
+	}
+	for _, token := range serviceResp.Tokens {
+		listResp.Tokens = append(listResp.Tokens, newPersonalTokenDTO(token))
+	}
+
+	err = helpers.WriteJSON(w, http.StatusOK, listResp)
+	if err != nil {
+		h.logger.Printf("list tokens: write response failed: %v", err)
+	}
+}
+
+func (h *PersonalTokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("revoke token: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	_, err := h.personalTokenService.Revoke(ctx, user.RevokePersonalTokenRequest{
+		UserID:  userID,
+		TokenID: chi.URLParam(r, "id"),
+	})
+	if err != nil {
+		if errors.Is(err, user.ErrPersonalTokenNotFound) {
+			helpers.WriteError(w, http.StatusNotFound, "token not found")
+			return
+		}
+		h.logger.Printf("revoke token: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newPersonalTokenDTO(token entities.PersonalAccessToken) PersonalTokenDTO {
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return PersonalTokenDTO{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     scopes,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt.ToPointer(),
+		LastUsedAt: token.LastUsedAt.ToPointer(),
+	}
+}
@@ -1,23 +1,74 @@
 package http
 
 import (
+	"crud/internal/services/user"
 	"crud/internal/transport/http/middleware"
+	"crud/internal/transport/http/middleware/ratelimit"
 	"net/http"
 
 	"github.com/go-chi/chi"
 )
 
-func NewRouter(userHandler *UserHandler, authMiddleware *middleware.AuthMiddleware) http.Handler {
+// RateLimits are the limiters of the route groups; nil ones do not limit.
+type RateLimits struct {
+	// Public covers the /users endpoints that need no authentication, such as
+	// registration and login.
+	Public *ratelimit.Limiter
+	// Authenticated covers the endpoints behind RequireAuth and runs after it,
+	// so it can key by user.
+	Authenticated *ratelimit.Limiter
+}
+
+func NewRouter(userHandler *UserHandler, tokenHandler *TokenHandler, personalTokenHandler *PersonalTokenHandler, twoFactorHandler *TwoFactorHandler, passwordResetHandler *PasswordResetHandler, emailVerificationHandler *EmailVerificationHandler, oidcHandler *OIDCHandler, authMiddleware *middleware.AuthMiddleware, rateLimits RateLimits, csrf *middleware.CSRFProtection) http.Handler {
 	r := chi.NewRouter()
+	r.Get("/.well-known/jwks.json", tokenHandler.JWKS)
+	r.Get("/.well-known/openid-configuration", oidcHandler.Discovery)
+	r.Post("/oauth/token", oidcHandler.Token)
+	r.Get("/oauth/userinfo", oidcHandler.UserInfo)
+	r.Post("/oauth/userinfo", oidcHandler.UserInfo)
 	r.Route("/users", func(r chi.Router) {
+		r.Use(rateLimits.Public.Handler)
 		r.Post("/register", userHandler.Register)
 		r.Post("/login", userHandler.Login)
+		r.Post("/login/2fa", userHandler.LoginTwoFactor)
+		r.Post("/login/magic", userHandler.SendMagicLink)
+		r.Get("/login/magic/{token}", userHandler.MagicLinkLogin)
+		r.Get("/login/external/{provider}", userHandler.ExternalLogin)
+		r.Get("/login/external/{provider}/callback", userHandler.ExternalLoginCallback)
+		r.Post("/password/forgot", passwordResetHandler.Forgot)
+		r.Post("/password/reset", passwordResetHandler.Reset)
+		r.Post("/verify-email", emailVerificationHandler.Verify)
+		r.Post("/verify-email/resend", emailVerificationHandler.Resend)
+		r.Post("/token/refresh", tokenHandler.Refresh)
+		r.Post("/token/revoke", tokenHandler.Revoke)
+		if csrf != nil {
+			r.Get("/csrf-token", csrf.Token)
+		}
 	})
 	r.Group(func(r chi.Router) {
 		r.Use(authMiddleware.RequireAuth)
+		r.Use(csrf.Handler)
+		r.Use(rateLimits.Authenticated.Handler)
 		r.Post("/users/logout", userHandler.Logout)
-		r.Patch("/users/me", userHandler.Update)
-		r.Delete("/users/me", userHandler.Delete)
+		r.With(middleware.RequireScope(user.ScopeProfileWrite)).Patch("/users/me", userHandler.Update)
+		r.With(middleware.RejectPersonalTokens).Delete("/users/me", userHandler.Delete)
+		r.With(middleware.RequireScope(user.ScopeSessionsRead)).Get("/users/me/sessions", userHandler.ListSessions)
+		r.With(middleware.RequireScope(user.ScopeSessionsWrite)).Delete("/users/me/sessions", userHandler.RevokeAllSessions)
+		r.With(middleware.RequireScope(user.ScopeSessionsWrite)).Delete("/users/me/sessions/{id}", userHandler.RevokeSession)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RejectPersonalTokens)
+			r.Post("/users/me/tokens", personalTokenHandler.Create)
+			r.Get("/users/me/tokens", personalTokenHandler.List)
+			r.Delete("/users/me/tokens/{id}", personalTokenHandler.Revoke)
+			r.Post("/users/me/2fa/enroll", twoFactorHandler.Enroll)
+			r.Post("/users/me/2fa/confirm", twoFactorHandler.Confirm)
+			r.Post("/users/me/2fa/disable", twoFactorHandler.Disable)
+			r.Get("/oauth/authorize", oidcHandler.Authorize)
+			r.Post("/oauth/authorize/consent", oidcHandler.Consent)
+			r.Post("/oauth/clients", oidcHandler.RegisterClient)
+			r.Get("/oauth/clients", oidcHandler.ListClients)
+			r.Delete("/oauth/clients/{id}", oidcHandler.DeleteClient)
+		})
 	})
 	return r
 }
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"crud/internal/adapters/jwt"
+	"crud/internal/services/user"
+	helpers "crud/internal/transport/http/helpers"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type JWKSource interface {
+	JWKS() jwt.JWKS
+}
+
+type TokenHandler struct {
+	tokenService *user.TokenService
+	keys         JWKSource
+	logger       *log.Logger
+}
+
+func NewTokenHandler(tokenService *user.TokenService, keys JWKSource, logger *log.Logger) *TokenHandler {
+	return &TokenHandler{
+		tokenService: tokenService,
+		keys:         keys,
+		logger:       logger,
+	}
+}
+
+func (h *TokenHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var refreshReq RefreshTokenRequest
+	err := helpers.DecodeJSON(r, &refreshReq)
+	if err != nil {
+		h.logger.Printf("refresh: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	serviceResp, err := h.tokenService.Refresh(r.Context(), user.RefreshTokensRequest{
+		RefreshToken: refreshReq.RefreshToken,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrRefreshTokenReused):
+			h.logger.Printf("refresh: token reuse detected, family revoked")
+			helpers.WriteError(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		case errors.Is(err, user.ErrRefreshTokenInvalid):
+			helpers.WriteError(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		default:
+			h.logger.Printf("refresh: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	err = helpers.WriteJSON(w, http.StatusOK, newTokenPairDTO(serviceResp.Tokens))
+	if err != nil {
+		h.logger.Printf("refresh: write response failed: %v", err)
+	}
+}
+
+func (h *TokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	var revokeReq RefreshTokenRequest
+	err := helpers.DecodeJSON(r, &revokeReq)
+	if err != nil {
+		h.logger.Printf("revoke token: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	_, err = h.tokenService.Revoke(r.Context(), user.RevokeTokensRequest{
+		RefreshToken: revokeReq.RefreshToken,
+	})
+	if err != nil {
+		h.logger.Printf("revoke token: internal error: %v", err)
+		helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TokenHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	err := helpers.WriteJSON(w, http.StatusOK, h.keys.JWKS())
+	if err != nil {
+		h.logger.Printf("jwks: write response failed: %v", err)
+	}
+}
+
+func newTokenPairDTO(tokens user.TokenPair) *TokenDTO {
+	return &TokenDTO{
+		AccessToken:  tokens.AccessToken.Token,
+		TokenType:    "Bearer",
+		ExpiresAt:    tokens.AccessToken.ExpiresAt,
+		RefreshToken: tokens.RefreshToken,
+	}
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"crud/internal/services/user"
+	helpers "crud/internal/transport/http/helpers"
+	"crud/internal/transport/http/middleware"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *user.TwoFactorService
+	logger           *log.Logger
+}
+
+func NewTwoFactorHandler(twoFactorService *user.TwoFactorService, logger *log.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+		logger:           logger,
+	}
+}
+
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("enroll 2fa: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	serviceResp, err := h.twoFactorService.Enroll(ctx, user.EnrollTwoFactorRequest{UserID: userID})
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrTwoFactorAlreadyEnabled):
+			helpers.WriteError(w, http.StatusConflict, err.Error())
+			return
+		default:
+			h.logger.Printf("enroll 2fa: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	err = helpers.WriteJSON(w, http.StatusOK, EnrollTwoFactorResponse{
+		Secret:     serviceResp.Secret,
+		OTPAuthURI: serviceResp.URI,
+	})
+	if err != nil {
+		h.logger.Printf("enroll 2fa: write response failed: %v", err)
+	}
+}
+
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("confirm 2fa: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	var codeReq TwoFactorCodeRequest
+	err := helpers.DecodeJSON(r, &codeReq)
+	if err != nil {
+		h.logger.Printf("confirm 2fa: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	serviceResp, err := h.twoFactorService.Confirm(ctx, user.ConfirmTwoFactorRequest{
+		UserID: userID,
+		Code:   codeReq.Code,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrTwoFactorCodeRequired) || errors.Is(err, user.ErrTwoFactorCodeInvalid) ||
+			errors.Is(err, user.ErrTwoFactorNotEnrolled):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, user.ErrTwoFactorAlreadyEnabled):
+			helpers.WriteError(w, http.StatusConflict, err.Error())
+			return
+		default:
+			h.logger.Printf("confirm 2fa: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	err = helpers.WriteJSON(w, http.StatusOK, ConfirmTwoFactorResponse{RecoveryCodes: serviceResp.RecoveryCodes})
+	if err != nil {
+		h.logger.Printf("confirm 2fa: write response failed: %v", err)
+	}
+}
+
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		h.logger.Printf("disable 2fa: userID missing in context")
+		helpers.WriteError(w, http.StatusUnauthorized, "missing session")
+		return
+	}
+
+	var codeReq TwoFactorCodeRequest
+	err := helpers.DecodeJSON(r, &codeReq)
+	if err != nil {
+		h.logger.Printf("disable 2fa: decode request failed: %v", err)
+		helpers.WriteError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	_, err = h.twoFactorService.Disable(ctx, user.DisableTwoFactorRequest{
+		UserID: userID,
+		Code:   codeReq.Code,
+	})
+	if err != nil {
+		var throttled *user.ThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			helpers.SetRetryAfter(w, throttled.RetryAfter)
+			helpers.WriteError(w, http.StatusTooManyRequests, "too many attempts")
+			return
+		case errors.Is(err, user.ErrTwoFactorCodeRequired) || errors.Is(err, user.ErrTwoFactorNotEnrolled):
+			helpers.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, user.ErrTwoFactorCodeInvalid):
+			helpers.WriteError(w, http.StatusForbidden, err.Error())
+			return
+		default:
+			h.logger.Printf("disable 2fa: internal error: %v", err)
+			helpers.WriteError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
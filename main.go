@@ -3,10 +3,16 @@ package main
 import (
 	"crud/cmd"
 	"log"
+	"os"
 )
 
 func main() {
-	err := cmd.RunServer()
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "build-breach-filter" {
+		err = cmd.RunBuildBreachFilter(os.Args[2:])
+	} else {
+		err = cmd.RunServer()
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
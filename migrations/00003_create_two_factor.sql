@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE two_factor (
+	user_id VARCHAR(255) PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+	secret VARCHAR(64) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	confirmed_at TIMESTAMPTZ,
+	last_used_step BIGINT NOT NULL DEFAULT 0,
+	recovery_codes TEXT[] NOT NULL DEFAULT '{}'
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS two_factor;
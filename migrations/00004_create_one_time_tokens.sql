@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE one_time_tokens (
+	token_hash VARCHAR(64) PRIMARY KEY,
+	user_id VARCHAR(255) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	purpose VARCHAR(32) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX one_time_tokens_user_id_purpose_idx ON one_time_tokens (user_id, purpose);
+
+-- +goose Down
+DROP TABLE IF EXISTS one_time_tokens;
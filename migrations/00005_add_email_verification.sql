@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+	ADD COLUMN email_verified_at TIMESTAMPTZ,
+	ADD COLUMN verification_sent_at TIMESTAMPTZ;
+
+-- Accounts from before verification existed count as verified, otherwise
+-- email_verification.required would lock all of them out.
+UPDATE users SET email_verified_at = now();
+
+-- +goose Down
+ALTER TABLE users
+	DROP COLUMN IF EXISTS verification_sent_at,
+	DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE oauth_clients (
+	id VARCHAR(255) PRIMARY KEY,
+	owner_id VARCHAR(255) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	secret_hash VARCHAR(64),
+	redirect_uris TEXT[] NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX oauth_clients_owner_id_idx ON oauth_clients (owner_id);
+
+CREATE TABLE oauth_consents (
+	user_id VARCHAR(255) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	client_id VARCHAR(255) NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+	scopes TEXT[] NOT NULL,
+	granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, client_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_clients;
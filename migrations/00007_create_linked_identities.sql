@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE linked_identities (
+	provider VARCHAR(50) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	user_id VARCHAR(255) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	email VARCHAR(255) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX linked_identities_user_id_idx ON linked_identities (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS linked_identities;